@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"golang.org/x/term"
+)
+
+const adminUsage = "usage: admin grant <name> | revoke <name>"
+
+// middlewareAdmin is middlewareLoggedIn for commands that affect every user,
+// like reset. The first user registered with the CLI is an admin.
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+		if !user.IsAdmin {
+			return unauthorized("Only admins can run %v.", cmd.name)
+		}
+		return handler(s, cmd, user)
+	})
+}
+
+// takeYes removes --yes (or -y) from args and reports whether it was there.
+func takeYes(args []string) ([]string, bool) {
+	rest := []string{}
+	yes := false
+	for _, arg := range args {
+		if arg == "--yes" || arg == "-y" {
+			yes = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, yes
+}
+
+// confirmAction asks before a destructive command goes ahead, unless --yes
+// was given. Without a terminal to ask on, only --yes will do.
+func confirmAction(yes bool, warning string) error {
+	if yes {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return invalidArgs("Not running in a terminal, add --yes to confirm.")
+	}
+	fmt.Fprintf(os.Stderr, "%v\nType yes to continue: ", warning)
+	answer, _ := stdin.ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		return invalidArgs("Cancelled.")
+	}
+	return nil
+}
+
+// handlerAdmin gives other users the admin role or takes it away. The last
+// admin can't be removed.
+func handlerAdmin(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) != 2 {
+		return invalidArgs(adminUsage)
+	}
+	subcommand, name := cmd.arguments[0], cmd.arguments[1]
+	if subcommand != "grant" && subcommand != "revoke" {
+		return invalidArgs(adminUsage)
+	}
+	target, err := s.db.GetUser(context.Background(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No user named %v.", name)
+	}
+	if err != nil {
+		return err
+	}
+	if subcommand == "revoke" && target.IsAdmin {
+		admins, err := s.db.CountAdmins(context.Background())
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return unauthorized("Can't revoke the last admin.")
+		}
+	}
+	params := database.SetUserAdminParams{Name: name, IsAdmin: subcommand == "grant", UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	if _, err := s.db.SetUserAdmin(context.Background(), params); err != nil {
+		return err
+	}
+	if subcommand == "grant" {
+		fmt.Printf("%v is now an admin.\n", name)
+	} else {
+		fmt.Printf("%v is no longer an admin.\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"golang.org/x/term"
+)
+
+func TestTakeYes(t *testing.T) {
+	tests := []struct {
+		args []string
+		rest []string
+		yes  bool
+	}{
+		{nil, []string{}, false},
+		{[]string{"--yes"}, []string{}, true},
+		{[]string{"larry", "-y"}, []string{"larry"}, true},
+		{[]string{"larry", "--yes=false"}, []string{"larry", "--yes=false"}, false},
+	}
+	for _, tt := range tests {
+		rest, yes := takeYes(tt.args)
+		if !reflect.DeepEqual(rest, tt.rest) || yes != tt.yes {
+			t.Errorf("takeYes(%q) = %q, %v, want %q, %v", tt.args, rest, yes, tt.rest, tt.yes)
+		}
+	}
+}
+
+func TestConfirmActionNeedsYesWithoutTerminal(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("stdin is a terminal")
+	}
+	if err := confirmAction(false, "Deleting everything."); err == nil {
+		t.Error("expected confirmation to be refused without --yes")
+	}
+	if err := confirmAction(true, "Deleting everything."); err != nil {
+		t.Errorf("--yes should confirm: %v", err)
+	}
+}
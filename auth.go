@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores anything after 72 bytes.
+	maxPasswordLength = 72
+	sessionLength     = 30 * 24 * time.Hour
+)
+
+var errBadLogin = unauthorized("Wrong user name or password.")
+
+// stdin is shared so a password and its confirmation can both be read when
+// they are piped in.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassword prompts on stderr and reads a password without echoing it.
+// When stdin isn't a terminal it reads one line instead, so scripts can pipe
+// the password in.
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", invalidArgs("No password given.")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readNewPassword asks for a password twice and checks it's usable.
+func readNewPassword() (string, error) {
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return "", err
+	}
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	confirm, err := readPassword("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirm {
+		return "", invalidArgs("Passwords don't match.")
+	}
+	return password, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return invalidArgs("Password must be at least %d characters.", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return invalidArgs("Password can't be longer than %d bytes.", maxPasswordLength)
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether password is the user's. Users created before
+// passwords existed have no hash and never match.
+func checkPassword(user database.User, password string) bool {
+	if user.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// hashToken is how session tokens are stored, so a copy of the database
+// can't be used to log in.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createSession starts a session for user and returns its token. Only the
+// hash of the token is kept in the database.
+func createSession(ctx context.Context, db database.Querier, user database.User, length time.Duration) (string, error) {
+	token := randomToken()
+	params := database.CreateSessionParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, ExpiresAt: time.Now().Add(length).UTC(), TokenHash: hashToken(token), UserID: user.ID}
+	if err := db.CreateSession(ctx, params); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// currentUser is the user whose token is in GATOR_TOKEN or, failing that,
+// whose session token is in the config file. The scope is always write for
+// a login session.
+func currentUser(s *state) (database.User, string, error) {
+	if token := os.Getenv(tokenEnv); token != "" {
+		user, scope, err := userByAPIToken(context.Background(), s.db, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.User{}, "", unauthorized("The token in %v is invalid, expired or revoked.", tokenEnv)
+		}
+		return user, scope, err
+	}
+	if s.config.SessionToken == "" {
+		return database.User{}, "", unauthorized("Not logged in. Use: gator login <name>")
+	}
+	user, err := s.db.GetUserBySession(context.Background(), hashToken(s.config.SessionToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, "", unauthorized("Session expired or revoked. Log in again with: gator login <name>")
+	}
+	return user, tokenScopeWrite, err
+}
+
+// handlerLogout revokes the current session and removes it from the config.
+func handlerLogout(s *state, cmd command) error {
+	if s.config.SessionToken == "" {
+		return unauthorized("Not logged in.")
+	}
+	if err := s.db.DeleteSession(context.Background(), hashToken(s.config.SessionToken)); err != nil {
+		return err
+	}
+	if err := s.config.SetSession(""); err != nil {
+		return err
+	}
+	fmt.Println("Logged out.")
+	return nil
+}
+
+// handlerPasswd changes the current user's password. Every other session is
+// revoked, so a leaked token stops working too.
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	current, err := readPassword("Current password: ")
+	if err != nil {
+		return err
+	}
+	if !checkPassword(user, current) {
+		return unauthorized("Wrong password.")
+	}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := setPassword(s, user, password); err != nil {
+		return err
+	}
+	fmt.Printf("Password changed for %v. Other sessions have been logged out.\n", user.Name)
+	return nil
+}
+
+// setPassword stores a new password for user and replaces all of their
+// sessions with a new one for this machine.
+func setPassword(s *state, user database.User, password string) error {
+	if err := storePassword(context.Background(), s.db, user, password); err != nil {
+		return err
+	}
+	token, err := createSession(context.Background(), s.db, user, sessionLength)
+	if err != nil {
+		return err
+	}
+	return s.config.SetSession(token)
+}
+
+// storePassword replaces user's password and logs out all of their sessions.
+func storePassword(ctx context.Context, db database.Querier, user database.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	params := database.SetUserPasswordParams{ID: user.ID, PasswordHash: hash, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	if err := db.SetUserPassword(ctx, params); err != nil {
+		return err
+	}
+	return db.DeleteSessionsForUser(ctx, user.ID)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		err      bool
+	}{
+		{"", true},
+		{"seven77", true},
+		{"eight888", false},
+		{strings.Repeat("a", maxPasswordLength), false},
+		{strings.Repeat("a", maxPasswordLength+1), true},
+	}
+	for _, tt := range tests {
+		if err := validatePassword(tt.password); (err != nil) != tt.err {
+			t.Errorf("validatePassword(%q) = %v, want error: %v", tt.password, err, tt.err)
+		}
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := database.User{Name: "david", PasswordHash: hash}
+	if !checkPassword(user, "correct horse") {
+		t.Error("the right password was rejected")
+	}
+	if checkPassword(user, "battery staple") {
+		t.Error("a wrong password was accepted")
+	}
+	if checkPassword(database.User{Name: "larry"}, "") {
+		t.Error("a user without a password can't log in")
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token := randomToken()
+	if len(token) != 64 || token == randomToken() {
+		t.Fatalf("unexpected token %q", token)
+	}
+	if hashToken(token) == token || hashToken(token) != hashToken(token) {
+		t.Error("tokens must be stored as a stable hash")
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+	"github.com/curtisbraxdale/blog-gator/internal/filter"
+	"github.com/curtisbraxdale/blog-gator/internal/render"
+	"github.com/google/uuid"
+)
+
+type browseOptions struct {
+	limit       int
+	offset      int
+	feed        string
+	category    string
+	since       string
+	until       string
+	unread      bool
+	starred     bool
+	cursor      string
+	sort        string
+	showMuted   bool
+	where       string
+	saved       string
+	publishedAt time.Time
+	cursorID    uuid.UUID
+}
+
+// parseBrowseOptions reads the browse flags. A bare number as the first
+// argument is still accepted as the limit so `gator browse 5` keeps working.
+func parseBrowseOptions(args []string) (browseOptions, error) {
+	opts := browseOptions{}
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.IntVar(&opts.limit, "limit", 2, "number of posts to show")
+	flags.IntVar(&opts.offset, "offset", 0, "number of posts to skip")
+	flags.StringVar(&opts.feed, "feed", "", "only show posts from the feed with this name or URL")
+	flags.StringVar(&opts.category, "category", "", "only show posts from feeds in this category")
+	flags.StringVar(&opts.since, "since", "", "only show posts published at or after this time")
+	flags.StringVar(&opts.until, "until", "", "only show posts published before this time")
+	flags.BoolVar(&opts.unread, "unread", false, "only show unread posts")
+	flags.BoolVar(&opts.starred, "starred", false, "only show starred posts")
+	flags.StringVar(&opts.where, "where", "", "only show posts matching this filter expression")
+	flags.StringVar(&opts.saved, "saved", "", "only show posts matching this saved search")
+	flags.BoolVar(&opts.showMuted, "show-muted", false, "include posts hidden by mute rules")
+	flags.StringVar(&opts.cursor, "cursor", "", "continue from the cursor printed by a previous browse")
+	flags.StringVar(&opts.sort, "sort", "newest", "sort order: newest or oldest")
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		limit, err := strconv.Atoi(args[0])
+		if err != nil {
+			return browseOptions{}, invalidArgs("invalid limit %q", args[0])
+		}
+		args = args[1:]
+		opts.limit = limit
+	}
+	if err := flags.Parse(args); err != nil {
+		return browseOptions{}, invalidArgs("%v", err)
+	}
+	if flags.NArg() > 0 {
+		return browseOptions{}, invalidArgs("unexpected argument %q", flags.Arg(0))
+	}
+	if opts.limit < 1 {
+		return browseOptions{}, invalidArgs("limit must be at least 1")
+	}
+	if opts.offset < 0 {
+		return browseOptions{}, invalidArgs("offset can't be negative")
+	}
+	if opts.sort != "newest" && opts.sort != "oldest" {
+		return browseOptions{}, invalidArgs("unknown sort order %q (use newest or oldest)", opts.sort)
+	}
+	if opts.cursor != "" {
+		publishedAt, id, err := decodeCursor(opts.cursor)
+		if err != nil {
+			return browseOptions{}, err
+		}
+		opts.publishedAt = publishedAt
+		opts.cursorID = id
+	}
+	return opts, nil
+}
+
+// params converts the options into the arguments for BrowsePostsForUser.
+func (opts browseOptions) params(userID uuid.UUID) (database.BrowsePostsForUserParams, error) {
+	params := database.BrowsePostsForUserParams{
+		UserID:      userID,
+		UnreadOnly:  opts.unread,
+		StarredOnly: opts.starred,
+		OldestFirst: opts.sort == "oldest",
+		PostLimit:   int32(opts.limit),
+		PostOffset:  int32(opts.offset),
+	}
+	if opts.feed != "" {
+		params.Feed = sql.NullString{String: opts.feed, Valid: true}
+	}
+	if opts.category != "" {
+		params.Category = sql.NullString{String: opts.category, Valid: true}
+	}
+	if opts.since != "" {
+		since, err := parseTimeArg(opts.since)
+		if err != nil {
+			return params, err
+		}
+		params.Since = sql.NullTime{Time: since, Valid: true}
+	}
+	if opts.until != "" {
+		until, err := parseTimeArg(opts.until)
+		if err != nil {
+			return params, err
+		}
+		params.Until = sql.NullTime{Time: until, Valid: true}
+	}
+	if opts.cursor != "" {
+		params.CursorPublishedAt = sql.NullTime{Time: opts.publishedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: opts.cursorID, Valid: true}
+	}
+	return params, nil
+}
+
+// wheres compiles the --where expression and --saved search, if given.
+func (opts browseOptions) wheres(s *state, user database.User) ([]*expr.Program, error) {
+	wheres := []*expr.Program{}
+	if opts.where != "" {
+		where, err := expr.Compile(opts.where)
+		if err != nil {
+			return nil, invalidArgs("invalid --where expression: %w", err)
+		}
+		wheres = append(wheres, where)
+	}
+	if opts.saved != "" {
+		saved, err := compileSavedSearch(s, user, opts.saved)
+		if err != nil {
+			return nil, err
+		}
+		wheres = append(wheres, saved)
+	}
+	return wheres, nil
+}
+
+type browsedPost struct {
+	database.BrowsePostsForUserRow
+	highlighted bool
+}
+
+// loadBrowsePosts fetches a page of posts and applies the user's mute and
+// highlight rules and any where expressions. Posts that are muted or don't
+// match are dropped and the page is topped up from the posts after it,
+// so a page still holds the requested number of posts.
+// It also reports whether there are more posts after the page.
+func loadBrowsePosts(s *state, user database.User, params database.BrowsePostsForUserParams, wheres []*expr.Program, showMuted bool) ([]browsedPost, bool, error) {
+	userRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	rules, err := filter.CompileAll(userRules)
+	if err != nil {
+		return nil, false, err
+	}
+
+	posts := []browsedPost{}
+	for {
+		rows, err := s.db.BrowsePostsForUser(context.Background(), params)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, row := range rows {
+			post := filter.FromBrowseRow(row)
+			if !matchesAll(wheres, post) {
+				continue
+			}
+			result := filter.Evaluate(rules, post)
+			if result.Muted && !showMuted {
+				continue
+			}
+			posts = append(posts, browsedPost{BrowsePostsForUserRow: row, highlighted: result.Highlighted})
+			if len(posts) == int(params.PostLimit) {
+				return posts, true, nil
+			}
+		}
+		if !filter.NextPage(&params, rows) {
+			return posts, false, nil
+		}
+	}
+}
+
+func matchesAll(programs []*expr.Program, post filter.Post) bool {
+	env := post.Env()
+	for _, program := range programs {
+		if !program.Match(env) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTimeArg accepts a date (2006-01-02), an RFC 3339 timestamp, or a
+// duration such as 48h meaning that long ago.
+func parseTimeArg(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().UTC().Add(-d), nil
+	}
+	return time.Time{}, invalidArgs("invalid time %q (use 2006-01-02, RFC 3339 or a duration like 24h)", value)
+}
+
+// encodeCursor builds an opaque token pointing just past the given post.
+func encodeCursor(publishedAt time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(publishedAt.UnixNano(), 10) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	invalid := invalidArgs("invalid cursor %q", cursor)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, invalid
+	}
+	nanos, idString, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, uuid.UUID{}, invalid
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, invalid
+	}
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, invalid
+	}
+	return time.Unix(0, unixNano).UTC(), id, nil
+}
+
+// terminalRenderOptions wraps post bodies to $COLUMNS (80 if unset) and only
+// adds colour when writing to a terminal and NO_COLOR isn't set.
+func terminalRenderOptions() render.Options {
+	opts := render.Options{}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		opts.Width = columns
+	}
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		opts.ANSI = os.Getenv("NO_COLOR") == ""
+	}
+	return opts
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const categoryUsage = "usage: category add <name> | rm <name> | rename <old> <new> | set <feed url> <name> | unset <feed url> | list"
+
+type categoryRecord struct {
+	Name string `json:"name"`
+}
+
+// handlerCategory manages the current user's categories. Categories belong to
+// a single user, so two users can file the same shared feed differently.
+func handlerCategory(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 1 {
+		return invalidArgs(categoryUsage)
+	}
+	subcommand, args := cmd.arguments[0], cmd.arguments[1:]
+	switch {
+	case subcommand == "add" && len(args) == 1:
+		return categoryAdd(s, user, args[0])
+	case subcommand == "rm" && len(args) == 1:
+		return categoryRemove(s, user, args[0])
+	case subcommand == "rename" && len(args) == 2:
+		return categoryRename(s, user, args[0], args[1])
+	case subcommand == "set" && len(args) == 2:
+		return categorySet(s, user, args[0], args[1])
+	case subcommand == "unset" && len(args) == 1:
+		return categoryUnset(s, user, args[0])
+	case subcommand == "list" && len(args) == 0:
+		return categoryList(s, user)
+	}
+	return invalidArgs(categoryUsage)
+}
+
+func categoryAdd(s *state, user database.User, name string) error {
+	params := database.CreateCategoryParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, UserID: user.ID}
+	category, err := s.db.CreateCategory(context.Background(), params)
+	if isUniqueViolation(err) {
+		return alreadyExists("You already have a category named %v.", name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Category created: %v\n", category.Name)
+	return nil
+}
+
+func categoryRemove(s *state, user database.User, name string) error {
+	removed, err := s.db.DeleteCategory(context.Background(), database.DeleteCategoryParams{UserID: user.ID, Name: name})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return notFound("No category named %v.", name)
+	}
+	fmt.Printf("Category removed: %v\n", name)
+	return nil
+}
+
+func categoryRename(s *state, user database.User, name, newName string) error {
+	renamed, err := s.db.RenameCategory(context.Background(), database.RenameCategoryParams{NewName: newName, UserID: user.ID, Name: name})
+	if isUniqueViolation(err) {
+		return alreadyExists("You already have a category named %v.", newName)
+	}
+	if err != nil {
+		return err
+	}
+	if renamed == 0 {
+		return notFound("No category named %v.", name)
+	}
+	fmt.Printf("Category %v renamed to %v\n", name, newName)
+	return nil
+}
+
+func categorySet(s *state, user database.User, feedURL, name string) error {
+	feedID, err := s.db.GetFeedID(context.Background(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No feed with URL %v.", feedURL)
+	}
+	if err != nil {
+		return err
+	}
+	updated, err := s.db.SetFeedFollowCategory(context.Background(), database.SetFeedFollowCategoryParams{UserID: user.ID, FeedID: feedID, Category: name})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return notFound("You don't follow %v or have no category named %v.", feedURL, name)
+	}
+	fmt.Printf("%v moved to %v\n", feedURL, name)
+	return nil
+}
+
+func categoryUnset(s *state, user database.User, feedURL string) error {
+	feedID, err := s.db.GetFeedID(context.Background(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No feed with URL %v.", feedURL)
+	}
+	if err != nil {
+		return err
+	}
+	updated, err := s.db.ClearFeedFollowCategory(context.Background(), database.ClearFeedFollowCategoryParams{UserID: user.ID, FeedID: feedID})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return notFound("You don't follow %v.", feedURL)
+	}
+	fmt.Printf("%v removed from its category\n", feedURL)
+	return nil
+}
+
+func categoryList(s *state, user database.User) error {
+	categories, err := s.db.GetCategoriesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if s.output != outputText {
+		records := []categoryRecord{}
+		for _, category := range categories {
+			records = append(records, categoryRecord{Name: category.Name})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	for _, category := range categories {
+		fmt.Printf("* %v\n", category.Name)
+	}
+	return nil
+}
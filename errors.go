@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// The kinds of errors a command can fail with. Each has its own exit code,
+// so scripts can tell a typo from a missing feed without parsing messages.
+var (
+	errInvalidArgs   = errors.New("invalid arguments")
+	errNotFound      = errors.New("not found")
+	errAlreadyExists = errors.New("already exists")
+	errUnauthorized  = errors.New("unauthorized")
+)
+
+const (
+	exitOK            = 0
+	exitFailure       = 1
+	exitInvalidArgs   = 2
+	exitNotFound      = 3
+	exitAlreadyExists = 4
+	exitUnauthorized  = 5
+)
+
+// commandError is an error of one of the kinds above. Its message is the
+// one the user sees; the kind only picks the exit code and prefix.
+type commandError struct {
+	kind error
+	err  error
+}
+
+func (e *commandError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+func newCommandError(kind error, format string, args ...any) error {
+	return &commandError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+func invalidArgs(format string, args ...any) error {
+	return newCommandError(errInvalidArgs, format, args...)
+}
+
+func notFound(format string, args ...any) error {
+	return newCommandError(errNotFound, format, args...)
+}
+
+func alreadyExists(format string, args ...any) error {
+	return newCommandError(errAlreadyExists, format, args...)
+}
+
+func unauthorized(format string, args ...any) error {
+	return newCommandError(errUnauthorized, format, args...)
+}
+
+// exitCode picks the exit code for err. Database errors that weren't given
+// a kind are mapped the way the API maps them to statuses.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errInvalidArgs):
+		return exitInvalidArgs
+	case errors.Is(err, errNotFound), errors.Is(err, sql.ErrNoRows):
+		return exitNotFound
+	case errors.Is(err, errAlreadyExists), isUniqueViolation(err):
+		return exitAlreadyExists
+	case errors.Is(err, errUnauthorized):
+		return exitUnauthorized
+	}
+	return exitFailure
+}
+
+// errorMessage is how err is reported before gator exits with exitCode(err).
+func errorMessage(err error) string {
+	prefix := "Error Found"
+	switch exitCode(err) {
+	case exitInvalidArgs:
+		prefix = "Invalid arguments"
+	case exitNotFound:
+		prefix = "Not found"
+	case exitAlreadyExists:
+		prefix = "Already exists"
+	case exitUnauthorized:
+		prefix = "Not allowed"
+	}
+	return fmt.Sprintf("%v: %v", prefix, err)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    int
+		message string
+	}{
+		{nil, exitOK, ""},
+		{errors.New("disk full"), exitFailure, "Error Found: disk full"},
+		{invalidArgs("usage: login <name>"), exitInvalidArgs, "Invalid arguments: usage: login <name>"},
+		{notFound("No feed with URL %v.", "x"), exitNotFound, "Not found: No feed with URL x."},
+		{alreadyExists("There's already a user named %v.", "susan"), exitAlreadyExists, "Already exists: There's already a user named susan."},
+		{unauthorized("Not logged in."), exitUnauthorized, "Not allowed: Not logged in."},
+		{errBadLogin, exitUnauthorized, "Not allowed: Wrong user name or password."},
+		{fmt.Errorf("saved search x: %w", invalidArgs("bad")), exitInvalidArgs, "Invalid arguments: saved search x: bad"},
+		{sql.ErrNoRows, exitNotFound, "Not found: " + sql.ErrNoRows.Error()},
+		{errFakeUnique, exitAlreadyExists, "Already exists: " + errFakeUnique.Error()},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.code {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.code)
+		}
+		if tt.err == nil {
+			continue
+		}
+		if got := errorMessage(tt.err); got != tt.message {
+			t.Errorf("errorMessage(%v) = %q, want %q", tt.err, got, tt.message)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(tokenEnv, "")
+	if got := run([]string{"users"}); got != exitFailure {
+		t.Errorf("without a config file, run() = %d, want %d", got, exitFailure)
+	}
+
+	dbURL := "sqlite:" + filepath.Join(home, "gator.db")
+	config := fmt.Sprintf(`{"db_url": %q}`, dbURL)
+	if err := os.WriteFile(filepath.Join(home, ".gatorconfig.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		args  []string
+		stdin string
+		code  int
+	}{
+		{nil, "", exitInvalidArgs},
+		{[]string{"-o"}, "", exitInvalidArgs},
+		{[]string{"users"}, "", exitFailure}, // the schema isn't set up yet
+		{[]string{"migrate", "up"}, "", exitOK},
+		{[]string{"frobnicate"}, "", exitInvalidArgs},
+		{[]string{"users"}, "", exitOK},
+		{[]string{"follow", "https://example.com/feed.xml"}, "", exitUnauthorized},
+		{[]string{"feed", "info", "https://example.com/feed.xml"}, "", exitUnauthorized},
+		{[]string{"serve", "--port", "80"}, "", exitInvalidArgs},
+		{[]string{"serve", "now"}, "", exitInvalidArgs},
+		{[]string{"register", "david"}, "correct horse\ncorrect horse\n", exitOK},
+		{[]string{"browse", "--newest"}, "", exitInvalidArgs},
+		{[]string{"export", "--format"}, "", exitInvalidArgs},
+		{[]string{"fever-key", "secret"}, "", exitInvalidArgs},
+		{[]string{"fever-key", "--remove"}, "", exitNotFound},
+		{[]string{"admin", "revoke", "david"}, "", exitUnauthorized},
+		{[]string{"register", "susan"}, "correct horse\ncorrect horse\n", exitOK},
+		{[]string{"login", "david"}, "correct horse\n", exitOK},
+		{[]string{"user", "delete", "david", "--transfer-to", "susan", "--yes"}, "", exitUnauthorized},
+	}
+	for _, step := range steps {
+		withStdin(t, step.stdin, func() {
+			if got := run(step.args); got != step.code {
+				t.Errorf("run(%q) = %d, want %d", step.args, got, step.code)
+			}
+		})
+	}
+}
+
+// withStdin runs f with input as stdin, read from a file rather than a
+// terminal so passwords are read from it.
+func withStdin(t *testing.T, input string, f func()) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	oldStdin, oldReader := os.Stdin, stdin
+	os.Stdin, stdin = file, bufio.NewReader(file)
+	defer func() { os.Stdin, stdin = oldStdin, oldReader }()
+	f()
+}
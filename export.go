@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+	"github.com/curtisbraxdale/blog-gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+type exportOptions struct {
+	format   string
+	category string
+	saved    string
+	starred  bool
+	limit    int
+	title    string
+	link     string
+}
+
+func parseExportOptions(args []string) (exportOptions, error) {
+	opts := exportOptions{}
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&opts.format, "format", "rss", "rss or atom")
+	flags.StringVar(&opts.category, "category", "", "only posts from feeds in this category")
+	flags.StringVar(&opts.saved, "saved", "", "only posts matching this saved search")
+	flags.BoolVar(&opts.starred, "starred", false, "only starred posts")
+	flags.IntVar(&opts.limit, "limit", 50, "number of posts to include")
+	flags.StringVar(&opts.title, "title", "", "title of the generated feed")
+	flags.StringVar(&opts.link, "link", "", "link of the generated feed")
+	if err := flags.Parse(args); err != nil {
+		return exportOptions{}, invalidArgs("%v", err)
+	}
+	if flags.NArg() > 0 {
+		return exportOptions{}, invalidArgs("unexpected argument %q", flags.Arg(0))
+	}
+	if opts.format != "rss" && opts.format != "atom" {
+		return exportOptions{}, invalidArgs("unknown format %q (use rss or atom)", opts.format)
+	}
+	if opts.limit < 1 {
+		return exportOptions{}, invalidArgs("limit must be at least 1")
+	}
+	return opts, nil
+}
+
+// handlerExport writes the current user's timeline (or a category, saved
+// search or their starred posts) to stdout as an RSS or Atom feed, so other
+// readers can subscribe to what gator has collected.
+func handlerExport(s *state, cmd command, user database.User) error {
+	opts, err := parseExportOptions(cmd.arguments)
+	if err != nil {
+		return err
+	}
+	return writeTimeline(os.Stdout, s, user, opts)
+}
+
+func writeTimeline(w io.Writer, s *state, user database.User, opts exportOptions) error {
+	params := database.BrowsePostsForUserParams{UserID: user.ID, StarredOnly: opts.starred, PostLimit: int32(opts.limit)}
+	selection := []string{}
+	if opts.category != "" {
+		params.Category = sql.NullString{String: opts.category, Valid: true}
+		selection = append(selection, opts.category)
+	}
+	wheres := []*expr.Program{}
+	if opts.saved != "" {
+		saved, err := compileSavedSearch(s, user, opts.saved)
+		if err != nil {
+			return err
+		}
+		wheres = append(wheres, saved)
+		selection = append(selection, opts.saved)
+	}
+	if opts.starred {
+		selection = append(selection, "Starred")
+	}
+	posts, _, err := loadBrowsePosts(s, user, params, wheres, false)
+	if err != nil {
+		return err
+	}
+
+	title := opts.title
+	if title == "" {
+		title = strings.Join(append([]string{user.Name + "'s gator timeline"}, selection...), " - ")
+	}
+	channel, entries := timelineFeed(user, title, opts.link, selection, posts)
+	if opts.format == "atom" {
+		return rss.WriteAtom(w, channel, entries)
+	}
+	return rss.WriteRSS(w, channel, entries)
+}
+
+// timelineFeed converts posts into a feed. The feed's ID is derived from the
+// user and the selection so it stays the same between exports. Without a
+// link, the feed links to its newest post, since RSS needs a channel link.
+func timelineFeed(user database.User, title, link string, selection []string, posts []browsedPost) (rss.Channel, []rss.Entry) {
+	feedID := uuid.NewSHA1(user.ID, []byte(strings.Join(selection, "\x00")))
+	if link == "" && len(posts) > 0 {
+		link = posts[0].Url
+	}
+	channel := rss.Channel{
+		ID:          "urn:uuid:" + feedID.String(),
+		Title:       title,
+		Link:        link,
+		Description: "Posts collected by gator for " + user.Name,
+		Updated:     time.Now().UTC(),
+	}
+	entries := []rss.Entry{}
+	for _, post := range posts {
+		var categories []string
+		if post.Categories != "" {
+			categories = strings.Split(post.Categories, ", ")
+		}
+		entries = append(entries, rss.Entry{
+			ID:          post.Url,
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: post.Description,
+			Content:     post.Content,
+			Author:      post.Author,
+			Categories:  categories,
+			Published:   post.PublishedAt.Time,
+		})
+	}
+	if len(posts) > 0 && posts[0].PublishedAt.Valid {
+		channel.Updated = posts[0].PublishedAt.Time
+	}
+	return channel, entries
+}
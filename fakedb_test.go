@@ -0,0 +1,973 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeDB keeps the tables the command handlers use in memory, so handler
+// tests run without a database server or file. Queries nothing calls yet
+// aren't implemented and panic through the nil Querier it embeds. It
+// reports constraint violations the way Postgres does and cascades deletes
+// like the schema, but it isn't safe for concurrent use.
+type fakeDB struct {
+	database.Querier
+
+	users       []*database.User
+	sessions    []*database.Session
+	apiTokens   []*database.ApiToken
+	feverKeys   []*database.FeverApiKey
+	feeds       []*database.Feed
+	follows     []*database.FeedFollow
+	categories  []*database.Category
+	posts       []*database.Post
+	postStates  []*database.PostState
+	rules       []*database.Rule
+	savedSearch []*database.SavedSearch
+	icons       []*database.FeedIcon
+	purgedPosts []*database.PurgedPost
+	retention   database.RetentionSetting
+	lastPostSeq int64
+}
+
+var (
+	errFakeUnique     = &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}
+	errFakeForeignKey = &pq.Error{Code: "23503", Message: "insert or update violates foreign key constraint"}
+)
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{retention: database.RetentionSetting{ID: 1, KeepLatest: 20}}
+}
+
+func fakeNow() sql.NullTime {
+	return sql.NullTime{Time: time.Now().UTC(), Valid: true}
+}
+
+func (f *fakeDB) user(id uuid.UUID) *database.User {
+	for _, user := range f.users {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) feed(id uuid.UUID) *database.Feed {
+	for _, feed := range f.feeds {
+		if feed.ID == id {
+			return feed
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) follow(userID, feedID uuid.UUID) *database.FeedFollow {
+	for _, follow := range f.follows {
+		if follow.UserID == userID && follow.FeedID == feedID {
+			return follow
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) category(id uuid.NullUUID) *database.Category {
+	for _, category := range f.categories {
+		if id.Valid && category.ID == id.UUID {
+			return category
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) postState(userID, postID uuid.UUID) *database.PostState {
+	for _, state := range f.postStates {
+		if state.UserID == userID && state.PostID == postID {
+			return state
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) deleteUser(id uuid.UUID) {
+	for _, feed := range slices.Clone(f.feeds) {
+		if feed.UserID == id {
+			f.deleteFeed(feed.ID)
+		}
+	}
+	f.users = slices.DeleteFunc(f.users, func(u *database.User) bool { return u.ID == id })
+	f.sessions = slices.DeleteFunc(f.sessions, func(s *database.Session) bool { return s.UserID == id })
+	f.apiTokens = slices.DeleteFunc(f.apiTokens, func(t *database.ApiToken) bool { return t.UserID == id })
+	f.feverKeys = slices.DeleteFunc(f.feverKeys, func(k *database.FeverApiKey) bool { return k.UserID == id })
+	f.follows = slices.DeleteFunc(f.follows, func(ff *database.FeedFollow) bool { return ff.UserID == id })
+	f.categories = slices.DeleteFunc(f.categories, func(c *database.Category) bool { return c.UserID == id })
+	f.postStates = slices.DeleteFunc(f.postStates, func(s *database.PostState) bool { return s.UserID == id })
+	f.rules = slices.DeleteFunc(f.rules, func(r *database.Rule) bool { return r.UserID == id })
+	f.savedSearch = slices.DeleteFunc(f.savedSearch, func(s *database.SavedSearch) bool { return s.UserID == id })
+}
+
+func (f *fakeDB) deleteFeed(id uuid.UUID) {
+	for _, post := range slices.Clone(f.posts) {
+		if post.FeedID == id {
+			f.deletePost(post.ID)
+		}
+	}
+	f.feeds = slices.DeleteFunc(f.feeds, func(feed *database.Feed) bool { return feed.ID == id })
+	f.follows = slices.DeleteFunc(f.follows, func(ff *database.FeedFollow) bool { return ff.FeedID == id })
+	f.icons = slices.DeleteFunc(f.icons, func(i *database.FeedIcon) bool { return i.FeedID == id })
+	f.purgedPosts = slices.DeleteFunc(f.purgedPosts, func(p *database.PurgedPost) bool { return p.FeedID == id })
+}
+
+func (f *fakeDB) deletePost(id uuid.UUID) {
+	f.posts = slices.DeleteFunc(f.posts, func(p *database.Post) bool { return p.ID == id })
+	f.postStates = slices.DeleteFunc(f.postStates, func(s *database.PostState) bool { return s.PostID == id })
+}
+
+// Users
+
+func (f *fakeDB) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	if _, err := f.GetUser(ctx, arg.Name); err == nil {
+		return database.User{}, errFakeUnique
+	}
+	admins, _ := f.CountAdmins(ctx)
+	user := database.User{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, PasswordHash: arg.PasswordHash, IsAdmin: arg.CanBeAdmin && admins == 0}
+	f.users = append(f.users, &user)
+	return user, nil
+}
+
+func (f *fakeDB) GetUser(ctx context.Context, name string) (database.User, error) {
+	for _, user := range f.users {
+		if user.Name == name {
+			return *user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) GetUsername(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if user := f.user(id); user != nil {
+		return *user, nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) GetUsers(ctx context.Context) ([]string, error) {
+	names := []string{}
+	for _, user := range f.users {
+		names = append(names, user.Name)
+	}
+	return names, nil
+}
+
+func (f *fakeDB) ResetDB(ctx context.Context) error {
+	for _, user := range slices.Clone(f.users) {
+		f.deleteUser(user.ID)
+	}
+	return nil
+}
+
+func (f *fakeDB) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	if user := f.user(arg.ID); user != nil {
+		user.PasswordHash, user.UpdatedAt = arg.PasswordHash, arg.UpdatedAt
+	}
+	return nil
+}
+
+func (f *fakeDB) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (int64, error) {
+	for _, user := range f.users {
+		if user.Name == arg.Name {
+			user.IsAdmin, user.UpdatedAt = arg.IsAdmin, arg.UpdatedAt
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeDB) CountAdmins(ctx context.Context) (int64, error) {
+	var admins int64
+	for _, user := range f.users {
+		if user.IsAdmin {
+			admins++
+		}
+	}
+	return admins, nil
+}
+
+func (f *fakeDB) RenameUser(ctx context.Context, arg database.RenameUserParams) (int64, error) {
+	user := f.user(arg.ID)
+	if user == nil {
+		return 0, nil
+	}
+	if other, err := f.GetUser(ctx, arg.Name); err == nil && other.ID != arg.ID {
+		return 0, errFakeUnique
+	}
+	user.Name, user.UpdatedAt = arg.Name, arg.UpdatedAt
+	return 1, nil
+}
+
+// InTx runs f straight away. The fake can't roll back, so tests of what a
+// failed transaction leaves behind use a real database.
+func (f *fakeDB) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	return fn(f)
+}
+
+func (f *fakeDB) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	if f.user(id) == nil {
+		return 0, nil
+	}
+	f.deleteUser(id)
+	return 1, nil
+}
+
+func (f *fakeDB) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	stats := database.GetUserStatsRow{}
+	for _, follow := range f.follows {
+		if follow.UserID == userID {
+			stats.Follows++
+		}
+	}
+	for _, feed := range f.feeds {
+		if feed.UserID == userID {
+			stats.FeedsAdded++
+		}
+	}
+	for _, state := range f.postStates {
+		if state.UserID == userID && state.ReadAt.Valid {
+			stats.ReadPosts++
+		}
+		if state.UserID == userID && state.StarredAt.Valid {
+			stats.StarredPosts++
+		}
+	}
+	for _, token := range f.apiTokens {
+		if token.UserID == userID {
+			stats.ApiTokens++
+		}
+	}
+	return stats, nil
+}
+
+// Sessions and tokens
+
+func (f *fakeDB) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	if f.user(arg.UserID) == nil {
+		return errFakeForeignKey
+	}
+	for _, session := range f.sessions {
+		if session.TokenHash == arg.TokenHash {
+			return errFakeUnique
+		}
+	}
+	f.sessions = append(f.sessions, &database.Session{ID: arg.ID, CreatedAt: arg.CreatedAt, ExpiresAt: arg.ExpiresAt, TokenHash: arg.TokenHash, UserID: arg.UserID})
+	return nil
+}
+
+func (f *fakeDB) GetUserBySession(ctx context.Context, tokenHash string) (database.User, error) {
+	for _, session := range f.sessions {
+		if session.TokenHash == tokenHash && session.ExpiresAt.After(time.Now()) {
+			return f.GetUsername(ctx, session.UserID)
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) DeleteSession(ctx context.Context, tokenHash string) error {
+	f.sessions = slices.DeleteFunc(f.sessions, func(s *database.Session) bool { return s.TokenHash == tokenHash })
+	return nil
+}
+
+func (f *fakeDB) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	f.sessions = slices.DeleteFunc(f.sessions, func(s *database.Session) bool { return s.UserID == userID })
+	return nil
+}
+
+func (f *fakeDB) CreateAPIToken(ctx context.Context, arg database.CreateAPITokenParams) error {
+	if f.user(arg.UserID) == nil {
+		return errFakeForeignKey
+	}
+	for _, token := range f.apiTokens {
+		if token.TokenHash == arg.TokenHash || token.UserID == arg.UserID && token.Name == arg.Name {
+			return errFakeUnique
+		}
+	}
+	f.apiTokens = append(f.apiTokens, &database.ApiToken{ID: arg.ID, CreatedAt: arg.CreatedAt, Name: arg.Name, TokenHash: arg.TokenHash, Scope: arg.Scope, ExpiresAt: arg.ExpiresAt, UserID: arg.UserID})
+	return nil
+}
+
+func (f *fakeDB) GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	tokens := []database.ApiToken{}
+	for _, token := range f.apiTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, *token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b database.ApiToken) int { return strings.Compare(a.Name, b.Name) })
+	return tokens, nil
+}
+
+func (f *fakeDB) DeleteAPIToken(ctx context.Context, arg database.DeleteAPITokenParams) (int64, error) {
+	before := len(f.apiTokens)
+	f.apiTokens = slices.DeleteFunc(f.apiTokens, func(t *database.ApiToken) bool { return t.UserID == arg.UserID && t.Name == arg.Name })
+	return int64(before - len(f.apiTokens)), nil
+}
+
+func (f *fakeDB) GetUserByAPIToken(ctx context.Context, tokenHash string) (database.GetUserByAPITokenRow, error) {
+	for _, token := range f.apiTokens {
+		if token.TokenHash != tokenHash || token.ExpiresAt.Valid && !token.ExpiresAt.Time.After(time.Now()) {
+			continue
+		}
+		user, err := f.GetUsername(ctx, token.UserID)
+		return database.GetUserByAPITokenRow{User: user, TokenID: token.ID, Scope: token.Scope}, err
+	}
+	return database.GetUserByAPITokenRow{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) TouchAPIToken(ctx context.Context, arg database.TouchAPITokenParams) error {
+	for _, token := range f.apiTokens {
+		if token.ID == arg.ID {
+			token.LastUsedAt = arg.LastUsedAt
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) SetFeverAPIKey(ctx context.Context, arg database.SetFeverAPIKeyParams) error {
+	if f.user(arg.UserID) == nil {
+		return errFakeForeignKey
+	}
+	for _, key := range f.feverKeys {
+		if key.UserID != arg.UserID && key.ApiKey == arg.ApiKey {
+			return errFakeUnique
+		}
+	}
+	f.feverKeys = slices.DeleteFunc(f.feverKeys, func(k *database.FeverApiKey) bool { return k.UserID == arg.UserID })
+	f.feverKeys = append(f.feverKeys, &database.FeverApiKey{UserID: arg.UserID, CreatedAt: arg.CreatedAt, ApiKey: arg.ApiKey})
+	return nil
+}
+
+func (f *fakeDB) DeleteFeverAPIKey(ctx context.Context, userID uuid.UUID) (int64, error) {
+	before := len(f.feverKeys)
+	f.feverKeys = slices.DeleteFunc(f.feverKeys, func(k *database.FeverApiKey) bool { return k.UserID == userID })
+	return int64(before - len(f.feverKeys)), nil
+}
+
+// Feeds
+
+func (f *fakeDB) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	if f.user(arg.UserID) == nil {
+		return database.Feed{}, errFakeForeignKey
+	}
+	if _, err := f.GetFeedByURL(ctx, arg.Url); err == nil {
+		return database.Feed{}, errFakeUnique
+	}
+	feed := database.Feed{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, Url: arg.Url, UserID: arg.UserID}
+	f.feeds = append(f.feeds, &feed)
+	return feed, nil
+}
+
+func (f *fakeDB) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	rows := []database.GetFeedsRow{}
+	for _, feed := range f.feeds {
+		rows = append(rows, database.GetFeedsRow{Name: feed.Name, Url: feed.Url, UserID: feed.UserID, Title: feed.Title, SiteUrl: feed.SiteUrl, Description: feed.Description})
+	}
+	return rows, nil
+}
+
+func (f *fakeDB) GetFeedID(ctx context.Context, url string) (uuid.UUID, error) {
+	feed, err := f.GetFeedByURL(ctx, url)
+	return feed.ID, err
+}
+
+func (f *fakeDB) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	for _, feed := range f.feeds {
+		if feed.Url == url {
+			return *feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	f.deleteFeed(id)
+	return nil
+}
+
+func (f *fakeDB) RenameFeed(ctx context.Context, arg database.RenameFeedParams) error {
+	if feed := f.feed(arg.ID); feed != nil {
+		feed.Name, feed.UpdatedAt = arg.Name, arg.UpdatedAt
+	}
+	return nil
+}
+
+func (f *fakeDB) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	var moved int64
+	for _, feed := range f.feeds {
+		if feed.UserID == arg.FromUserID {
+			feed.UserID, feed.UpdatedAt = arg.ToUserID, arg.UpdatedAt
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (f *fakeDB) GetFeedFollowerNames(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	names := []string{}
+	for _, follow := range f.follows {
+		if follow.FeedID == feedID {
+			names = append(names, f.user(follow.UserID).Name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (f *fakeDB) GetFeedPostStats(ctx context.Context, feedID uuid.UUID) (database.GetFeedPostStatsRow, error) {
+	stats := database.GetFeedPostStatsRow{}
+	for _, post := range f.posts {
+		if post.FeedID != feedID {
+			continue
+		}
+		stats.Posts++
+		published := post.PublishedAt.Time
+		if stats.Posts == 1 || published.Before(stats.OldestPublishedAt) {
+			stats.OldestPublishedAt = published
+		}
+		if stats.Posts == 1 || published.After(stats.NewestPublishedAt) {
+			stats.NewestPublishedAt = published
+		}
+	}
+	if stats.Posts == 0 {
+		stats.OldestPublishedAt = time.Unix(0, 0).UTC()
+		stats.NewestPublishedAt = time.Unix(0, 0).UTC()
+	}
+	return stats, nil
+}
+
+// GetNextFeedToFetch picks the feed fetched longest ago, feeds never fetched
+// first.
+func (f *fakeDB) GetNextFeedToFetch(ctx context.Context) (database.GetNextFeedToFetchRow, error) {
+	var next *database.Feed
+	for _, feed := range f.feeds {
+		switch {
+		case next == nil:
+			next = feed
+		case !next.LastFetchedAt.Valid:
+		case !feed.LastFetchedAt.Valid || feed.LastFetchedAt.Time.Before(next.LastFetchedAt.Time):
+			next = feed
+		}
+	}
+	if next == nil {
+		return database.GetNextFeedToFetchRow{}, sql.ErrNoRows
+	}
+	return database.GetNextFeedToFetchRow{ID: next.ID, Name: next.Name, Url: next.Url}, nil
+}
+
+func (f *fakeDB) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	if feed := f.feed(id); feed != nil {
+		feed.LastFetchedAt, feed.UpdatedAt = fakeNow(), fakeNow()
+	}
+	return nil
+}
+
+func (f *fakeDB) SetFeedLastError(ctx context.Context, arg database.SetFeedLastErrorParams) error {
+	if feed := f.feed(arg.ID); feed != nil {
+		feed.LastError = arg.LastError
+	}
+	return nil
+}
+
+func (f *fakeDB) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	if feed := f.feed(arg.ID); feed != nil {
+		feed.Title, feed.SiteUrl, feed.Description = arg.Title, arg.SiteUrl, arg.Description
+		feed.Language, feed.ImageUrl, feed.Generator = arg.Language, arg.ImageUrl, arg.Generator
+	}
+	return nil
+}
+
+func (f *fakeDB) GetFeedIconFetchedAt(ctx context.Context, feedID uuid.UUID) (time.Time, error) {
+	for _, icon := range f.icons {
+		if icon.FeedID == feedID {
+			return icon.FetchedAt, nil
+		}
+	}
+	return time.Time{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) SetFeedIcon(ctx context.Context, arg database.SetFeedIconParams) error {
+	if f.feed(arg.FeedID) == nil {
+		return errFakeForeignKey
+	}
+	f.icons = slices.DeleteFunc(f.icons, func(i *database.FeedIcon) bool { return i.FeedID == arg.FeedID })
+	f.icons = append(f.icons, &database.FeedIcon{FeedID: arg.FeedID, FetchedAt: arg.FetchedAt, SourceUrl: arg.SourceUrl, ContentType: arg.ContentType, Data: arg.Data, Hash: arg.Hash})
+	return nil
+}
+
+// Follows and categories
+
+func (f *fakeDB) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	user, feed := f.user(arg.UserID), f.feed(arg.FeedID)
+	if user == nil || feed == nil {
+		return database.CreateFeedFollowRow{}, errFakeForeignKey
+	}
+	if f.follow(arg.UserID, arg.FeedID) != nil {
+		return database.CreateFeedFollowRow{}, errFakeUnique
+	}
+	follow := database.FeedFollow{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, UserID: arg.UserID, FeedID: arg.FeedID}
+	f.follows = append(f.follows, &follow)
+	return database.CreateFeedFollowRow{ID: follow.ID, CreatedAt: follow.CreatedAt, UpdatedAt: follow.UpdatedAt, UserID: follow.UserID, FeedID: follow.FeedID, FeedName: feed.Name, UserName: user.Name}, nil
+}
+
+func (f *fakeDB) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	f.follows = slices.DeleteFunc(f.follows, func(ff *database.FeedFollow) bool { return ff.UserID == arg.UserID && ff.FeedID == arg.FeedID })
+	return nil
+}
+
+// GetFeedFollowsForUser orders follows by category, uncategorized last, then
+// by feed name.
+func (f *fakeDB) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows := []database.GetFeedFollowsForUserRow{}
+	for _, follow := range f.follows {
+		if follow.UserID != userID {
+			continue
+		}
+		feed := f.feed(follow.FeedID)
+		row := database.GetFeedFollowsForUserRow{ID: follow.ID, FeedID: feed.ID, FeedName: feed.Name, FeedUrl: feed.Url, UserName: f.user(userID).Name}
+		if category := f.category(follow.CategoryID); category != nil {
+			row.CategoryName = sql.NullString{String: category.Name, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetFeedFollowsForUserRow) int {
+		if a.CategoryName.Valid != b.CategoryName.Valid {
+			if a.CategoryName.Valid {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(a.CategoryName.String, b.CategoryName.String); c != 0 {
+			return c
+		}
+		return strings.Compare(a.FeedName, b.FeedName)
+	})
+	return rows, nil
+}
+
+func (f *fakeDB) CreateCategory(ctx context.Context, arg database.CreateCategoryParams) (database.Category, error) {
+	if f.user(arg.UserID) == nil {
+		return database.Category{}, errFakeForeignKey
+	}
+	for _, category := range f.categories {
+		if category.UserID == arg.UserID && category.Name == arg.Name {
+			return database.Category{}, errFakeUnique
+		}
+	}
+	category := database.Category{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, UserID: arg.UserID}
+	f.categories = append(f.categories, &category)
+	return category, nil
+}
+
+func (f *fakeDB) GetCategoriesForUser(ctx context.Context, userID uuid.UUID) ([]database.Category, error) {
+	categories := []database.Category{}
+	for _, category := range f.categories {
+		if category.UserID == userID {
+			categories = append(categories, *category)
+		}
+	}
+	slices.SortFunc(categories, func(a, b database.Category) int { return strings.Compare(a.Name, b.Name) })
+	return categories, nil
+}
+
+func (f *fakeDB) RenameCategory(ctx context.Context, arg database.RenameCategoryParams) (int64, error) {
+	var renamed *database.Category
+	for _, category := range f.categories {
+		if category.UserID != arg.UserID {
+			continue
+		}
+		if category.Name == arg.Name {
+			renamed = category
+		} else if category.Name == arg.NewName {
+			return 0, errFakeUnique
+		}
+	}
+	if renamed == nil {
+		return 0, nil
+	}
+	renamed.Name, renamed.UpdatedAt = arg.NewName, fakeNow()
+	return 1, nil
+}
+
+func (f *fakeDB) DeleteCategory(ctx context.Context, arg database.DeleteCategoryParams) (int64, error) {
+	for _, category := range f.categories {
+		if category.UserID != arg.UserID || category.Name != arg.Name {
+			continue
+		}
+		for _, follow := range f.follows {
+			if follow.CategoryID.Valid && follow.CategoryID.UUID == category.ID {
+				follow.CategoryID = uuid.NullUUID{}
+			}
+		}
+		f.categories = slices.DeleteFunc(f.categories, func(c *database.Category) bool { return c == category })
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (f *fakeDB) SetFeedFollowCategory(ctx context.Context, arg database.SetFeedFollowCategoryParams) (int64, error) {
+	follow := f.follow(arg.UserID, arg.FeedID)
+	if follow == nil {
+		return 0, nil
+	}
+	for _, category := range f.categories {
+		if category.UserID == arg.UserID && category.Name == arg.Category {
+			follow.CategoryID, follow.UpdatedAt = uuid.NullUUID{UUID: category.ID, Valid: true}, fakeNow()
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeDB) ClearFeedFollowCategory(ctx context.Context, arg database.ClearFeedFollowCategoryParams) (int64, error) {
+	follow := f.follow(arg.UserID, arg.FeedID)
+	if follow == nil {
+		return 0, nil
+	}
+	follow.CategoryID, follow.UpdatedAt = uuid.NullUUID{}, fakeNow()
+	return 1, nil
+}
+
+// Posts
+
+func (f *fakeDB) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	if f.feed(arg.FeedID) == nil {
+		return database.Post{}, errFakeForeignKey
+	}
+	for _, post := range f.posts {
+		if post.Url == arg.Url {
+			return database.Post{}, errFakeUnique
+		}
+	}
+	f.lastPostSeq++
+	post := database.Post{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Title: arg.Title, Url: arg.Url, Description: arg.Description, PublishedAt: arg.PublishedAt, FeedID: arg.FeedID, Content: arg.Content, Author: arg.Author, Categories: arg.Categories, Seq: f.lastPostSeq}
+	f.posts = append(f.posts, &post)
+	return post, nil
+}
+
+// comparePosts orders posts by when they were published, then by id, like
+// the (published_at, id) row comparisons in the queries.
+func comparePosts(aPublished time.Time, aID uuid.UUID, bPublished time.Time, bID uuid.UUID) int {
+	if c := aPublished.Compare(bPublished); c != 0 {
+		return c
+	}
+	return bytes.Compare(aID[:], bID[:])
+}
+
+func (f *fakeDB) BrowsePostsForUser(ctx context.Context, arg database.BrowsePostsForUserParams) ([]database.BrowsePostsForUserRow, error) {
+	rows := []database.BrowsePostsForUserRow{}
+	for _, follow := range f.follows {
+		if follow.UserID != arg.UserID {
+			continue
+		}
+		feed := f.feed(follow.FeedID)
+		category := sql.NullString{}
+		if c := f.category(follow.CategoryID); c != nil {
+			category = sql.NullString{String: c.Name, Valid: true}
+		}
+		for _, post := range f.posts {
+			if post.FeedID != feed.ID {
+				continue
+			}
+			row := database.BrowsePostsForUserRow{ID: post.ID, Title: post.Title, Url: post.Url, Description: post.Description, PublishedAt: post.PublishedAt, FeedID: post.FeedID, Content: post.Content, Author: post.Author, Categories: post.Categories, FeedName: feed.Name, FeedUrl: feed.Url, CategoryName: category}
+			if state := f.postState(arg.UserID, post.ID); state != nil {
+				row.ReadAt, row.StarredAt = state.ReadAt, state.StarredAt
+			}
+			if browseMatches(arg, row) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	slices.SortFunc(rows, func(a, b database.BrowsePostsForUserRow) int {
+		c := comparePosts(a.PublishedAt.Time, a.ID, b.PublishedAt.Time, b.ID)
+		if !arg.OldestFirst {
+			c = -c
+		}
+		return c
+	})
+	offset := min(int(arg.PostOffset), len(rows))
+	rows = rows[offset:]
+	return rows[:min(int(arg.PostLimit), len(rows))], nil
+}
+
+func browseMatches(arg database.BrowsePostsForUserParams, row database.BrowsePostsForUserRow) bool {
+	published := row.PublishedAt.Time
+	switch {
+	case arg.Feed.Valid && row.FeedUrl != arg.Feed.String && row.FeedName != arg.Feed.String:
+		return false
+	case arg.Category.Valid && (!row.CategoryName.Valid || row.CategoryName.String != arg.Category.String):
+		return false
+	case arg.Since.Valid && published.Before(arg.Since.Time):
+		return false
+	case arg.Until.Valid && !published.Before(arg.Until.Time):
+		return false
+	case arg.UnreadOnly && row.ReadAt.Valid:
+		return false
+	case arg.StarredOnly && !row.StarredAt.Valid:
+		return false
+	case arg.ItemIds != nil && !slices.Contains(arg.ItemIds, hex.EncodeToString(row.ID[:8])):
+		return false
+	}
+	if arg.CursorPublishedAt.Valid {
+		c := comparePosts(published, row.ID, arg.CursorPublishedAt.Time, arg.CursorID.UUID)
+		if arg.OldestFirst && c <= 0 || !arg.OldestFirst && c >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeDB) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	state, err := f.upsertPostState(arg.UserID, arg.PostID)
+	if err == nil && !state.ReadAt.Valid {
+		state.ReadAt = fakeNow()
+	}
+	return err
+}
+
+func (f *fakeDB) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	state, err := f.upsertPostState(arg.UserID, arg.PostID)
+	if err == nil && !state.StarredAt.Valid {
+		state.StarredAt = fakeNow()
+	}
+	return err
+}
+
+func (f *fakeDB) upsertPostState(userID, postID uuid.UUID) (*database.PostState, error) {
+	if state := f.postState(userID, postID); state != nil {
+		return state, nil
+	}
+	if f.user(userID) == nil || !slices.ContainsFunc(f.posts, func(p *database.Post) bool { return p.ID == postID }) {
+		return nil, errFakeForeignKey
+	}
+	state := &database.PostState{UserID: userID, PostID: postID}
+	f.postStates = append(f.postStates, state)
+	return state, nil
+}
+
+// Rules and saved searches
+
+func (f *fakeDB) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	if f.user(arg.UserID) == nil {
+		return database.Rule{}, errFakeForeignKey
+	}
+	rule := database.Rule{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, UserID: arg.UserID, Field: arg.Field, Pattern: arg.Pattern, IsRegex: arg.IsRegex, Action: arg.Action}
+	f.rules = append(f.rules, &rule)
+	return rule, nil
+}
+
+func (f *fakeDB) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	rules := []database.Rule{}
+	for _, rule := range f.rules {
+		if rule.UserID == userID {
+			rules = append(rules, *rule)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakeDB) GetRulesForFeedFollowers(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedFollowersRow, error) {
+	rules := []database.GetRulesForFeedFollowersRow{}
+	for _, rule := range f.rules {
+		follow := f.follow(rule.UserID, feedID)
+		if follow == nil {
+			continue
+		}
+		row := database.GetRulesForFeedFollowersRow{ID: rule.ID, CreatedAt: rule.CreatedAt, UpdatedAt: rule.UpdatedAt, UserID: rule.UserID, Field: rule.Field, Pattern: rule.Pattern, IsRegex: rule.IsRegex, Action: rule.Action}
+		if category := f.category(follow.CategoryID); category != nil {
+			row.CategoryName = sql.NullString{String: category.Name, Valid: true}
+		}
+		rules = append(rules, row)
+	}
+	return rules, nil
+}
+
+func (f *fakeDB) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	before := len(f.rules)
+	f.rules = slices.DeleteFunc(f.rules, func(r *database.Rule) bool { return r.ID == arg.ID && r.UserID == arg.UserID })
+	return int64(before - len(f.rules)), nil
+}
+
+func (f *fakeDB) CreateSavedSearch(ctx context.Context, arg database.CreateSavedSearchParams) (database.SavedSearch, error) {
+	if f.user(arg.UserID) == nil {
+		return database.SavedSearch{}, errFakeForeignKey
+	}
+	if _, err := f.GetSavedSearch(ctx, database.GetSavedSearchParams{UserID: arg.UserID, Name: arg.Name}); err == nil {
+		return database.SavedSearch{}, errFakeUnique
+	}
+	search := database.SavedSearch{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, Query: arg.Query, UserID: arg.UserID}
+	f.savedSearch = append(f.savedSearch, &search)
+	return search, nil
+}
+
+func (f *fakeDB) GetSavedSearch(ctx context.Context, arg database.GetSavedSearchParams) (database.SavedSearch, error) {
+	for _, search := range f.savedSearch {
+		if search.UserID == arg.UserID && search.Name == arg.Name {
+			return *search, nil
+		}
+	}
+	return database.SavedSearch{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) GetSavedSearchesForUser(ctx context.Context, userID uuid.UUID) ([]database.SavedSearch, error) {
+	searches := []database.SavedSearch{}
+	for _, search := range f.savedSearch {
+		if search.UserID == userID {
+			searches = append(searches, *search)
+		}
+	}
+	slices.SortFunc(searches, func(a, b database.SavedSearch) int { return strings.Compare(a.Name, b.Name) })
+	return searches, nil
+}
+
+func (f *fakeDB) DeleteSavedSearch(ctx context.Context, arg database.DeleteSavedSearchParams) (int64, error) {
+	before := len(f.savedSearch)
+	f.savedSearch = slices.DeleteFunc(f.savedSearch, func(s *database.SavedSearch) bool { return s.UserID == arg.UserID && s.Name == arg.Name })
+	return int64(before - len(f.savedSearch)), nil
+}
+
+// Retention
+
+func (f *fakeDB) GetRetentionSettings(ctx context.Context) (database.RetentionSetting, error) {
+	return f.retention, nil
+}
+
+func (f *fakeDB) SetRetentionSettings(ctx context.Context, arg database.SetRetentionSettingsParams) error {
+	f.retention = database.RetentionSetting{ID: 1, UpdatedAt: arg.UpdatedAt, MaxAgeDays: arg.MaxAgeDays, MaxPosts: arg.MaxPosts, KeepLatest: arg.KeepLatest}
+	return nil
+}
+
+func (f *fakeDB) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	if feed := f.feed(arg.ID); feed != nil {
+		feed.RetentionMaxAgeDays, feed.RetentionMaxPosts, feed.UpdatedAt = arg.RetentionMaxAgeDays, arg.RetentionMaxPosts, arg.UpdatedAt
+	}
+	return nil
+}
+
+func (f *fakeDB) GetFeedRetentions(ctx context.Context) ([]database.GetFeedRetentionsRow, error) {
+	rows := []database.GetFeedRetentionsRow{}
+	for _, feed := range f.feeds {
+		if feed.RetentionMaxAgeDays.Valid || feed.RetentionMaxPosts.Valid {
+			rows = append(rows, database.GetFeedRetentionsRow{Name: feed.Name, Url: feed.Url, RetentionMaxAgeDays: feed.RetentionMaxAgeDays, RetentionMaxPosts: feed.RetentionMaxPosts})
+		}
+	}
+	slices.SortFunc(rows, func(a, b database.GetFeedRetentionsRow) int { return strings.Compare(a.Name, b.Name) })
+	return rows, nil
+}
+
+// GetPurgeablePosts ranks each feed's posts newest first and returns those
+// past the feed's limits, leaving starred posts and posts a follower hasn't
+// read.
+func (f *fakeDB) GetPurgeablePosts(ctx context.Context, arg database.GetPurgeablePostsParams) ([]database.GetPurgeablePostsRow, error) {
+	type candidate struct {
+		feed string
+		post *database.Post
+	}
+	candidates := []candidate{}
+	for _, feed := range f.feeds {
+		posts := slices.DeleteFunc(slices.Clone(f.posts), func(p *database.Post) bool { return p.FeedID != feed.ID })
+		slices.SortFunc(posts, func(a, b *database.Post) int {
+			if c := b.PublishedAt.Time.Compare(a.PublishedAt.Time); c != 0 {
+				return c
+			}
+			return bytes.Compare(a.ID[:], b.ID[:])
+		})
+		maxAge, maxPosts := arg.MaxAgeDays, arg.MaxPosts
+		if feed.RetentionMaxAgeDays.Valid {
+			maxAge = feed.RetentionMaxAgeDays.Int32
+		}
+		if feed.RetentionMaxPosts.Valid {
+			maxPosts = feed.RetentionMaxPosts.Int32
+		}
+		for i, post := range posts {
+			position := int32(i + 1)
+			if position <= arg.KeepLatest {
+				continue
+			}
+			old := maxAge > 0 && post.PublishedAt.Time.Before(time.Now().AddDate(0, 0, -int(maxAge)))
+			over := maxPosts > 0 && position > maxPosts
+			if (old || over) && !f.keepPost(feed.ID, post.ID) {
+				candidates = append(candidates, candidate{feed: feed.Name, post: post})
+			}
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if c := strings.Compare(a.feed, b.feed); c != 0 {
+			return c
+		}
+		return a.post.PublishedAt.Time.Compare(b.post.PublishedAt.Time)
+	})
+	rows := []database.GetPurgeablePostsRow{}
+	for _, c := range candidates {
+		rows = append(rows, database.GetPurgeablePostsRow{ID: c.post.ID, FeedName: c.feed})
+	}
+	return rows, nil
+}
+
+// keepPost reports whether anyone starred the post or a follower of its feed
+// hasn't read it yet.
+func (f *fakeDB) keepPost(feedID, postID uuid.UUID) bool {
+	for _, state := range f.postStates {
+		if state.PostID == postID && state.StarredAt.Valid {
+			return true
+		}
+	}
+	for _, follow := range f.follows {
+		if follow.FeedID != feedID {
+			continue
+		}
+		if state := f.postState(follow.UserID, postID); state == nil || !state.ReadAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeDB) PurgePosts(ctx context.Context, arg database.PurgePostsParams) (int64, error) {
+	var purged int64
+	for _, post := range slices.Clone(f.posts) {
+		if !slices.Contains(arg.Ids, post.ID) {
+			continue
+		}
+		f.deletePost(post.ID)
+		purged++
+		if slices.ContainsFunc(f.purgedPosts, func(p *database.PurgedPost) bool { return p.Url == post.Url }) {
+			continue
+		}
+		f.purgedPosts = append(f.purgedPosts, &database.PurgedPost{Url: post.Url, FeedID: post.FeedID, PurgedAt: arg.PurgedAt})
+	}
+	return purged, nil
+}
+
+func (f *fakeDB) PrunePurgedPosts(ctx context.Context, arg database.PrunePurgedPostsParams) (int64, error) {
+	before := len(f.purgedPosts)
+	f.purgedPosts = slices.DeleteFunc(f.purgedPosts, func(p *database.PurgedPost) bool {
+		return p.FeedID == arg.FeedID && !slices.Contains(arg.Urls, p.Url)
+	})
+	return int64(before - len(f.purgedPosts)), nil
+}
+
+func (f *fakeDB) GetPurgedPostURLs(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	urls := []string{}
+	for _, purged := range f.purgedPosts {
+		if purged.FeedID == feedID {
+			urls = append(urls, purged.Url)
+		}
+	}
+	return urls, nil
+}
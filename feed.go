@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+const feedUsage = "usage: feed info <url> | rename <url> <new name> | delete <url> [--force] [--yes]"
+
+type feedInfoRecord struct {
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	Title         string     `json:"title"`
+	SiteURL       string     `json:"site_url"`
+	Description   string     `json:"description"`
+	Language      string     `json:"language"`
+	ImageURL      string     `json:"image_url"`
+	Generator     string     `json:"generator"`
+	AddedBy       string     `json:"added_by"`
+	CreatedAt     *time.Time `json:"created_at"`
+	Followers     int        `json:"followers"`
+	Posts         int64      `json:"posts"`
+	PostsPerWeek  float64    `json:"posts_per_week"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	LastError     string     `json:"last_error"`
+}
+
+// handlerFeed looks after a single feed, named by its URL. Only the user who
+// added a feed or an admin can rename or delete it, and a feed other people
+// follow is only deleted when an admin forces it.
+func handlerFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 2 {
+		return invalidArgs(feedUsage)
+	}
+	subcommand, url, args := cmd.arguments[0], cmd.arguments[1], cmd.arguments[2:]
+	feed, err := s.db.GetFeedByURL(context.Background(), url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No feed with URL %v.", url)
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case subcommand == "info" && len(args) == 0:
+		return feedInfo(s, feed)
+	case subcommand == "rename" && len(args) == 1:
+		return feedRename(s, user, feed, args[0])
+	case subcommand == "delete":
+		return feedDelete(s, user, feed, args)
+	}
+	return invalidArgs(feedUsage)
+}
+
+func feedInfo(s *state, feed database.Feed) error {
+	creator, err := s.db.GetUsername(context.Background(), feed.UserID)
+	if err != nil {
+		return err
+	}
+	followers, err := s.db.GetFeedFollowerNames(context.Background(), feed.ID)
+	if err != nil {
+		return err
+	}
+	stats, err := s.db.GetFeedPostStats(context.Background(), feed.ID)
+	if err != nil {
+		return err
+	}
+	record := feedInfoRecord{
+		Name:          feed.Name,
+		URL:           feed.Url,
+		Title:         feed.Title,
+		SiteURL:       feed.SiteUrl,
+		Description:   feed.Description,
+		Language:      feed.Language,
+		ImageURL:      feed.ImageUrl,
+		Generator:     feed.Generator,
+		AddedBy:       creator.Name,
+		CreatedAt:     nullTime(feed.CreatedAt.Valid, feed.CreatedAt.Time),
+		Followers:     len(followers),
+		Posts:         stats.Posts,
+		PostsPerWeek:  postsPerWeek(stats.Posts, stats.OldestPublishedAt, stats.NewestPublishedAt),
+		LastFetchedAt: nullTime(feed.LastFetchedAt.Valid, feed.LastFetchedAt.Time),
+		LastError:     feed.LastError,
+	}
+	if s.output != outputText {
+		return writeRecords(os.Stdout, s.output, []feedInfoRecord{record})
+	}
+	fmt.Printf("Name:        %v\n", record.Name)
+	fmt.Printf("URL:         %v\n", record.URL)
+	// The channel's own details are only known once the feed is fetched.
+	for _, field := range [][2]string{
+		{"Title", record.Title},
+		{"Site", record.SiteURL},
+		{"Description", record.Description},
+		{"Language", record.Language},
+		{"Image", record.ImageURL},
+		{"Generator", record.Generator},
+	} {
+		if field[1] != "" {
+			fmt.Printf("%-13v%v\n", field[0]+":", field[1])
+		}
+	}
+	fmt.Printf("Added by:    %v\n", record.AddedBy)
+	fmt.Printf("Followers:   %d\n", record.Followers)
+	fmt.Printf("Posts:       %d\n", record.Posts)
+	if stats.Posts > 1 {
+		fmt.Printf("Posting:     %.1f posts/week\n", record.PostsPerWeek)
+	}
+	if record.LastFetchedAt != nil {
+		fmt.Printf("Last fetch:  %v\n", record.LastFetchedAt.Local().Format("2006-01-02 15:04"))
+	} else {
+		fmt.Println("Last fetch:  never")
+	}
+	if record.LastError != "" {
+		fmt.Printf("Last error:  %v\n", record.LastError)
+	}
+	return nil
+}
+
+// postsPerWeek is the average posting rate between the oldest and newest
+// posts. It's zero until there are two posts to measure between.
+func postsPerWeek(posts int64, oldest, newest time.Time) float64 {
+	span := newest.Sub(oldest)
+	if posts < 2 || span <= 0 {
+		return 0
+	}
+	return float64(posts-1) / (span.Hours() / (24 * 7))
+}
+
+// feedMetadata is the channel information gator keeps from each fetch.
+func feedMetadata(id uuid.UUID, feed *rss.RSSFeed) database.UpdateFeedMetadataParams {
+	params := database.UpdateFeedMetadataParams{
+		ID:          id,
+		Title:       strings.TrimSpace(feed.Channel.Title),
+		SiteUrl:     strings.TrimSpace(feed.Channel.Link),
+		Description: strings.TrimSpace(feed.Channel.Description),
+		Language:    strings.TrimSpace(feed.Channel.Language),
+		Generator:   strings.TrimSpace(feed.Channel.Generator),
+	}
+	if feed.Channel.Image != nil {
+		params.ImageUrl = strings.TrimSpace(feed.Channel.Image.URL)
+	}
+	return params
+}
+
+func canManageFeed(user database.User, feed database.Feed) error {
+	if feed.UserID != user.ID && !user.IsAdmin {
+		return unauthorized("Only the user who added a feed or an admin can change it.")
+	}
+	return nil
+}
+
+func feedRename(s *state, user database.User, feed database.Feed, name string) error {
+	if err := canManageFeed(user, feed); err != nil {
+		return err
+	}
+	if name == "" {
+		return invalidArgs("The new name can't be empty.")
+	}
+	params := database.RenameFeedParams{ID: feed.ID, Name: name, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	if err := s.db.RenameFeed(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("Feed renamed: %v -> %v\n", feed.Name, name)
+	return nil
+}
+
+// feedDelete removes a feed with its posts and follows. It refuses while
+// anyone else follows the feed, unless an admin passes --force.
+func feedDelete(s *state, user database.User, feed database.Feed, args []string) error {
+	if err := canManageFeed(user, feed); err != nil {
+		return err
+	}
+	args, yes := takeYes(args)
+	flags := flag.NewFlagSet("feed delete", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	force := flags.Bool("force", false, "delete even if other users follow the feed (admins only)")
+	if err := flags.Parse(args); err != nil {
+		return invalidArgs("%v\n%v", err, feedUsage)
+	}
+	if flags.NArg() > 0 {
+		return invalidArgs("unexpected argument %q\n%v", flags.Arg(0), feedUsage)
+	}
+	if *force && !user.IsAdmin {
+		return unauthorized("Only admins can use --force.")
+	}
+
+	followers, err := s.db.GetFeedFollowerNames(context.Background(), feed.ID)
+	if err != nil {
+		return err
+	}
+	others := slices.DeleteFunc(followers, func(name string) bool { return name == user.Name })
+	if len(others) > 0 && !*force {
+		return unauthorized("%v is still followed by %d other users, so it can't be deleted.", feed.Name, len(others))
+	}
+	stats, err := s.db.GetFeedPostStats(context.Background(), feed.ID)
+	if err != nil {
+		return err
+	}
+	warning := fmt.Sprintf("This deletes %v and its %d posts.", feed.Name, stats.Posts)
+	if len(others) > 0 {
+		warning = fmt.Sprintf("This deletes %v and its %d posts, and unfollows it for %d other users.", feed.Name, stats.Posts, len(others))
+	}
+	if err := confirmAction(yes, warning); err != nil {
+		return err
+	}
+	if err := s.db.DeleteFeed(context.Background(), feed.ID); err != nil {
+		return err
+	}
+	fmt.Printf("Feed deleted: %v\n", feed.Name)
+	return nil
+}
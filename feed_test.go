@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+)
+
+func TestPostsPerWeek(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		posts  int64
+		newest time.Time
+		want   float64
+	}{
+		{0, start, 0},
+		{1, start, 0},
+		{5, start, 0},
+		{8, start.Add(7 * 24 * time.Hour), 7},
+		{3, start.Add(28 * 24 * time.Hour), 0.5},
+	}
+	for _, tt := range tests {
+		if got := postsPerWeek(tt.posts, start, tt.newest); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("postsPerWeek(%d, %v) = %v, want %v", tt.posts, tt.newest.Sub(start), got, tt.want)
+		}
+	}
+}
+
+func TestFeedDelete(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		s := &state{db: db, output: outputText}
+		david, larry, susan := testUser(t, db, "david"), testUser(t, db, "larry"), testUser(t, db, "susan")
+		url := "https://example.com/feed.xml"
+		if err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"Blog", url}}, larry); err != nil {
+			t.Fatal(err)
+		}
+		if err := handlerFollow(s, command{name: "follow", arguments: []string{url}}, susan); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := handlerFeed(s, command{name: "feed", arguments: []string{"rename", url, "Mine"}}, susan); err == nil {
+			t.Error("only the creator or an admin can rename a feed")
+		}
+		if err := handlerFeed(s, command{name: "feed", arguments: []string{"rename", url, "Larry's Blog"}}, larry); err != nil {
+			t.Fatal(err)
+		}
+		if err := handlerFeed(s, command{name: "feed", arguments: []string{"delete", url, "--yes"}}, larry); err == nil {
+			t.Error("a feed someone else follows can't be deleted")
+		}
+		if err := handlerFeed(s, command{name: "feed", arguments: []string{"delete", url, "--force", "--yes"}}, larry); err == nil {
+			t.Error("only admins can force a delete")
+		}
+		if err := handlerFeed(s, command{name: "feed", arguments: []string{"delete", url, "--force", "--yes"}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.GetFeedByURL(context.Background(), url); err != sql.ErrNoRows {
+			t.Fatalf("the feed should be gone, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The Fever API. Clients log in with api_key, the MD5 of "user:password",
+// and ask for parts of the response with query parameters such as
+// ?api&items&since_id=10. Groups are categories; the post seq column gives
+// items the increasing integer ids Fever needs.
+const (
+	feverAPIVersion = 3
+	feverPageSize   = 50
+)
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverFavicon struct {
+	ID   int64  `json:"id"`
+	Data string `json:"data"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+// handlerFeverKey asks for the password Fever clients log in with, alongside
+// the user name. "--remove" turns Fever access off again.
+func handlerFeverKey(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) > 1 || (len(cmd.arguments) == 1 && cmd.arguments[0] != "--remove") {
+		return invalidArgs("usage: fever-key [--remove]")
+	}
+	if len(cmd.arguments) == 1 {
+		removed, err := s.db.DeleteFeverAPIKey(context.Background(), user.ID)
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return notFound("%v has no Fever API key.", user.Name)
+		}
+		fmt.Printf("Fever API key removed for %v\n", user.Name)
+		return nil
+	}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	params := database.SetFeverAPIKeyParams{UserID: user.ID, CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, ApiKey: feverAPIKey(user.Name, password)}
+	if err := s.db.SetFeverAPIKey(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("Fever API key set. Log in as %v with that password.\n", user.Name)
+	return nil
+}
+
+func feverAPIKey(name, password string) string {
+	sum := md5.Sum([]byte(name + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// feverID gives feeds and categories the integer ids Fever needs. It is the
+// first 48 bits of the UUID, which stays positive and exact in JavaScript.
+func feverID(id uuid.UUID) int64 {
+	var n int64
+	for _, b := range id[:6] {
+		n = n<<8 | int64(b)
+	}
+	return n
+}
+
+func (api *apiServer) registerFever(mux *http.ServeMux) {
+	mux.HandleFunc("/fever/", api.handleFever)
+}
+
+func (api *apiServer) handleFever(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+	user, err := api.state.db.GetUserByFeverAPIKey(r.Context(), strings.ToLower(r.Form.Get("api_key")))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	response["auth"] = 1
+	response["last_refreshed_on_time"] = time.Now().Unix()
+
+	query := r.URL.Query()
+	if err := api.feverMark(r.Context(), user, r.Form); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.Has("groups") || query.Has("feeds") {
+		groups, feeds, feedsGroups, err := api.feverFeeds(r.Context(), user)
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		if query.Has("groups") {
+			response["groups"] = groups
+		}
+		if query.Has("feeds") {
+			response["feeds"] = feeds
+		}
+		response["feeds_groups"] = feedsGroups
+	}
+	if query.Has("favicons") {
+		icons, err := api.state.db.GetFeedIconsForUser(r.Context(), user.ID)
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		favicons := []feverFavicon{}
+		for _, feedIcon := range icons {
+			favicons = append(favicons, feverFavicon{ID: feverID(feedIcon.FeedID), Data: feedIcon.ContentType + ";base64," + base64.StdEncoding.EncodeToString(feedIcon.Data)})
+		}
+		response["favicons"] = favicons
+	}
+	if query.Has("links") {
+		response["links"] = []any{}
+	}
+	if query.Has("items") {
+		params, err := feverItemsParams(user, query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := api.state.db.GetFeverItems(r.Context(), params)
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		total, err := api.state.db.CountPostsForUser(r.Context(), user.ID)
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		items := []feverItem{}
+		for _, row := range rows {
+			items = append(items, newFeverItem(row))
+		}
+		response["items"] = items
+		response["total_items"] = total
+	}
+	if query.Has("unread_item_ids") {
+		ids, err := api.state.db.GetFeverItemIDs(r.Context(), database.GetFeverItemIDsParams{UserID: user.ID, UnreadOnly: true})
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		response["unread_item_ids"] = joinIDs(ids)
+	}
+	if query.Has("saved_item_ids") {
+		ids, err := api.state.db.GetFeverItemIDs(r.Context(), database.GetFeverItemIDsParams{UserID: user.ID, StarredOnly: true})
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		response["saved_item_ids"] = joinIDs(ids)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// feverFeeds lists the user's categories as groups and their follows as
+// feeds, with the feeds_groups table that links them.
+func (api *apiServer) feverFeeds(ctx context.Context, user database.User) ([]feverGroup, []feverFeed, []feverFeedsGroup, error) {
+	categories, err := api.state.db.GetCategoriesForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	following, err := api.state.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	icons, err := api.state.db.GetFeedIconHashesForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// A feed's favicon shares its id.
+	hasIcon := map[uuid.UUID]bool{}
+	for _, feedIcon := range icons {
+		hasIcon[feedIcon.FeedID] = true
+	}
+	groups := []feverGroup{}
+	members := map[string][]string{}
+	for _, category := range categories {
+		groups = append(groups, feverGroup{ID: feverID(category.ID), Title: category.Name})
+	}
+	feeds := []feverFeed{}
+	for _, followRow := range following {
+		id := feverID(followRow.FeedID)
+		feed := feverFeed{ID: id, Title: followRow.FeedName, URL: followRow.FeedUrl, SiteURL: followRow.FeedUrl}
+		if hasIcon[followRow.FeedID] {
+			feed.FaviconID = id
+		}
+		feeds = append(feeds, feed)
+		if followRow.CategoryName.Valid {
+			members[followRow.CategoryName.String] = append(members[followRow.CategoryName.String], strconv.FormatInt(id, 10))
+		}
+	}
+	feedsGroups := []feverFeedsGroup{}
+	for _, category := range categories {
+		if ids, ok := members[category.Name]; ok {
+			feedsGroups = append(feedsGroups, feverFeedsGroup{GroupID: feverID(category.ID), FeedIDs: strings.Join(ids, ",")})
+		}
+	}
+	return groups, feeds, feedsGroups, nil
+}
+
+// feverItemsParams reads since_id, max_id and with_ids. Without any of them
+// the oldest items are returned first.
+func feverItemsParams(user database.User, query url.Values) (database.GetFeverItemsParams, error) {
+	params := database.GetFeverItemsParams{UserID: user.ID, ItemLimit: feverPageSize}
+	if value := query.Get("since_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid since_id %q", value)
+		}
+		params.SinceID = id
+	}
+	if value := query.Get("max_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid max_id %q", value)
+		}
+		params.MaxID = id
+	}
+	if value := query.Get("with_ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return params, fmt.Errorf("invalid with_ids %q", value)
+			}
+			params.WithIds = append(params.WithIds, id)
+		}
+		if len(params.WithIds) > feverPageSize {
+			return params, fmt.Errorf("with_ids can't list more than %d items", feverPageSize)
+		}
+	}
+	return params, nil
+}
+
+// feverMark handles mark=item|feed|group with as=read|unread|saved|unsaved.
+// Feeds and groups can only be marked read, up to the before timestamp.
+// Group 0 is every feed.
+func (api *apiServer) feverMark(ctx context.Context, user database.User, form url.Values) error {
+	mark, as := form.Get("mark"), form.Get("as")
+	if mark == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(form.Get("id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", form.Get("id"))
+	}
+
+	if mark == "item" {
+		changes := map[string]postStateChange{"read": markRead, "unread": markUnread, "saved": star, "unsaved": unstar}
+		change, ok := changes[as]
+		if !ok {
+			return fmt.Errorf("can't mark an item as %q", as)
+		}
+		rows, err := api.state.db.GetFeverItems(ctx, database.GetFeverItemsParams{UserID: user.ID, WithIds: []int64{id}, ItemLimit: 1})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := change(ctx, api.state.db, user.ID, row.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if mark != "feed" && mark != "group" {
+		return fmt.Errorf("can't mark %q", mark)
+	}
+	if as != "read" {
+		return fmt.Errorf("can't mark a %v as %q", mark, as)
+	}
+	before := time.Now()
+	if value := form.Get("before"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid before %q", value)
+		}
+		before = time.Unix(seconds, 0)
+	}
+	following, err := api.state.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	groupName := ""
+	if mark == "group" && id != 0 {
+		categories, err := api.state.db.GetCategoriesForUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			if feverID(category.ID) == id {
+				groupName = category.Name
+			}
+		}
+		if groupName == "" {
+			return nil
+		}
+	}
+	feedIDs := []uuid.UUID{}
+	for _, followRow := range following {
+		switch {
+		case mark == "feed" && feverID(followRow.FeedID) == id,
+			mark == "group" && id == 0,
+			mark == "group" && followRow.CategoryName.String == groupName && followRow.CategoryName.Valid:
+			feedIDs = append(feedIDs, followRow.FeedID)
+		}
+	}
+	if len(feedIDs) == 0 {
+		return nil
+	}
+	return api.state.db.MarkFeedsReadBefore(ctx, database.MarkFeedsReadBeforeParams{UserID: user.ID, FeedIds: feedIDs, Before: sql.NullTime{Time: before.UTC(), Valid: true}})
+}
+
+func newFeverItem(row database.GetFeverItemsRow) feverItem {
+	html := row.Content
+	if html == "" {
+		html = row.Description
+	}
+	item := feverItem{
+		ID:            row.Seq,
+		FeedID:        feverID(row.FeedID),
+		Title:         row.Title,
+		Author:        row.Author,
+		HTML:          html,
+		URL:           row.Url,
+		CreatedOnTime: row.PublishedAt.Time.Unix(),
+	}
+	if row.ReadAt.Valid {
+		item.IsRead = 1
+	}
+	if row.StarredAt.Valid {
+		item.IsSaved = 1
+	}
+	return item
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
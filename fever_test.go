@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestFeverAPIKey(t *testing.T) {
+	if got, want := feverAPIKey("David", "secret"), "1d495523bfc3decb75e6d2806a2fc083"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFeverID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want int64
+	}{
+		{"00000000-0001-4000-8000-000000000000", 1},
+		{"ffffffff-ffff-4fff-bfff-ffffffffffff", 1<<48 - 1},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d479", 0xf47ac10b58cc},
+	}
+	for _, tt := range tests {
+		if got := feverID(uuid.MustParse(tt.id)); got != tt.want {
+			t.Errorf("feverID(%v) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFeverItemsParams(t *testing.T) {
+	user := database.User{ID: uuid.New()}
+	tests := []struct {
+		query string
+		want  database.GetFeverItemsParams
+		err   bool
+	}{
+		{"api&items", database.GetFeverItemsParams{UserID: user.ID, ItemLimit: feverPageSize}, false},
+		{"api&items&since_id=42", database.GetFeverItemsParams{UserID: user.ID, SinceID: 42, ItemLimit: feverPageSize}, false},
+		{"api&items&max_id=42", database.GetFeverItemsParams{UserID: user.ID, MaxID: 42, ItemLimit: feverPageSize}, false},
+		{"api&items&with_ids=1,2,%203", database.GetFeverItemsParams{UserID: user.ID, WithIds: []int64{1, 2, 3}, ItemLimit: feverPageSize}, false},
+		{"api&items&since_id=x", database.GetFeverItemsParams{}, true},
+		{"api&items&with_ids=1,,2", database.GetFeverItemsParams{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := feverItemsParams(user, query)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error: %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The Google Reader API, as spoken by mobile readers such as Reeder, FeedMe
+// and NetNewsWire. Feeds are streams named "feed/<url>", categories are
+// labels, and read and starred are the com.google states.
+const (
+	greaderPrefix      = "/reader/api/0"
+	greaderReadingList = "user/-/state/com.google/reading-list"
+	greaderRead        = "user/-/state/com.google/read"
+	greaderStarred     = "user/-/state/com.google/starred"
+	greaderKeptUnread  = "user/-/state/com.google/kept-unread"
+	greaderLabelPrefix = "user/-/label/"
+	greaderFeedPrefix  = "feed/"
+	greaderItemPrefix  = "tag:google.com,2005:reader/item/"
+	greaderMaxItems    = 10000
+
+	greaderSessionLength = 365 * 24 * time.Hour
+)
+
+type greaderSubscription struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Categories []greaderCategory `json:"categories"`
+	URL        string            `json:"url"`
+	HTMLURL    string            `json:"htmlUrl"`
+	IconURL    string            `json:"iconUrl"`
+}
+
+type greaderCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type greaderTag struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int64  `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type greaderOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+type greaderItem struct {
+	ID            string         `json:"id"`
+	CrawlTimeMsec string         `json:"crawlTimeMsec"`
+	TimestampUsec string         `json:"timestampUsec"`
+	Published     int64          `json:"published"`
+	Updated       int64          `json:"updated"`
+	Title         string         `json:"title"`
+	Canonical     []greaderLink  `json:"canonical"`
+	Alternate     []greaderLink  `json:"alternate"`
+	Summary       greaderContent `json:"summary"`
+	Author        string         `json:"author,omitempty"`
+	Origin        greaderOrigin  `json:"origin"`
+	Categories    []string       `json:"categories"`
+}
+
+type greaderStream struct {
+	Direction    string        `json:"direction"`
+	ID           string        `json:"id"`
+	Updated      int64         `json:"updated"`
+	Items        []greaderItem `json:"items"`
+	Continuation string        `json:"continuation,omitempty"`
+}
+
+type greaderItemRef struct {
+	ID            string   `json:"id"`
+	TimestampUsec string   `json:"timestampUsec"`
+	DirectStreams []string `json:"directStreamIds"`
+}
+
+func (api *apiServer) registerGReader(mux *http.ServeMux) {
+	mux.HandleFunc("POST /accounts/ClientLogin", api.handleClientLogin)
+	mux.HandleFunc("GET "+greaderPrefix+"/token", api.greaderAuthenticated(api.handleGReaderToken))
+	mux.HandleFunc("GET "+greaderPrefix+"/user-info", api.greaderAuthenticated(api.handleGReaderUserInfo))
+	mux.HandleFunc("GET "+greaderPrefix+"/subscription/list", api.greaderAuthenticated(api.handleGReaderSubscriptions))
+	mux.HandleFunc("GET "+greaderPrefix+"/tag/list", api.greaderAuthenticated(api.handleGReaderTags))
+	mux.HandleFunc("GET "+greaderPrefix+"/unread-count", api.greaderAuthenticated(api.handleGReaderUnreadCount))
+	mux.HandleFunc("GET "+greaderPrefix+"/stream/contents/{stream...}", api.greaderAuthenticated(api.handleGReaderStreamContents))
+	mux.HandleFunc("GET "+greaderPrefix+"/stream/items/ids", api.greaderAuthenticated(api.handleGReaderItemIDs))
+	mux.HandleFunc("POST "+greaderPrefix+"/stream/items/contents", api.greaderAuthenticated(api.handleGReaderItemContents))
+	mux.HandleFunc("POST "+greaderPrefix+"/edit-tag", api.greaderAuthenticated(api.handleGReaderEditTag))
+}
+
+// handleClientLogin checks the user name and password and hands out the
+// token clients send back in the "Authorization: GoogleLogin auth=<token>"
+// header. The token is a session like the CLI's, valid for a year.
+func (api *apiServer) handleClientLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error=BadAuthentication", http.StatusBadRequest)
+		return
+	}
+	// The password is only read from the body, so it stays out of URLs and
+	// the request log.
+	user, err := api.state.db.GetUser(r.Context(), r.PostForm.Get("Email"))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		api.internalError(w, err)
+		return
+	}
+	if !checkPassword(user, r.PostForm.Get("Passwd")) {
+		http.Error(w, "Error=BadAuthentication", http.StatusForbidden)
+		return
+	}
+	token, err := createSession(r.Context(), api.state.db, user, greaderSessionLength)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	if r.Form.Get("output") == "json" {
+		writeJSON(w, http.StatusOK, map[string]string{"SID": token, "LSID": token, "Auth": token})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%v\nLSID=%v\nAuth=%v\n", token, token, token)
+}
+
+// greaderAuthenticated checks the GoogleLogin token from ClientLogin.
+func (api *apiServer) greaderAuthenticated(handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := api.state.db.GetUserBySession(r.Context(), hashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// handleGReaderToken returns the token clients pass as T when editing. The
+// GoogleLogin header already authenticates those requests, so it isn't
+// checked.
+func (api *apiServer) handleGReaderToken(w http.ResponseWriter, r *http.Request, user database.User) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.ReplaceAll(user.ID.String(), "-", ""))
+}
+
+func (api *apiServer) handleGReaderUserInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+func (api *apiServer) handleGReaderSubscriptions(w http.ResponseWriter, r *http.Request, user database.User) {
+	following, err := api.state.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	subscriptions := []greaderSubscription{}
+	for _, followRow := range following {
+		subscription := greaderSubscription{
+			ID:         greaderFeedPrefix + followRow.FeedUrl,
+			Title:      followRow.FeedName,
+			Categories: []greaderCategory{},
+			URL:        followRow.FeedUrl,
+			HTMLURL:    followRow.FeedUrl,
+		}
+		if followRow.CategoryName.Valid {
+			subscription.Categories = append(subscription.Categories, greaderCategory{ID: greaderLabelPrefix + followRow.CategoryName.String, Label: followRow.CategoryName.String})
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	writeJSON(w, http.StatusOK, map[string][]greaderSubscription{"subscriptions": subscriptions})
+}
+
+func (api *apiServer) handleGReaderTags(w http.ResponseWriter, r *http.Request, user database.User) {
+	categories, err := api.state.db.GetCategoriesForUser(r.Context(), user.ID)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	tags := []greaderTag{{ID: greaderStarred}}
+	for _, category := range categories {
+		tags = append(tags, greaderTag{ID: greaderLabelPrefix + category.Name, Type: "folder"})
+	}
+	writeJSON(w, http.StatusOK, map[string][]greaderTag{"tags": tags})
+}
+
+// handleGReaderUnreadCount counts unread posts per feed, per label and for
+// the whole reading list.
+func (api *apiServer) handleGReaderUnreadCount(w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := api.state.db.GetUnreadCountsForUser(r.Context(), user.ID)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	following, err := api.state.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	follows := map[uuid.UUID]database.GetFeedFollowsForUserRow{}
+	for _, followRow := range following {
+		follows[followRow.FeedID] = followRow
+	}
+
+	type unread struct {
+		count  int64
+		newest time.Time
+	}
+	ids := []string{}
+	totals := map[string]*unread{}
+	add := func(id string, count int64, newest time.Time) {
+		total, ok := totals[id]
+		if !ok {
+			total = &unread{}
+			totals[id] = total
+			ids = append(ids, id)
+		}
+		total.count += count
+		if newest.After(total.newest) {
+			total.newest = newest
+		}
+	}
+	for _, count := range counts {
+		followRow, ok := follows[count.FeedID]
+		if !ok {
+			continue
+		}
+		add(greaderFeedPrefix+followRow.FeedUrl, count.Unread, count.NewestPublishedAt)
+		if followRow.CategoryName.Valid {
+			add(greaderLabelPrefix+followRow.CategoryName.String, count.Unread, count.NewestPublishedAt)
+		}
+		add(greaderReadingList, count.Unread, count.NewestPublishedAt)
+	}
+	result := []greaderUnreadCount{}
+	for _, id := range ids {
+		total := totals[id]
+		result = append(result, greaderUnreadCount{ID: id, Count: total.count, NewestItemTimestampUsec: strconv.FormatInt(max(total.newest.UnixMicro(), 0), 10)})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"max": greaderMaxItems, "unreadcounts": result})
+}
+
+func (api *apiServer) handleGReaderStreamContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	streamID := r.PathValue("stream")
+	if streamID == "" {
+		streamID = r.URL.Query().Get("s")
+	}
+	params, err := greaderStreamParams(r.URL.Query(), user, streamID, 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	posts, more, err := loadBrowsePosts(api.state, user, params, nil, false)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	stream := greaderStream{Direction: "ltr", ID: streamID, Updated: time.Now().Unix(), Items: []greaderItem{}, Continuation: greaderContinuation(posts, more)}
+	for _, post := range posts {
+		stream.Items = append(stream.Items, newGReaderItem(post))
+	}
+	writeJSON(w, http.StatusOK, stream)
+}
+
+func (api *apiServer) handleGReaderItemIDs(w http.ResponseWriter, r *http.Request, user database.User) {
+	params, err := greaderStreamParams(r.URL.Query(), user, r.URL.Query().Get("s"), 1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	posts, more, err := loadBrowsePosts(api.state, user, params, nil, false)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	refs := []greaderItemRef{}
+	for _, post := range posts {
+		refs = append(refs, greaderItemRef{ID: strconv.FormatInt(greaderShortID(post.ID), 10), TimestampUsec: greaderTimestamp(post), DirectStreams: []string{}})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"itemRefs": refs, "continuation": greaderContinuation(posts, more)})
+}
+
+// greaderStreamParams reads the query parameters every stream endpoint
+// takes: n (count), c (continuation), r=o (oldest first), ot and nt (oldest
+// and newest time) and xt (exclude read posts).
+func greaderStreamParams(query url.Values, user database.User, streamID string, count int) (database.BrowsePostsForUserParams, error) {
+	params := database.BrowsePostsForUserParams{UserID: user.ID, OldestFirst: query.Get("r") == "o"}
+	switch {
+	case streamID == greaderReadingList:
+	case streamID == greaderStarred:
+		params.StarredOnly = true
+	case strings.HasPrefix(streamID, greaderFeedPrefix):
+		params.Feed = sql.NullString{String: strings.TrimPrefix(streamID, greaderFeedPrefix), Valid: true}
+	case strings.HasPrefix(streamID, greaderLabelPrefix):
+		params.Category = sql.NullString{String: strings.TrimPrefix(streamID, greaderLabelPrefix), Valid: true}
+	default:
+		return params, fmt.Errorf("unsupported stream %q", streamID)
+	}
+	for _, exclude := range query["xt"] {
+		if exclude == greaderRead {
+			params.UnreadOnly = true
+		}
+	}
+	if value := query.Get("n"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return params, fmt.Errorf("invalid n %q", value)
+		}
+		count = min(n, greaderMaxItems)
+	}
+	params.PostLimit = int32(count)
+	if value := query.Get("ot"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid ot %q", value)
+		}
+		params.Since = sql.NullTime{Time: time.Unix(seconds, 0).UTC(), Valid: true}
+	}
+	if value := query.Get("nt"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid nt %q", value)
+		}
+		params.Until = sql.NullTime{Time: time.Unix(seconds, 0).UTC(), Valid: true}
+	}
+	if value := query.Get("c"); value != "" {
+		publishedAt, id, err := decodeCursor(value)
+		if err != nil {
+			return params, err
+		}
+		params.CursorPublishedAt = sql.NullTime{Time: publishedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	return params, nil
+}
+
+func greaderContinuation(posts []browsedPost, more bool) string {
+	if !more {
+		return ""
+	}
+	last := posts[len(posts)-1]
+	return encodeCursor(last.PublishedAt.Time, last.ID)
+}
+
+// handleGReaderItemContents returns the posts named by the i form values.
+func (api *apiServer) handleGReaderItemContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemIDs, err := parseGReaderItemIDs(r.PostForm["i"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := database.BrowsePostsForUserParams{UserID: user.ID, ItemIds: itemIDs, PostLimit: int32(len(itemIDs))}
+	posts, _, err := loadBrowsePosts(api.state, user, params, nil, true)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	stream := greaderStream{Direction: "ltr", ID: greaderReadingList, Updated: time.Now().Unix(), Items: []greaderItem{}}
+	for _, post := range posts {
+		stream.Items = append(stream.Items, newGReaderItem(post))
+	}
+	writeJSON(w, http.StatusOK, stream)
+}
+
+// handleGReaderEditTag adds (a) and removes (r) the read and starred states
+// on the posts named by the i form values. Labels on posts aren't supported.
+func (api *apiServer) handleGReaderEditTag(w http.ResponseWriter, r *http.Request, user database.User) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemIDs, err := parseGReaderItemIDs(r.PostForm["i"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := database.BrowsePostsForUserParams{UserID: user.ID, ItemIds: itemIDs, PostLimit: int32(len(itemIDs))}
+	posts, _, err := loadBrowsePosts(api.state, user, params, nil, true)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	changes := []postStateChange{}
+	for _, tag := range r.PostForm["a"] {
+		switch tag {
+		case greaderRead:
+			changes = append(changes, markRead)
+		case greaderStarred:
+			changes = append(changes, star)
+		case greaderKeptUnread:
+			changes = append(changes, markUnread)
+		}
+	}
+	for _, tag := range r.PostForm["r"] {
+		switch tag {
+		case greaderRead:
+			changes = append(changes, markUnread)
+		case greaderStarred:
+			changes = append(changes, unstar)
+		}
+	}
+	for _, post := range posts {
+		for _, change := range changes {
+			if err := change(r.Context(), api.state.db, user.ID, post.ID); err != nil {
+				api.internalError(w, err)
+				return
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
+
+func parseGReaderItemIDs(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no items given")
+	}
+	itemIDs := []string{}
+	for _, id := range ids {
+		itemID, err := parseGReaderItemID(id)
+		if err != nil {
+			return nil, err
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+	return itemIDs, nil
+}
+
+// Item ids are the first 64 bits of a post's UUID, either as
+// "tag:google.com,2005:reader/item/<16 hex digits>" or as a signed decimal.
+func greaderShortID(id uuid.UUID) int64 {
+	var n uint64
+	for _, b := range id[:8] {
+		n = n<<8 | uint64(b)
+	}
+	return int64(n)
+}
+
+func greaderLongID(id uuid.UUID) string {
+	return fmt.Sprintf("%v%016x", greaderItemPrefix, uint64(greaderShortID(id)))
+}
+
+// parseGReaderItemID returns the hex form of an item id, as matched by
+// BrowsePostsForUser's item_ids.
+func parseGReaderItemID(id string) (string, error) {
+	if hex, ok := strings.CutPrefix(id, greaderItemPrefix); ok {
+		if _, err := strconv.ParseUint(hex, 16, 64); err != nil || len(hex) != 16 {
+			return "", fmt.Errorf("invalid item id %q", id)
+		}
+		return strings.ToLower(hex), nil
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid item id %q", id)
+	}
+	return fmt.Sprintf("%016x", uint64(n)), nil
+}
+
+func greaderTimestamp(post browsedPost) string {
+	return strconv.FormatInt(post.PublishedAt.Time.UnixMicro(), 10)
+}
+
+func newGReaderItem(post browsedPost) greaderItem {
+	content := post.Content
+	if content == "" {
+		content = post.Description
+	}
+	categories := []string{greaderReadingList}
+	if post.ReadAt.Valid {
+		categories = append(categories, greaderRead)
+	}
+	if post.StarredAt.Valid {
+		categories = append(categories, greaderStarred)
+	}
+	if post.CategoryName.Valid {
+		categories = append(categories, greaderLabelPrefix+post.CategoryName.String)
+	}
+	published := post.PublishedAt.Time
+	return greaderItem{
+		ID:            greaderLongID(post.ID),
+		CrawlTimeMsec: strconv.FormatInt(published.UnixMilli(), 10),
+		TimestampUsec: greaderTimestamp(post),
+		Published:     published.Unix(),
+		Updated:       published.Unix(),
+		Title:         post.Title,
+		Canonical:     []greaderLink{{Href: post.Url}},
+		Alternate:     []greaderLink{{Href: post.Url, Type: "text/html"}},
+		Summary:       greaderContent{Direction: "ltr", Content: content},
+		Author:        post.Author,
+		Origin:        greaderOrigin{StreamID: greaderFeedPrefix + post.FeedUrl, Title: post.FeedName, HTMLURL: post.FeedUrl},
+		Categories:    categories,
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestGReaderItemIDs(t *testing.T) {
+	id := uuid.MustParse("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	short := greaderShortID(id)
+	if short >= 0 {
+		t.Fatalf("expected the high bit to make %v negative", short)
+	}
+	tests := []struct {
+		id   string
+		want string
+		err  bool
+	}{
+		{greaderLongID(id), "f47ac10b58cc4372", false},
+		{"tag:google.com,2005:reader/item/F47AC10B58CC4372", "f47ac10b58cc4372", false},
+		{"-830138926817852558", "f47ac10b58cc4372", false},
+		{"42", "000000000000002a", false},
+		{"tag:google.com,2005:reader/item/xyz", "", true},
+		{"tag:google.com,2005:reader/item/2a", "", true},
+		{"not-an-id", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := parseGReaderItemID(tt.id)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error: %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGReaderStreamParams(t *testing.T) {
+	user := database.User{ID: uuid.New()}
+	tests := []struct {
+		stream string
+		query  string
+		check  func(database.BrowsePostsForUserParams) bool
+		err    bool
+	}{
+		{greaderReadingList, "", func(p database.BrowsePostsForUserParams) bool {
+			return p.PostLimit == 20 && !p.UnreadOnly && !p.StarredOnly && !p.OldestFirst
+		}, false},
+		{greaderReadingList, "xt=" + url.QueryEscape(greaderRead) + "&n=50&r=o", func(p database.BrowsePostsForUserParams) bool {
+			return p.PostLimit == 50 && p.UnreadOnly && p.OldestFirst
+		}, false},
+		{greaderStarred, "n=100000", func(p database.BrowsePostsForUserParams) bool {
+			return p.StarredOnly && p.PostLimit == greaderMaxItems
+		}, false},
+		{"feed/https://example.com/feed.xml", "ot=1700000000", func(p database.BrowsePostsForUserParams) bool {
+			return p.Feed.String == "https://example.com/feed.xml" && p.Since.Valid && p.Since.Time.Unix() == 1700000000
+		}, false},
+		{"user/-/label/Tech", "", func(p database.BrowsePostsForUserParams) bool {
+			return p.Category.String == "Tech" && p.Category.Valid
+		}, false},
+		{"user/-/state/com.google/broadcast", "", nil, true},
+		{greaderReadingList, "n=0", nil, true},
+		{greaderReadingList, "c=nonsense", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stream+"?"+tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := greaderStreamParams(query, user, tt.stream, 20)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error: %v", err, tt.err)
+			}
+			if !tt.err && !tt.check(params) {
+				t.Errorf("unexpected params %+v", params)
+			}
+		})
+	}
+}
+
+func TestGReaderRequiresLogin(t *testing.T) {
+	server := testAPI(t, nil)
+	for _, path := range []string{
+		"/reader/api/0/user-info",
+		"/reader/api/0/subscription/list?output=json",
+		"/reader/api/0/unread-count?output=json",
+		"/reader/api/0/stream/contents/user/-/state/com.google/reading-list",
+	} {
+		res := call(t, server, "GET", path, "", nil)
+		expectStatus(t, res, http.StatusUnauthorized)
+	}
+}
+
+func TestGReaderLoginAndItems(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		server := testAPI(t, db)
+		expectStatus(t, call(t, server, "POST", "/api/v1/users", "", map[string]string{"name": "david", "password": "correct horse"}), http.StatusCreated)
+		var feed apiFeed
+		call(t, server, "POST", "/api/v1/feeds", testToken(t, db, "david", tokenScopeWrite), map[string]string{"name": "Blog", "url": "https://example.com/feed.xml"}).decode(t, &feed)
+		ids := []uuid.UUID{}
+		for i := range 3 {
+			params := database.CreatePostParams{
+				ID:          uuid.New(),
+				Title:       fmt.Sprintf("Post %d", i),
+				Url:         fmt.Sprintf("https://example.com/%d", i),
+				PublishedAt: sql.NullTime{Time: time.Date(2025, 1, 1, i, 0, 0, 0, time.UTC), Valid: true},
+				FeedID:      uuid.MustParse(feed.ID),
+			}
+			if _, err := db.CreatePost(context.Background(), params); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, params.ID)
+		}
+
+		credentials := url.Values{"Email": {"david"}, "Passwd": {"correct horse"}}
+		expectStatus(t, call(t, server, "GET", "/accounts/ClientLogin?"+credentials.Encode(), "", nil), http.StatusMethodNotAllowed)
+		expectStatus(t, call(t, server, "POST", "/accounts/ClientLogin?"+credentials.Encode(), "", nil), http.StatusForbidden)
+		res, err := server.Client().PostForm(server.URL+"/accounts/ClientLogin", credentials)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		_, auth, found := strings.Cut(string(body), "Auth=")
+		if res.StatusCode != http.StatusOK || !found {
+			t.Fatalf("login failed: %v %s", res.Status, body)
+		}
+
+		// Both forms of item id find their posts.
+		items := url.Values{"i": {greaderLongID(ids[0]), strconv.FormatInt(greaderShortID(ids[2]), 10)}}
+		req, err := http.NewRequest("POST", server.URL+"/reader/api/0/stream/items/contents", strings.NewReader(items.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "GoogleLogin auth="+strings.TrimSpace(auth))
+		res, err = server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var stream greaderStream
+		if err := json.NewDecoder(res.Body).Decode(&stream); err != nil {
+			t.Fatal(err)
+		}
+		titles := []string{}
+		for _, item := range stream.Items {
+			titles = append(titles, item.Title)
+		}
+		if !slices.Equal(titles, []string{"Post 2", "Post 0"}) {
+			t.Errorf("got items %v, want Post 2 and Post 0", titles)
+		}
+	})
+}
@@ -0,0 +1,894 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/config"
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The fixture every handler test starts from:
+//   - david is the admin and follows News, which has no posts.
+//   - larry added Blog and follows it. He has no password yet, like the
+//     accounts from before passwords, and a read token named laptop.
+//   - susan follows Blog in her Tech category. She has read the first of
+//     its three posts, starred the third and mutes titles with "Second".
+//     Her saved search firsts finds the first post.
+const (
+	blogURL         = "https://example.com/feed.xml"
+	newsURL         = "https://example.com/news.xml"
+	fixturePassword = "correct horse"
+	fixtureRuleID   = "6a1c0b8e-3f4d-4d6a-9a57-0c1f3e2b7d01"
+)
+
+// fixtureHash is fixturePassword hashed at the lowest cost, so creating the
+// fixture for every test stays fast.
+var fixtureHash = sync.OnceValue(func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(fixturePassword), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+})
+
+func seedFixture(t *testing.T, db *fakeDB) {
+	t.Helper()
+	ctx := context.Background()
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	david, larry, susan := testUser(t, db, "david"), testUser(t, db, "larry"), testUser(t, db, "susan")
+	for _, user := range []database.User{david, susan} {
+		if err := db.SetUserPassword(ctx, database.SetUserPasswordParams{ID: user.ID, PasswordHash: fixtureHash(), UpdatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := createAPIToken(ctx, db, larry, "laptop", tokenScopeRead, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	blog := testFeed(t, db, larry, "Blog", blogURL)
+	news := testFeed(t, db, david, "News", newsURL)
+	for _, follow := range []struct {
+		user database.User
+		feed database.Feed
+	}{{larry, blog}, {susan, blog}, {david, news}} {
+		params := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: follow.user.ID, FeedID: follow.feed.ID}
+		if _, err := db.CreateFeedFollow(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	published := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, title := range []string{"First post", "Second post", "Third post"} {
+		params := database.CreatePostParams{
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Title:       title,
+			Url:         fmt.Sprintf("https://example.com/posts/%d", i+1),
+			Description: "About the " + strings.ToLower(title),
+			PublishedAt: sql.NullTime{Time: published.AddDate(0, 0, i), Valid: true},
+			FeedID:      blog.ID,
+		}
+		post, err := db.CreatePost(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch i {
+		case 0:
+			err = markRead(ctx, db, susan.ID, post.ID)
+		case 2:
+			err = star(ctx, db, susan.ID, post.ID)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := db.CreateCategory(ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Tech", UserID: susan.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetFeedFollowCategory(ctx, database.SetFeedFollowCategoryParams{UserID: susan.ID, FeedID: blog.ID, Category: "Tech"}); err != nil {
+		t.Fatal(err)
+	}
+	rule := database.CreateRuleParams{ID: uuid.MustParse(fixtureRuleID), CreatedAt: now, UpdatedAt: now, UserID: susan.ID, Field: "title", Pattern: "Second", Action: "mute"}
+	if _, err := db.CreateRule(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+	search := database.CreateSavedSearchParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "firsts", Query: `post.title contains "First"`, UserID: susan.ID}
+	if _, err := db.CreateSavedSearch(ctx, search); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testFeed adds a feed directly in the database.
+func testFeed(t *testing.T, db database.Querier, user database.User, name, url string) database.Feed {
+	t.Helper()
+	params := database.CreateFeedParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, Url: url, UserID: user.ID}
+	feed, err := db.CreateFeed(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+// handlerTest runs one command line against a fresh fixture.
+type handlerTest struct {
+	name    string
+	args    []string // everything after gator, including -o
+	as      string   // the user logged in, if any
+	scope   string   // log in with an API token of this scope instead of a session
+	stdin   string
+	setup   func(t *testing.T, db *fakeDB)
+	wantErr string // part of the error; empty when the command should succeed
+	code    int    // the exit code wantErr maps to, when it matters
+	want    []string
+	notWant []string
+	check   func(t *testing.T, db *fakeDB)
+}
+
+func runHandlerTests(t *testing.T, tests []handlerTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			seedFixture(t, db)
+			if tt.setup != nil {
+				tt.setup(t, db)
+			}
+			out, err := runHandler(t, db, tt)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("gator %v: %v", strings.Join(tt.args, " "), err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("gator %v succeeded, want an error containing %q", strings.Join(tt.args, " "), tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("gator %v: error %q, want it to contain %q", strings.Join(tt.args, " "), err, tt.wantErr)
+			case tt.code != 0 && exitCode(err) != tt.code:
+				t.Errorf("gator %v: exit code %d, want %d", strings.Join(tt.args, " "), exitCode(err), tt.code)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output doesn't contain %q:\n%v", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("output contains %q:\n%v", notWant, out)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, db)
+			}
+		})
+	}
+}
+
+// runHandler runs the command the way main does and returns what it printed.
+// stdin is a file rather than a terminal, so passwords are read from it and
+// confirmations need --yes.
+func runHandler(t *testing.T, db *fakeDB, tt handlerTest) (string, error) {
+	t.Helper()
+	ctx := context.Background()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(tokenEnv, "")
+	s := &state{db: db, config: &config.Config{}}
+	if tt.as != "" {
+		user, err := db.GetUser(ctx, tt.as)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.scope != "" {
+			token, err := createAPIToken(ctx, db, user, "test", tt.scope, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(tokenEnv, token)
+		} else {
+			token, err := createSession(ctx, db, user, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.config.SessionToken = token
+		}
+	}
+
+	input := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(input, []byte(tt.stdin), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	oldStdin, oldReader := os.Stdin, stdin
+	os.Stdin, stdin = file, bufio.NewReader(file)
+	defer func() { os.Stdin, stdin = oldStdin, oldReader }()
+
+	output, args, err := extractOutputFlag(tt.args)
+	if err != nil {
+		return "", err
+	}
+	s.output = output
+	cliCommands := newCommands()
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = cliCommands.run(s, command{name: args[0], arguments: args[1:]})
+	})
+	return out, runErr
+}
+
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-output
+}
+
+func sessionsFor(db *fakeDB, name string) int {
+	user, _ := db.GetUser(context.Background(), name)
+	sessions := 0
+	for _, session := range db.sessions {
+		if session.UserID == user.ID {
+			sessions++
+		}
+	}
+	return sessions
+}
+
+func TestAccountCommands(t *testing.T) {
+	newPassword := "battery staple\nbattery staple\n"
+	runHandlerTests(t, []handlerTest{
+		{name: "unknown command", args: []string{"frobnicate"}, wantErr: "Command not found.", code: exitInvalidArgs},
+		{name: "login", args: []string{"login", "susan"}, stdin: fixturePassword + "\n", want: []string{"User has been set to: susan"},
+			check: func(t *testing.T, db *fakeDB) {
+				if sessionsFor(db, "susan") != 1 {
+					t.Error("login should start a session")
+				}
+			}},
+		{name: "login without a name", args: []string{"login"}, wantErr: "usage: login <name>", code: exitInvalidArgs},
+		{name: "login with the wrong password", args: []string{"login", "susan"}, stdin: "wrong password\n", wantErr: errBadLogin.Error(), code: exitUnauthorized},
+		{name: "login as nobody", args: []string{"login", "nobody"}, stdin: fixturePassword + "\n", wantErr: errBadLogin.Error()},
+		{name: "login without a password", args: []string{"login", "susan"}, wantErr: "No password given."},
+		{name: "login before a password is set", args: []string{"login", "larry"}, stdin: newPassword,
+			wantErr: "larry has no password yet. Ask an admin", code: exitUnauthorized,
+			check: func(t *testing.T, db *fakeDB) {
+				larry, _ := db.GetUser(context.Background(), "larry")
+				if larry.PasswordHash != "" || sessionsFor(db, "larry") != 0 {
+					t.Error("logging in must not let anyone claim a user without a password")
+				}
+			}},
+		{name: "logout", args: []string{"logout"}, as: "susan", want: []string{"Logged out."},
+			check: func(t *testing.T, db *fakeDB) {
+				if sessionsFor(db, "susan") != 0 {
+					t.Error("logout should end the session")
+				}
+			}},
+		{name: "logout when logged out", args: []string{"logout"}, wantErr: "Not logged in.", code: exitUnauthorized},
+		{name: "passwd", args: []string{"passwd"}, as: "susan", stdin: fixturePassword + "\n" + newPassword, want: []string{"Password changed for susan"},
+			check: func(t *testing.T, db *fakeDB) {
+				susan, _ := db.GetUser(context.Background(), "susan")
+				if !checkPassword(susan, "battery staple") || sessionsFor(db, "susan") != 1 {
+					t.Error("passwd should store the password and keep only a new session")
+				}
+			}},
+		{name: "passwd with the wrong password", args: []string{"passwd"}, as: "susan", stdin: "wrong password\n" + newPassword, wantErr: "Wrong password."},
+		{name: "passwd too short", args: []string{"passwd"}, as: "susan", stdin: fixturePassword + "\nshort\n", wantErr: "at least 8 characters"},
+		{name: "passwd mismatch", args: []string{"passwd"}, as: "susan", stdin: fixturePassword + "\nbattery staple\nbattery stable\n", wantErr: "Passwords don't match."},
+		{name: "passwd when logged out", args: []string{"passwd"}, wantErr: "Not logged in."},
+		{name: "passwd with a read token", args: []string{"passwd"}, as: "susan", scope: tokenScopeRead, wantErr: "read-only", code: exitUnauthorized},
+		{name: "register", args: []string{"register", "alice"}, stdin: newPassword, want: []string{"User Created:", "alice ("},
+			check: func(t *testing.T, db *fakeDB) {
+				alice, err := db.GetUser(context.Background(), "alice")
+				if err != nil || alice.IsAdmin || sessionsFor(db, "alice") != 1 {
+					t.Errorf("alice should be a logged in user, not an admin: %+v, %v", alice, err)
+				}
+			}},
+		{name: "register on an install without admins", args: []string{"register", "alice"}, stdin: newPassword,
+			setup: func(t *testing.T, db *fakeDB) {
+				for _, user := range db.users {
+					user.IsAdmin = false
+				}
+			},
+			check: func(t *testing.T, db *fakeDB) {
+				if alice, _ := db.GetUser(context.Background(), "alice"); !alice.IsAdmin {
+					t.Error("the first user registered when there are no admins should become one")
+				}
+			}},
+		{name: "register without a name", args: []string{"register"}, wantErr: "usage: register <name>", code: exitInvalidArgs},
+		{name: "register a taken name", args: []string{"register", "susan"}, stdin: newPassword, wantErr: "There's already a user named susan.", code: exitAlreadyExists},
+		{name: "register with a short password", args: []string{"register", "alice"}, stdin: "short\n", wantErr: "at least 8 characters"},
+	})
+}
+
+func TestUserCommands(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{name: "info", args: []string{"user", "info"}, as: "susan",
+			want: []string{"Name:          susan", "Admin:         false", "Following:     1 feeds", "Read posts:    1", "Starred posts: 1"}},
+		{name: "info as json", args: []string{"-o", "json", "user", "info", "larry"}, as: "david",
+			want: []string{`"name": "larry"`, `"feeds_added": 1`, `"api_tokens": 1`}},
+		{name: "info on someone else", args: []string{"user", "info", "larry"}, as: "susan", wantErr: "Only admins can manage other users.", code: exitUnauthorized},
+		{name: "info on nobody", args: []string{"user", "info", "nobody"}, as: "david", wantErr: "No user named nobody.", code: exitNotFound},
+		{name: "rename", args: []string{"user", "rename", "susan", "sue"}, as: "susan", want: []string{"User renamed: susan -> sue"}, notWant: []string{"Fever"},
+			check: func(t *testing.T, db *fakeDB) {
+				if _, err := db.GetUser(context.Background(), "sue"); err != nil {
+					t.Errorf("rename didn't stick: %v", err)
+				}
+			}},
+		{name: "rename removes the Fever key", args: []string{"user", "rename", "susan", "sue"}, as: "susan",
+			setup: func(t *testing.T, db *fakeDB) {
+				susan, _ := db.GetUser(context.Background(), "susan")
+				db.SetFeverAPIKey(context.Background(), database.SetFeverAPIKeyParams{UserID: susan.ID, ApiKey: feverAPIKey("susan", "fever secret")})
+			},
+			want: []string{"User renamed: susan -> sue", "The Fever password for sue stopped working. Set a new one with: gator fever-key"},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.feverKeys) != 0 {
+					t.Errorf("the Fever key for the old name should be gone: %+v", db.feverKeys)
+				}
+			}},
+		{name: "rename onto a taken name", args: []string{"user", "rename", "susan", "larry"}, as: "susan", wantErr: "There's already a user named larry."},
+		{name: "delete", args: []string{"user", "delete", "larry", "--transfer-to", "susan", "--yes"}, as: "david",
+			want: []string{"User deleted: larry (1 feeds transferred to susan)"},
+			check: func(t *testing.T, db *fakeDB) {
+				feed, _ := db.GetFeedByURL(context.Background(), blogURL)
+				if owner, _ := db.GetUsername(context.Background(), feed.UserID); owner.Name != "susan" {
+					t.Errorf("Blog should belong to susan, not %q", owner.Name)
+				}
+			}},
+		{name: "delete without --yes", args: []string{"user", "delete", "larry"}, as: "david", wantErr: "add --yes to confirm"},
+		{name: "delete as a user", args: []string{"user", "delete", "larry", "--yes"}, as: "susan", wantErr: "Only admins can delete users."},
+		{name: "delete the last admin", args: []string{"user", "delete", "david", "--transfer-to", "susan", "--yes"}, as: "david", wantErr: "Can't delete the last admin.", code: exitUnauthorized},
+		{name: "delete onto themselves", args: []string{"user", "delete", "larry", "--transfer-to", "larry", "--yes"}, as: "david", wantErr: "choose who takes over"},
+		{name: "passwd for someone else", args: []string{"user", "passwd", "larry"}, as: "david", stdin: "battery staple\nbattery staple\n",
+			want: []string{"Password set for larry."},
+			setup: func(t *testing.T, db *fakeDB) {
+				larry, _ := db.GetUser(context.Background(), "larry")
+				if _, err := createSession(context.Background(), db, larry, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, db *fakeDB) {
+				larry, _ := db.GetUser(context.Background(), "larry")
+				if !checkPassword(larry, "battery staple") || sessionsFor(db, "larry") != 0 {
+					t.Error("user passwd should store the password and log larry out")
+				}
+				if sessionsFor(db, "david") != 1 {
+					t.Error("user passwd shouldn't touch the admin's own session")
+				}
+			}},
+		{name: "passwd as a user", args: []string{"user", "passwd", "larry"}, as: "susan", stdin: "battery staple\nbattery staple\n",
+			wantErr: "Only admins can set other users' passwords.", code: exitUnauthorized},
+		{name: "passwd for yourself", args: []string{"user", "passwd", "david"}, as: "david", wantErr: "gator passwd", code: exitInvalidArgs},
+		{name: "passwd for nobody", args: []string{"user", "passwd", "nobody"}, as: "david", stdin: "battery staple\nbattery staple\n",
+			wantErr: "No user named nobody.", code: exitNotFound},
+		{name: "usage", args: []string{"user", "frobnicate"}, as: "susan", wantErr: userUsage, code: exitInvalidArgs},
+		{name: "admin grant", args: []string{"admin", "grant", "susan"}, as: "david", want: []string{"susan is now an admin."},
+			check: func(t *testing.T, db *fakeDB) {
+				if admins, _ := db.CountAdmins(context.Background()); admins != 2 {
+					t.Errorf("expected 2 admins, got %d", admins)
+				}
+			}},
+		{name: "admin revoke the last admin", args: []string{"admin", "revoke", "david"}, as: "david", wantErr: "Can't revoke the last admin.", code: exitUnauthorized},
+		{name: "admin as a user", args: []string{"admin", "grant", "susan"}, as: "susan", wantErr: "Only admins can run admin."},
+		{name: "admin grant nobody", args: []string{"admin", "grant", "nobody"}, as: "david", wantErr: "No user named nobody."},
+		{name: "admin usage", args: []string{"admin", "promote", "susan"}, as: "david", wantErr: adminUsage},
+		{name: "reset without --yes", args: []string{"reset"}, as: "david", wantErr: "add --yes to confirm"},
+		{name: "reset as a user", args: []string{"reset", "--yes"}, as: "susan", wantErr: "Only admins can run reset."},
+		{name: "reset usage", args: []string{"reset", "everything"}, as: "david", wantErr: "usage: reset [--yes]", code: exitInvalidArgs},
+		{name: "reset", args: []string{"reset", "--yes"}, as: "david", want: []string{"Succesfully reset table."},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.users) != 0 || len(db.feeds) != 0 {
+					t.Errorf("reset left %d users and %d feeds", len(db.users), len(db.feeds))
+				}
+			}},
+		{name: "users", args: []string{"users"}, as: "susan", want: []string{"* david\n", "* susan (current)\n", "* larry\n"}},
+		{name: "users logged out", args: []string{"users"}, want: []string{"* susan\n"}, notWant: []string{"(current)"}},
+		{name: "users as json", args: []string{"-o", "json", "users"}, as: "larry", want: []string{`"name": "larry",
+    "current": true`, `"name": "david",
+    "current": false`}},
+	})
+}
+
+func TestFeedCommands(t *testing.T) {
+	server := testFeedServer(t)
+	runHandlerTests(t, []handlerTest{
+		{name: "addfeed", args: []string{"addfeed", "Go Blog", "https://go.dev/blog/feed.atom"}, as: "larry",
+			want: []string{"Feed created:", "Go Blog (https://go.dev/blog/feed.atom)"},
+			check: func(t *testing.T, db *fakeDB) {
+				larry, _ := db.GetUser(context.Background(), "larry")
+				if stats, _ := db.GetUserStats(context.Background(), larry.ID); stats.Follows != 2 || stats.FeedsAdded != 2 {
+					t.Errorf("larry should follow the feed he added, got %+v", stats)
+				}
+			}},
+		{name: "addfeed from its URL", args: []string{"addfeed", server.URL + "/feed.xml"}, as: "larry",
+			want: []string{"Feed created:", "Example Blog (" + server.URL + "/feed.xml)"},
+			check: func(t *testing.T, db *fakeDB) {
+				feed, _ := db.GetFeedByURL(context.Background(), server.URL+"/feed.xml")
+				if feed.SiteUrl != server.URL+"/" || feed.Description != "Posts about examples" {
+					t.Errorf("the channel metadata wasn't stored: %+v", feed)
+				}
+			}},
+		{name: "addfeed without a title", args: []string{"addfeed", server.URL + "/untitled.xml"}, as: "larry", wantErr: "The feed has no title."},
+		{name: "addfeed twice", args: []string{"addfeed", "Again", blogURL}, as: "susan", wantErr: "There's already a feed with URL " + blogURL, code: exitAlreadyExists},
+		{name: "addfeed without arguments", args: []string{"addfeed"}, as: "larry", wantErr: "Not enough arguments."},
+		{name: "feeds", args: []string{"feeds"}, want: []string{"Name: Blog\nURL: " + blogURL, "Username: larry", "Name: News"}},
+		{name: "feeds as json", args: []string{"-o", "json", "feeds"}, want: []string{`"url": "` + newsURL + `"`, `"user": "david"`}},
+		{name: "feed info", args: []string{"feed", "info", blogURL}, as: "susan",
+			want: []string{"Name:        Blog", "Added by:    larry", "Followers:   2", "Posts:       3", "Posting:     7.0 posts/week", "Last fetch:  never"}},
+		{name: "feed info on an unknown feed", args: []string{"feed", "info", "https://example.com/nope.xml"}, as: "susan", wantErr: "No feed with URL https://example.com/nope.xml."},
+		{name: "feed rename", args: []string{"feed", "rename", blogURL, "Larry's Blog"}, as: "larry", want: []string{"Feed renamed: Blog -> Larry's Blog"}},
+		{name: "feed rename by a follower", args: []string{"feed", "rename", blogURL, "Mine"}, as: "susan", wantErr: "Only the user who added a feed or an admin can change it."},
+		{name: "feed delete while followed", args: []string{"feed", "delete", blogURL, "--yes"}, as: "larry", wantErr: "still followed by 1 other users", code: exitUnauthorized},
+		{name: "feed delete --force as a user", args: []string{"feed", "delete", blogURL, "--force", "--yes"}, as: "larry", wantErr: "Only admins can use --force."},
+		{name: "feed delete --force", args: []string{"feed", "delete", blogURL, "--force", "--yes"}, as: "david", want: []string{"Feed deleted: Blog"},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.posts) != 0 || len(db.follows) != 1 {
+					t.Errorf("the feed's posts and follows should be gone, %d posts and %d follows left", len(db.posts), len(db.follows))
+				}
+			}},
+		{name: "feed usage", args: []string{"feed", "info"}, as: "susan", wantErr: feedUsage},
+		{name: "follow", args: []string{"follow", newsURL}, as: "susan", want: []string{"(User: susan) now follows (Feed: News)"}},
+		{name: "follow twice", args: []string{"follow", blogURL}, as: "susan", wantErr: "You already follow " + blogURL, code: exitAlreadyExists},
+		{name: "follow an unknown feed", args: []string{"follow", "https://example.com/nope.xml"}, as: "susan", wantErr: "No feed with URL https://example.com/nope.xml.", code: exitNotFound},
+		{name: "follow without arguments", args: []string{"follow"}, as: "susan", wantErr: "Not enough arguments."},
+		{name: "follow when logged out", args: []string{"follow", newsURL}, wantErr: "Not logged in."},
+		{name: "following", args: []string{"following"}, as: "larry", want: []string{"larry follows:\nBlog\n"}},
+		{name: "following by category", args: []string{"following"}, as: "susan", want: []string{"susan follows:\n\nTech:\n  Blog\n"}},
+		{name: "following with a read token", args: []string{"--output=csv", "following"}, as: "susan", scope: tokenScopeRead,
+			want: []string{"feed,url,category\nBlog," + blogURL + ",Tech\n"}},
+		{name: "unfollow", args: []string{"unfollow", blogURL}, as: "susan",
+			check: func(t *testing.T, db *fakeDB) {
+				susan, _ := db.GetUser(context.Background(), "susan")
+				if follows, _ := db.GetFeedFollowsForUser(context.Background(), susan.ID); len(follows) != 0 {
+					t.Errorf("susan still follows %v", follows)
+				}
+			}},
+		{name: "unfollow with a read token", args: []string{"unfollow", blogURL}, as: "susan", scope: tokenScopeRead, wantErr: "read-only"},
+	})
+}
+
+func TestBrowseCommands(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{name: "newest first without muted posts", args: []string{"browse"}, as: "susan",
+			want: []string{"Title: Third post", "Title: First post", "About the third post"}, notWant: []string{"Second post"}},
+		{name: "show muted", args: []string{"browse", "--show-muted"}, as: "susan",
+			want: []string{"Title: Third post", "Title: Second post", "Next page: --cursor"}, notWant: []string{"First post"}},
+		{name: "everything for a user without rules", args: []string{"browse", "10"}, as: "larry",
+			want: []string{"Third post", "Second post", "First post"}},
+		{name: "unread", args: []string{"browse", "--unread", "--show-muted"}, as: "susan",
+			want: []string{"Second post", "Third post"}, notWant: []string{"First post"}},
+		{name: "starred", args: []string{"browse", "--starred"}, as: "susan", want: []string{"Third post"}, notWant: []string{"First post"}},
+		{name: "oldest first", args: []string{"browse", "--sort", "oldest", "--limit", "1"}, as: "larry",
+			want: []string{"First post", "Next page"}, notWant: []string{"Second post"}},
+		{name: "since", args: []string{"browse", "--since", "2025-01-02", "--show-muted"}, as: "susan",
+			want: []string{"Second post", "Third post"}, notWant: []string{"First post"}},
+		{name: "where", args: []string{"browse", "--where", `post.title contains "Third"`}, as: "susan",
+			want: []string{"Third post"}, notWant: []string{"First post"}},
+		{name: "saved search", args: []string{"browse", "--saved", "firsts"}, as: "susan", want: []string{"First post"}, notWant: []string{"Third post"}},
+		{name: "unknown saved search", args: []string{"browse", "--saved", "nope"}, as: "susan", wantErr: "No saved search named nope."},
+		{name: "category", args: []string{"browse", "--category", "Tech"}, as: "susan", want: []string{"Third post", "First post"}},
+		{name: "empty category", args: []string{"browse", "--category", "Nope"}, as: "susan", want: []string{"No posts found for your feeds!"}},
+		{name: "feed", args: []string{"browse", "--feed", "News"}, as: "david", want: []string{"No posts found for your feeds!"}},
+		{name: "cursor", args: []string{"browse", "--cursor", encodeCursor(time.Date(2025, 1, 2, 13, 0, 0, 0, time.UTC), uuid.Nil)}, as: "larry",
+			want: []string{"Second post", "First post"}, notWant: []string{"Third post"}},
+		{name: "invalid cursor", args: []string{"browse", "--cursor", "nope"}, as: "susan", wantErr: `invalid cursor "nope"`},
+		{name: "topping up past posts without a date", args: []string{"browse", "2", "--where", `post.title == "nope"`}, as: "susan",
+			setup: func(t *testing.T, db *fakeDB) {
+				for _, post := range db.posts {
+					post.PublishedAt = sql.NullTime{}
+				}
+			},
+			want: []string{"No posts found for your feeds!"}},
+		{name: "escape sequences in titles", args: []string{"browse", "1"}, as: "susan",
+			setup: func(t *testing.T, db *fakeDB) {
+				for _, post := range db.posts {
+					post.Title = "Pwned\x1b]0;pwn\x07\x1b[2J"
+					post.Description = "<p>Body\x1b[2J</p>"
+				}
+			},
+			want: []string{"Title: Pwned]0;pwn[2J", "Body[2J"}, notWant: []string{"\x1b", "\x07"}},
+		{name: "invalid limit", args: []string{"browse", "0"}, as: "susan", wantErr: "limit must be at least 1"},
+		{name: "as json", args: []string{"-o", "json", "browse", "--starred"}, as: "susan", want: []string{`"title": "Third post"`, `"starred": true`}},
+		{name: "with a read token", args: []string{"browse"}, as: "susan", scope: tokenScopeRead, want: []string{"Third post"}},
+		{name: "export", args: []string{"export"}, as: "susan",
+			want: []string{"<rss", "susan&#39;s gator timeline", "\n    <link>https://example.com/posts/3</link>", "<title>Third post</title>", "<title>First post</title>"}, notWant: []string{"Second post"}},
+		{name: "export with a link", args: []string{"export", "--link", "https://example.com/susan"}, as: "susan", want: []string{"\n    <link>https://example.com/susan</link>"}},
+		{name: "export atom", args: []string{"export", "--format", "atom", "--starred", "--title", "Stars"}, as: "susan",
+			want: []string{"<feed", "<title>Stars</title>", "Third post"}, notWant: []string{"First post"}},
+		{name: "export a saved search", args: []string{"export", "--saved", "firsts"}, as: "susan", want: []string{"First post"}, notWant: []string{"Third post"}},
+		{name: "export an unknown format", args: []string{"export", "--format", "json"}, as: "susan", wantErr: `unknown format "json"`},
+	})
+}
+
+func TestOrganizingCommands(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{name: "category add", args: []string{"category", "add", "Work"}, as: "susan", want: []string{"Category created: Work"}},
+		{name: "category add twice", args: []string{"category", "add", "Tech"}, as: "susan", wantErr: "You already have a category named Tech.", code: exitAlreadyExists},
+		{name: "category add someone else's name", args: []string{"category", "add", "Tech"}, as: "larry", want: []string{"Category created: Tech"}},
+		{name: "category list", args: []string{"category", "list"}, as: "susan", want: []string{"* Tech\n"}},
+		{name: "category rename", args: []string{"category", "rename", "Tech", "Reading"}, as: "susan", want: []string{"Category Tech renamed to Reading"},
+			check: func(t *testing.T, db *fakeDB) {
+				susan, _ := db.GetUser(context.Background(), "susan")
+				follows, _ := db.GetFeedFollowsForUser(context.Background(), susan.ID)
+				if len(follows) != 1 || follows[0].CategoryName.String != "Reading" {
+					t.Errorf("Blog should be in Reading, got %+v", follows)
+				}
+			}},
+		{name: "category rename an unknown category", args: []string{"category", "rename", "Nope", "Reading"}, as: "susan", wantErr: "No category named Nope."},
+		{name: "category rm", args: []string{"category", "rm", "Tech"}, as: "susan", want: []string{"Category removed: Tech"},
+			check: func(t *testing.T, db *fakeDB) {
+				if db.follows[1].CategoryID.Valid {
+					t.Error("removing a category should uncategorize its feeds")
+				}
+			}},
+		{name: "category rm an unknown category", args: []string{"category", "rm", "Nope"}, as: "susan", wantErr: "No category named Nope."},
+		{name: "category set", args: []string{"category", "set", newsURL, "Tech"}, as: "susan", wantErr: "You don't follow " + newsURL + " or have no category named Tech."},
+		{name: "category unset", args: []string{"category", "unset", blogURL}, as: "susan", want: []string{blogURL + " removed from its category"}},
+		{name: "category unset an unfollowed feed", args: []string{"category", "unset", blogURL}, as: "david", wantErr: "You don't follow " + blogURL + "."},
+		{name: "category usage", args: []string{"category", "add"}, as: "susan", wantErr: categoryUsage},
+		{name: "rule add", args: []string{"rule", "add", "star", "title", "(?i)third", "--regex"}, as: "susan", want: []string{"Rule created: "},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.rules) != 2 || !db.rules[1].IsRegex {
+					t.Errorf("expected a second, regex rule: %+v", db.rules)
+				}
+			}},
+		{name: "rule add an invalid regex", args: []string{"rule", "add", "star", "title", "(", "--regex"}, as: "susan", wantErr: "invalid regex"},
+		{name: "rule add an unknown action", args: []string{"rule", "add", "delete", "title", "Go"}, as: "susan", wantErr: `unknown action "delete"`},
+		{name: "rule list", args: []string{"rule", "list"}, as: "susan", want: []string{fixtureRuleID + `  mute when title contains "Second"`}},
+		{name: "rule rm", args: []string{"rule", "rm", fixtureRuleID}, as: "susan", want: []string{"Rule removed: " + fixtureRuleID}},
+		{name: "rule rm someone else's rule", args: []string{"rule", "rm", fixtureRuleID}, as: "larry", wantErr: "No rule with id " + fixtureRuleID + "."},
+		{name: "rule rm an invalid id", args: []string{"rule", "rm", "nope"}, as: "susan", wantErr: `invalid rule id "nope"`},
+		{name: "rule usage", args: []string{"rule", "add", "star", "title", "Go", "--glob"}, as: "susan", wantErr: ruleUsage},
+		{name: "saved-search add", args: []string{"saved-search", "add", "stars", "post.starred"}, as: "susan", want: []string{"Saved search created: stars"}},
+		{name: "saved-search add an invalid expression", args: []string{"saved-search", "add", "bad", "post.title contains"}, as: "susan", wantErr: "invalid expression"},
+		{name: "saved-search add twice", args: []string{"saved-search", "add", "firsts", "post.starred"}, as: "susan", wantErr: "You already have a saved search named firsts.", code: exitAlreadyExists},
+		{name: "saved-search list", args: []string{"saved-search", "list"}, as: "susan", want: []string{`* firsts (0 unread): post.title contains "First"`}},
+		{name: "saved-search list counts unread posts", args: []string{"saved-search", "list"}, as: "susan",
+			setup: func(t *testing.T, db *fakeDB) {
+				db.postStates[0].ReadAt = sql.NullTime{}
+			},
+			want: []string{"* firsts (1 unread)"}},
+		{name: "saved-search rm", args: []string{"saved-search", "rm", "firsts"}, as: "susan", want: []string{"Saved search removed: firsts"}},
+		{name: "saved-search rm an unknown search", args: []string{"saved-search", "rm", "nope"}, as: "susan", wantErr: "No saved search named nope."},
+		{name: "saved-search usage", args: []string{"saved-search"}, as: "susan", wantErr: savedSearchUsage},
+	})
+}
+
+func TestTokenCommands(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{name: "token create", args: []string{"token", "create", "ci", "--expires", "30d"}, as: "larry",
+			want: []string{"Token created: ci (write)", tokenPrefix, "Copy it now"},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.apiTokens) != 2 || !db.apiTokens[1].ExpiresAt.Valid {
+					t.Errorf("expected a second token that expires: %+v", db.apiTokens)
+				}
+			}},
+		{name: "token create twice", args: []string{"token", "create", "laptop"}, as: "larry", wantErr: "larry already has a token named laptop."},
+		{name: "token create with a bad scope", args: []string{"token", "create", "ci", "--scope", "admin"}, as: "larry", wantErr: `invalid scope "admin"`},
+		{name: "token create with a bad expiry", args: []string{"token", "create", "ci", "--expires", "soon"}, as: "larry", wantErr: `invalid expiry "soon"`},
+		{name: "token create with a read token", args: []string{"token", "create", "ci"}, as: "larry", scope: tokenScopeRead, wantErr: "read-only"},
+		{name: "token list", args: []string{"token", "list"}, as: "larry", want: []string{"* laptop (read, never expires, never used)"}},
+		{name: "token revoke", args: []string{"token", "revoke", "laptop"}, as: "larry", want: []string{"Token revoked: laptop"}},
+		{name: "token revoke an unknown token", args: []string{"token", "revoke", "laptop"}, as: "susan", wantErr: "No token named laptop."},
+		{name: "token usage", args: []string{"token"}, as: "larry", wantErr: tokenUsage},
+		{name: "fever-key", args: []string{"fever-key"}, as: "susan", stdin: "fever secret\nfever secret\n", want: []string{"Fever API key set. Log in as susan with that password."},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.feverKeys) != 1 || db.feverKeys[0].ApiKey != feverAPIKey("susan", "fever secret") {
+					t.Errorf("expected susan's key: %+v", db.feverKeys)
+				}
+			}},
+		{name: "fever-key --remove without a key", args: []string{"fever-key", "--remove"}, as: "susan", wantErr: "susan has no Fever API key.", code: exitNotFound},
+		{name: "fever-key with mismatched passwords", args: []string{"fever-key"}, as: "susan", stdin: "fever secret\nfever secrets\n", wantErr: "Passwords don't match.", code: exitInvalidArgs},
+		{name: "fever-key with the password as an argument", args: []string{"fever-key", "secret"}, as: "susan", wantErr: "usage: fever-key [--remove]", code: exitInvalidArgs},
+	})
+}
+
+func TestRetentionCommands(t *testing.T) {
+	// Everyone has read every post and only susan's star keeps the third.
+	readEverything := func(t *testing.T, db *fakeDB) {
+		for _, user := range db.users {
+			for _, post := range db.posts {
+				if err := markRead(context.Background(), db, user.ID, post.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		db.retention.KeepLatest = 0
+		db.retention.MaxPosts = 1
+	}
+	runHandlerTests(t, []handlerTest{
+		{name: "retention show", args: []string{"retention"}, as: "susan",
+			want: []string{"Max age:     off", "Max posts:   off", "Keep latest: 20 posts per feed"}, notWant: []string{"Feed overrides:"}},
+		{name: "retention set", args: []string{"retention", "set", "--max-age", "90d", "--keep-latest", "5"}, as: "david",
+			want: []string{"Retention set: max age 90 days, max posts off, keeping the latest 5 posts per feed."},
+			check: func(t *testing.T, db *fakeDB) {
+				if db.retention.MaxAgeDays != 90 || db.retention.KeepLatest != 5 {
+					t.Errorf("retention wasn't stored: %+v", db.retention)
+				}
+			}},
+		{name: "retention set as a user", args: []string{"retention", "set", "--max-posts", "2"}, as: "susan", wantErr: "Only admins can change the retention policy."},
+		{name: "retention set without flags", args: []string{"retention", "set"}, as: "david", wantErr: retentionUsage},
+		{name: "retention feed", args: []string{"retention", "feed", blogURL, "--max-posts", "2"}, as: "larry",
+			want: []string{"Retention for Blog: max age default, max posts 2."}},
+		{name: "retention feed by a follower", args: []string{"retention", "feed", blogURL, "--max-posts", "2"}, as: "susan",
+			wantErr: "Only the user who added a feed or an admin can change it."},
+		{name: "retention show with overrides", args: []string{"retention", "show"}, as: "susan",
+			setup: func(t *testing.T, db *fakeDB) {
+				db.feeds[0].RetentionMaxAgeDays = sql.NullInt32{Int32: 30, Valid: true}
+			},
+			want: []string{"Feed overrides:\n* Blog (" + blogURL + "), max age 30 days\n"}},
+		{name: "purge with nothing to purge", args: []string{"purge", "--yes"}, as: "david", want: []string{"Nothing to purge."}},
+		{name: "purge keeps unread posts", args: []string{"purge", "--dry-run"}, as: "david",
+			setup: func(t *testing.T, db *fakeDB) {
+				readEverything(t, db)
+				susan, _ := db.GetUser(context.Background(), "susan")
+				db.postState(susan.ID, db.posts[1].ID).ReadAt = sql.NullTime{}
+			},
+			want: []string{"Would purge 1 posts:\n* Blog: 1\n"}},
+		{name: "purge --dry-run", args: []string{"purge", "--dry-run"}, as: "david", setup: readEverything,
+			want: []string{"Would purge 2 posts:\n* Blog: 2\n"},
+			check: func(t *testing.T, db *fakeDB) {
+				if len(db.posts) != 3 {
+					t.Error("a dry run shouldn't delete anything")
+				}
+			}},
+		{name: "purge", args: []string{"purge", "--yes"}, as: "david", setup: readEverything, want: []string{"Purged 2 posts."},
+			check: func(t *testing.T, db *fakeDB) {
+				urls, _ := db.GetPurgedPostURLs(context.Background(), db.feeds[0].ID)
+				if len(db.posts) != 1 || db.posts[0].Title != "Third post" || len(urls) != 2 {
+					t.Errorf("only the starred post should be left, got %d posts and purged %q", len(db.posts), urls)
+				}
+			}},
+		{name: "purge without --yes", args: []string{"purge"}, as: "david", setup: readEverything, wantErr: "add --yes to confirm"},
+		{name: "purge as a user", args: []string{"purge", "--dry-run"}, as: "susan", wantErr: "Only admins can run purge."},
+		{name: "agg without an interval", args: []string{"agg"}, wantErr: "Not enough arguments."},
+		{name: "agg with a bad interval", args: []string{"agg", "soon"}, wantErr: `invalid duration "soon"`},
+		{name: "agg with an unknown flag", args: []string{"agg", "1m", "--now"}, wantErr: "usage: agg <interval> [--purge]"},
+	})
+}
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+  <title>%v</title>
+  <link>%v/</link>
+  <description>Posts about examples</description>
+  <language>en</language>
+  %v
+</channel>
+</rss>`
+
+const testRSSItems = `<item>
+    <title>Go 1.30 released</title>
+    <link>https://example.org/go-1-30</link>
+    <description>&lt;p&gt;New release.&lt;/p&gt;</description>
+    <content:encoded>&lt;p&gt;Everything that changed.&lt;/p&gt;</content:encoded>
+    <author>gopher@example.org</author>
+    <category>go</category>
+    <category>releases</category>
+    <pubDate>Mon, 02 Jun 2025 10:00:00 +0000</pubDate>
+  </item>
+  <item>
+    <title>An old post</title>
+    <link>https://example.org/old</link>
+    <pubDate>Wed, 01 Jan 2025 10:00:00 +0000</pubDate>
+  </item>
+  <item>
+    <title>Weekly notes</title>
+    <link>https://example.org/notes</link>
+    <pubDate>Sun, 01 Jun 2025 10:00:00 +0000</pubDate>
+  </item>`
+
+// testFeedServer serves RSS feeds: feed.xml with three posts, untitled.xml
+// without a title, bad-date.xml with a post whose date can't be read, and
+// broken.xml, which fails.
+func testFeedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := "http://" + r.Host
+		switch r.URL.Path {
+		case "/feed.xml":
+			fmt.Fprintf(w, testRSS, "Example Blog", site, testRSSItems)
+		case "/untitled.xml":
+			fmt.Fprintf(w, testRSS, "", site, testRSSItems)
+		case "/bad-date.xml":
+			fmt.Fprintf(w, testRSS, "Bad dates", site, "<item><title>When?</title><link>https://example.org/when</link><pubDate>yesterday</pubDate></item>")
+		case "/broken.xml":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrapeFeeds(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	server := testFeedServer(t)
+	postTitles := func(db *fakeDB) []string {
+		titles := []string{}
+		for _, post := range db.posts {
+			titles = append(titles, post.Title)
+		}
+		return titles
+	}
+	tests := []struct {
+		name      string
+		path      string
+		setup     func(t *testing.T, db *fakeDB, feed database.Feed)
+		wantErr   string
+		wantPosts []string
+		check     func(t *testing.T, db *fakeDB, feed database.Feed)
+	}{
+		{
+			name:      "new posts",
+			path:      "/feed.xml",
+			wantPosts: []string{"Go 1.30 released", "An old post", "Weekly notes"},
+			check: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				stored := db.feed(feed.ID)
+				if stored.Title != "Example Blog" || stored.SiteUrl != server.URL+"/" || stored.Language != "en" {
+					t.Errorf("the channel metadata wasn't stored: %+v", stored)
+				}
+				if !stored.LastFetchedAt.Valid || stored.LastError != "" {
+					t.Errorf("the fetch should be recorded without an error: %+v", stored)
+				}
+				post := db.posts[0]
+				if post.Content != "<p>Everything that changed.</p>" || post.Author != "gopher@example.org" || post.Categories != "go, releases" {
+					t.Errorf("the post wasn't stored in full: %+v", post)
+				}
+				if !post.PublishedAt.Time.Equal(time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)) {
+					t.Errorf("published at %v", post.PublishedAt.Time)
+				}
+				if _, err := db.GetFeedIconFetchedAt(context.Background(), feed.ID); err != nil {
+					t.Errorf("the icon lookup should be recorded even when there's no icon: %v", err)
+				}
+			},
+		},
+		{
+			name: "posts already stored or purged are skipped",
+			path: "/feed.xml",
+			setup: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				now := sql.NullTime{Time: time.Now(), Valid: true}
+				params := database.CreatePostParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Title: "Notes", Url: "https://example.org/notes", FeedID: feed.ID}
+				if _, err := db.CreatePost(context.Background(), params); err != nil {
+					t.Fatal(err)
+				}
+				db.purgedPosts = append(db.purgedPosts,
+					&database.PurgedPost{Url: "https://example.org/old", FeedID: feed.ID, PurgedAt: time.Now()},
+					&database.PurgedPost{Url: "https://example.org/gone", FeedID: feed.ID, PurgedAt: time.Now()},
+				)
+			},
+			wantPosts: []string{"Notes", "Go 1.30 released"},
+			check: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				urls, _ := db.GetPurgedPostURLs(context.Background(), feed.ID)
+				if !slices.Equal(urls, []string{"https://example.org/old"}) {
+					t.Errorf("only URLs still in the feed should stay purged, got %v", urls)
+				}
+			},
+		},
+		{
+			name: "followers' rules",
+			path: "/feed.xml",
+			setup: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				now := sql.NullTime{Time: time.Now(), Valid: true}
+				susan := testUser(t, db, "susan")
+				larry := testUser(t, db, "larry")
+				if _, err := db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: susan.ID, FeedID: feed.ID}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := db.CreateCategory(context.Background(), database.CreateCategoryParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Tech", UserID: susan.ID}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := db.SetFeedFollowCategory(context.Background(), database.SetFeedFollowCategoryParams{UserID: susan.ID, FeedID: feed.ID, Category: "Tech"}); err != nil {
+					t.Fatal(err)
+				}
+				for _, rule := range []database.CreateRuleParams{
+					{UserID: susan.ID, Field: "title", Pattern: "Go", Action: "star"},
+					{UserID: susan.ID, Field: "expr", Pattern: `post.title contains "notes"`, Action: "read"},
+					{UserID: susan.ID, Field: "expr", Pattern: `feed.category == "Tech" && post.title contains "old"`, Action: "star"},
+					{UserID: susan.ID, Field: "expr", Pattern: `feed.category == "News"`, Action: "read"},
+					{UserID: larry.ID, Field: "title", Pattern: "old", Action: "star"},
+				} {
+					rule.ID, rule.CreatedAt, rule.UpdatedAt = uuid.New(), now, now
+					if _, err := db.CreateRule(context.Background(), rule); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+			wantPosts: []string{"Go 1.30 released", "An old post", "Weekly notes"},
+			check: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				susan, _ := db.GetUser(context.Background(), "susan")
+				if stats, _ := db.GetUserStats(context.Background(), susan.ID); stats.StarredPosts != 2 || stats.ReadPosts != 1 {
+					t.Errorf("susan's rules should star two posts, one by its category, and read another, got %+v", stats)
+				}
+				if len(db.postStates) != 3 {
+					t.Errorf("rules of users who don't follow the feed shouldn't run: %+v", db.postStates)
+				}
+			},
+		},
+		{
+			name:    "a feed that fails",
+			path:    "/broken.xml",
+			wantErr: "error unmarshaling xml",
+			check: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				if stored := db.feed(feed.ID); !strings.Contains(stored.LastError, "error unmarshaling xml") || !stored.LastFetchedAt.Valid {
+					t.Errorf("the error should be recorded: %+v", stored)
+				}
+			},
+		},
+		{
+			name:    "a post without a date",
+			path:    "/bad-date.xml",
+			wantErr: `parsing time "yesterday"`,
+		},
+		{
+			name: "the feed fetched longest ago goes first",
+			path: "/feed.xml",
+			setup: func(t *testing.T, db *fakeDB, feed database.Feed) {
+				owner, _ := db.GetUsername(context.Background(), feed.UserID)
+				broken := testFeed(t, db, owner, "Broken", server.URL+"/broken.xml")
+				db.feed(feed.ID).LastFetchedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+				db.feed(broken.ID).LastFetchedAt = sql.NullTime{Time: time.Now().Add(-2 * time.Hour), Valid: true}
+			},
+			wantErr: "error unmarshaling xml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			feed := testFeed(t, db, testUser(t, db, "david"), "Example", server.URL+tt.path)
+			if tt.setup != nil {
+				tt.setup(t, db, feed)
+			}
+			err := scrapeFeeds(&state{db: db})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error %v, want it to contain %q", err, tt.wantErr)
+			}
+			if got := postTitles(db); !reflect.DeepEqual(got, append([]string{}, tt.wantPosts...)) {
+				t.Errorf("posts %q, want %q", got, tt.wantPosts)
+			}
+			if tt.check != nil {
+				tt.check(t, db, feed)
+			}
+		})
+	}
+
+	if err := scrapeFeeds(&state{db: newFakeDB()}); err != sql.ErrNoRows {
+		t.Errorf("with no feeds, got %v", err)
+	}
+}
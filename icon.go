@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/icon"
+	"github.com/google/uuid"
+)
+
+// iconRefreshInterval is how often a feed's icon is fetched again. Feeds
+// without an icon are tried again just as often.
+const iconRefreshInterval = 7 * 24 * time.Hour
+
+// refreshFeedIcon fetches the feed's icon when there isn't one yet or it's
+// due a refresh. When no icon can be found, an empty one is stored so the
+// site isn't asked again on every fetch. Only database errors are returned.
+func refreshFeedIcon(ctx context.Context, db database.Querier, metadata database.UpdateFeedMetadataParams) error {
+	fetchedAt, err := db.GetFeedIconFetchedAt(ctx, metadata.ID)
+	if err == nil && time.Since(fetchedAt) < iconRefreshInterval {
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	params := database.SetFeedIconParams{FeedID: metadata.ID, FetchedAt: time.Now().UTC(), Data: []byte{}}
+	found, err := icon.Fetch(ctx, metadata.ImageUrl, metadata.SiteUrl)
+	if err != nil {
+		log.Printf("no icon for %v: %v", metadata.Title, err)
+	} else {
+		params.SourceUrl, params.ContentType, params.Data, params.Hash = found.URL, found.ContentType, found.Data, found.Hash
+	}
+	return db.SetFeedIcon(ctx, params)
+}
+
+// iconPaths maps each feed the user follows that has an icon to the web UI
+// path it's served at. The hash is added so browsers can cache an
+// icon for as long as it doesn't change.
+func iconPaths(ctx context.Context, db database.Querier, userID uuid.UUID) (map[string]string, error) {
+	rows, err := db.GetFeedIconHashesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]string{}
+	for _, row := range rows {
+		paths[row.FeedID.String()] = "/feeds/" + row.FeedID.String() + "/icon?v=" + row.Hash[:12]
+	}
+	return paths, nil
+}
+
+func (api *apiServer) handleFeedIcon(w http.ResponseWriter, r *http.Request, user database.User) {
+	api.writeIcon(w, r)
+}
+
+func (api *apiServer) handleWebFeedIcon(w http.ResponseWriter, r *http.Request, page webPage) {
+	api.writeIcon(w, r)
+}
+
+// writeIcon serves the icon of the feed in the feedID path value. Icons
+// aren't private, so any signed in user can fetch any feed's icon.
+func (api *apiServer) writeIcon(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	feedIcon, err := api.state.db.GetFeedIcon(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	etag := `"` + feedIcon.Hash + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", feedIcon.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(feedIcon.Data)
+}
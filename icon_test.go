@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/icon"
+)
+
+func TestResizeIcon(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{16, 16, 16, 16},
+		{64, 64, 64, 64},
+		{256, 256, 64, 64},
+		{200, 100, 64, 32},
+		{100, 400, 16, 64},
+		{1000, 1, 64, 1},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, tt.w, tt.h))
+		got := icon.Resize(img, icon.Size).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("Resize(%dx%d) = %dx%d, want %dx%d", tt.w, tt.h, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+
+	// Each new pixel averages the ones it covers.
+	img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	for y := range 128 {
+		for x := range 128 {
+			if x%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	r, _, _, a := icon.Resize(img, icon.Size).At(10, 10).RGBA()
+	if r < 0x7000 || r > 0x9000 || a != 0xffff {
+		t.Errorf("expected mid grey, got r=%#x a=%#x", r, a)
+	}
+}
+
+func TestFetchIcon(t *testing.T) {
+	var big bytes.Buffer
+	if err := png.Encode(&big, image.NewRGBA(image.Rect(0, 0, 180, 180))); err != nil {
+		t.Fatal(err)
+	}
+	ico := testICO(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="icon" href="/missing.png"><link rel="apple-touch-icon" href="/static/touch.png"></head></html>`))
+	})
+	mux.HandleFunc("/static/touch.png", func(w http.ResponseWriter, r *http.Request) { w.Write(big.Bytes()) })
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) { w.Write(ico) })
+	mux.HandleFunc("/logo.txt", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("not an image")) })
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	t.Run("touch icon", func(t *testing.T) {
+		found, err := icon.Fetch(context.Background(), site.URL+"/logo.txt", site.URL+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.URL != site.URL+"/static/touch.png" || found.ContentType != "image/png" || len(found.Hash) != 64 {
+			t.Fatalf("unexpected icon %v %v %v", found.URL, found.ContentType, found.Hash)
+		}
+		img, err := png.Decode(bytes.NewReader(found.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != icon.Size || img.Bounds().Dy() != icon.Size {
+			t.Errorf("icon not resized: %v", img.Bounds())
+		}
+	})
+
+	t.Run("favicon", func(t *testing.T) {
+		found, err := icon.Fetch(context.Background(), "", site.URL+"/blog/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.URL != site.URL+"/favicon.ico" || found.ContentType != "image/png" {
+			t.Fatalf("unexpected icon %v %v", found.URL, found.ContentType)
+		}
+		img, err := png.Decode(bytes.NewReader(found.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The 128 pixel PNG inside the ICO is picked over the 16 pixel bitmap.
+		if img.Bounds().Dx() != icon.Size || img.Bounds().Dy() != icon.Size {
+			t.Errorf("icon not resized: %v", img.Bounds())
+		}
+	})
+
+	t.Run("slow site", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer slow.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := icon.Fetch(ctx, slow.URL+"/logo.png", slow.URL+"/"); err == nil {
+			t.Error("expected an error from a site that never answers")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Fetch took %v, it should give up with its context", elapsed)
+		}
+	})
+
+	t.Run("huge image", func(t *testing.T) {
+		huge := hugePNG(t)
+		var ico bytes.Buffer
+		binary.Write(&ico, binary.LittleEndian, []uint16{0, 1, 1})
+		binary.Write(&ico, binary.LittleEndian, []uint8{0, 0, 0, 0})
+		binary.Write(&ico, binary.LittleEndian, []uint16{1, 32})
+		binary.Write(&ico, binary.LittleEndian, []uint32{uint32(len(huge)), 6 + 16})
+		ico.Write(huge)
+		images := map[string][]byte{"/huge.png": huge, "/huge.ico": ico.Bytes()}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(images[r.URL.Path])
+		}))
+		defer server.Close()
+		for path := range images {
+			_, err := icon.Fetch(context.Background(), server.URL+path, "")
+			if err == nil || !strings.Contains(err.Error(), "too large (16000x16000)") {
+				t.Errorf("%v: got error %v, want the image refused before decoding", path, err)
+			}
+		}
+	})
+
+	t.Run("nothing", func(t *testing.T) {
+		if _, err := icon.Fetch(context.Background(), "", ""); err == nil {
+			t.Error("expected an error without any URLs")
+		}
+		if _, err := icon.Fetch(context.Background(), "file:///etc/passwd", ""); err == nil {
+			t.Error("expected an error for a file URL")
+		}
+	})
+}
+
+// testICO builds an ICO file with a 16 pixel bitmap and a 128 pixel PNG.
+func testICO(t *testing.T) []byte {
+	t.Helper()
+	var large bytes.Buffer
+	if err := png.Encode(&large, image.NewRGBA(image.Rect(0, 0, 128, 128))); err != nil {
+		t.Fatal(err)
+	}
+	bitmap := icoBitmap(16, 24, nil)
+	var ico bytes.Buffer
+	binary.Write(&ico, binary.LittleEndian, []uint16{0, 1, 2})
+	offset := uint32(6 + 2*16)
+	binary.Write(&ico, binary.LittleEndian, []uint8{16, 16, 0, 0})
+	binary.Write(&ico, binary.LittleEndian, []uint16{1, 24})
+	binary.Write(&ico, binary.LittleEndian, []uint32{uint32(len(bitmap)), offset})
+	binary.Write(&ico, binary.LittleEndian, []uint8{128, 128, 0, 0})
+	binary.Write(&ico, binary.LittleEndian, []uint16{1, 32})
+	binary.Write(&ico, binary.LittleEndian, []uint32{uint32(large.Len()), offset + uint32(len(bitmap))})
+	ico.Write(bitmap)
+	ico.Write(large.Bytes())
+	return ico.Bytes()
+}
+
+// hugePNG builds a tiny PNG whose header claims it is 16000 pixels square.
+func hugePNG(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	// The IHDR chunk follows the 8 byte signature: length, type, then the
+	// width and height, with a CRC of the type and data after them.
+	data := b.Bytes()
+	ihdr := data[8+4 : 8+4+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], 16000)
+	binary.BigEndian.PutUint32(ihdr[8:], 16000)
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+// icoBitmap builds the bitmap of a size by size ICO image with the given
+// depth. Pixel (x, y) is red when x < y and blue otherwise, and the top row
+// is masked out.
+func icoBitmap(size, depth int, palette []color.RGBA) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, []uint32{40, uint32(size), uint32(2 * size)})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, uint16(depth)})
+	binary.Write(&b, binary.LittleEndian, []uint32{0, 0, 0, 0, uint32(len(palette)), 0})
+	for _, c := range palette {
+		b.Write([]byte{c.B, c.G, c.R, 0})
+	}
+	stride := (size*depth + 31) / 32 * 4
+	for y := size - 1; y >= 0; y-- {
+		row := make([]byte, stride)
+		for x := range size {
+			red := x < y
+			switch depth {
+			case 24:
+				if red {
+					row[3*x+2] = 0xff
+				} else {
+					row[3*x] = 0xff
+				}
+			case 32:
+				if red {
+					row[4*x+2] = 0xff
+				} else {
+					row[4*x] = 0xff
+				}
+			case 1:
+				if !red {
+					row[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+		}
+		b.Write(row)
+	}
+	maskStride := (size + 31) / 32 * 4
+	for y := size - 1; y >= 0; y-- {
+		row := make([]byte, maskStride)
+		if y == 0 {
+			for x := range size {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+		b.Write(row)
+	}
+	return b.Bytes()
+}
+
+func TestDecodeICOBitmaps(t *testing.T) {
+	red, blue := color.RGBA{R: 0xff, A: 0xff}, color.RGBA{B: 0xff, A: 0xff}
+	tests := []struct {
+		name    string
+		depth   int
+		palette []color.RGBA
+	}{
+		{"24 bit", 24, nil},
+		{"32 bit without alpha", 32, nil},
+		{"1 bit", 1, []color.RGBA{red, blue}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bitmap := icoBitmap(8, tt.depth, tt.palette)
+			var ico bytes.Buffer
+			binary.Write(&ico, binary.LittleEndian, []uint16{0, 1, 1})
+			binary.Write(&ico, binary.LittleEndian, []uint8{8, 8, 0, 0})
+			binary.Write(&ico, binary.LittleEndian, []uint16{1, uint16(tt.depth)})
+			binary.Write(&ico, binary.LittleEndian, []uint32{uint32(len(bitmap)), 22})
+			ico.Write(bitmap)
+			site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(ico.Bytes()) }))
+			defer site.Close()
+
+			found, err := icon.Fetch(context.Background(), site.URL+"/favicon.ico", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(found.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[image.Point]color.RGBA{{0, 0}: {}, {7, 0}: {}, {1, 5}: red, {5, 1}: blue, {3, 3}: blue}
+			for p, c := range want {
+				if got := color.RGBAModel.Convert(img.At(p.X, p.Y)).(color.RGBA); got != c {
+					t.Errorf("pixel %v = %v, want %v", p, got, c)
+				}
+			}
+		})
+	}
+
+	for _, data := range [][]byte{{0, 0, 1, 0, 1, 0, 16, 16, 0, 0, 1, 0, 32, 0}, {0, 0, 1, 0, 0, 0}} {
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(data) }))
+		if _, err := icon.Fetch(context.Background(), site.URL+"/favicon.ico", ""); err == nil {
+			t.Errorf("expected an error for the broken ICO %v", data)
+		}
+		site.Close()
+	}
+}
@@ -7,8 +7,8 @@ import (
 )
 
 type Config struct {
-	DbUrl           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbUrl        string `json:"db_url"`
+	SessionToken string `json:"session_token,omitempty"`
 }
 
 func Read() (Config, error) {
@@ -33,9 +33,10 @@ func Read() (Config, error) {
 	return config, nil
 }
 
-func (cfg *Config) SetUser(username string) error {
-	// Set the current_user_name field and call the write() helper function.
-	cfg.CurrentUserName = username
+func (cfg *Config) SetSession(token string) error {
+	// Set the session_token field and call the write() helper function.
+	// An empty token logs out.
+	cfg.SessionToken = token
 	err := write(cfg)
 	if err != nil {
 		return errors.New("Error writing to ~/.gatorconfig.json")
@@ -55,7 +56,12 @@ func write(cfg *Config) error {
 	}
 	config_file := home + "/.gatorconfig.json"
 
-	err = os.WriteFile(config_file, jsonData, 0644)
+	// The file holds a session token, so only the owner may read it.
+	err = os.WriteFile(config_file, jsonData, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(config_file, 0600)
 	if err != nil {
 		return err
 	}
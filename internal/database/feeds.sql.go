@@ -8,6 +8,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_error, title, site_url, description, language, image_url, generator, retention_max_age_days, retention_max_posts
 `
 
 type CreateFeedParams struct {
@@ -52,10 +53,86 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.LastError,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, last_error, title, site_url, description, language, image_url, generator, retention_max_age_days, retention_max_posts FROM feeds WHERE url = $1
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.LastError,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const getFeedFollowerNames = `-- name: GetFeedFollowerNames :many
+SELECT users.name FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY users.name
+`
+
+func (q *Queries) GetFeedFollowerNames(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowerNames, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedID = `-- name: GetFeedID :one
 SELECT id FROM feeds WHERE url = $1
 `
@@ -67,14 +144,39 @@ func (q *Queries) GetFeedID(ctx context.Context, url string) (uuid.UUID, error)
 	return id, err
 }
 
+const getFeedPostStats = `-- name: GetFeedPostStats :one
+SELECT
+    COUNT(*) AS posts,
+    COALESCE(MIN(published_at), '1970-01-01')::timestamp AS oldest_published_at,
+    COALESCE(MAX(published_at), '1970-01-01')::timestamp AS newest_published_at
+FROM posts
+WHERE feed_id = $1
+`
+
+type GetFeedPostStatsRow struct {
+	Posts             int64
+	OldestPublishedAt time.Time
+	NewestPublishedAt time.Time
+}
+
+func (q *Queries) GetFeedPostStats(ctx context.Context, feedID uuid.UUID) (GetFeedPostStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostStats, feedID)
+	var i GetFeedPostStatsRow
+	err := row.Scan(&i.Posts, &i.OldestPublishedAt, &i.NewestPublishedAt)
+	return i, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds
+SELECT name, url, user_id, title, site_url, description FROM feeds
 `
 
 type GetFeedsRow struct {
-	Name   string
-	Url    string
-	UserID uuid.UUID
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	Title       string
+	SiteUrl     string
+	Description string
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -86,7 +188,14 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserID); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -119,6 +228,58 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow
 	return i, err
 }
 
+const listFeeds = `-- name: ListFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.name, feeds.url, feeds.last_fetched_at, users.name AS user_name
+FROM feeds
+INNER JOIN users ON users.id = feeds.user_id
+ORDER BY feeds.created_at, feeds.id
+LIMIT $1 OFFSET $2
+`
+
+type ListFeedsParams struct {
+	Limit  int32
+	Offset int32
+}
+
+type ListFeedsRow struct {
+	ID            uuid.UUID
+	CreatedAt     sql.NullTime
+	Name          string
+	Url           string
+	LastFetchedAt sql.NullTime
+	UserName      string
+}
+
+func (q *Queries) ListFeeds(ctx context.Context, arg ListFeedsParams) ([]ListFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeds, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFeedsRow
+	for rows.Next() {
+		var i ListFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
@@ -130,6 +291,23 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const renameFeed = `-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2, updated_at = $3
+WHERE id = $1
+`
+
+type RenameFeedParams struct {
+	ID        uuid.UUID
+	Name      string
+	UpdatedAt sql.NullTime
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.ID, arg.Name, arg.UpdatedAt)
+	return err
+}
+
 const resetFeeds = `-- name: ResetFeeds :exec
 DELETE FROM feeds
 `
@@ -138,3 +316,68 @@ func (q *Queries) ResetFeeds(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetFeeds)
 	return err
 }
+
+const setFeedLastError = `-- name: SetFeedLastError :exec
+UPDATE feeds
+SET last_error = $2
+WHERE id = $1
+`
+
+type SetFeedLastErrorParams struct {
+	ID        uuid.UUID
+	LastError string
+}
+
+func (q *Queries) SetFeedLastError(ctx context.Context, arg SetFeedLastErrorParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedLastError, arg.ID, arg.LastError)
+	return err
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = $2
+WHERE user_id = $3
+`
+
+type TransferFeedsParams struct {
+	ToUserID   uuid.UUID
+	UpdatedAt  sql.NullTime
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.UpdatedAt, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, site_url = $3, description = $4, language = $5, image_url = $6, generator = $7
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       string
+	SiteUrl     string
+	Description string
+	Language    string
+	ImageUrl    string
+	Generator   string
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.SiteUrl,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	return err
+}
@@ -6,26 +6,71 @@ package database
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     sql.NullTime
-	UpdatedAt     sql.NullTime
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  sql.NullTime
+	Name       string
+	TokenHash  string
+	Scope      string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	UserID     uuid.UUID
 }
 
-type FeedFollow struct {
+type Category struct {
 	ID        uuid.UUID
 	CreatedAt sql.NullTime
 	UpdatedAt sql.NullTime
+	Name      string
+	UserID    uuid.UUID
+}
+
+type Feed struct {
+	ID                  uuid.UUID
+	CreatedAt           sql.NullTime
+	UpdatedAt           sql.NullTime
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	LastError           string
+	Title               string
+	SiteUrl             string
+	Description         string
+	Language            string
+	ImageUrl            string
+	Generator           string
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
+}
+
+type FeedFollow struct {
+	ID         uuid.UUID
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	CategoryID uuid.NullUUID
+}
+
+type FeedIcon struct {
+	FeedID      uuid.UUID
+	FetchedAt   time.Time
+	SourceUrl   string
+	ContentType string
+	Data        []byte
+	Hash        string
+}
+
+type FeverApiKey struct {
 	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	CreatedAt sql.NullTime
+	ApiKey    string
 }
 
 type Post struct {
@@ -37,11 +82,66 @@ type Post struct {
 	Description string
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     string
+	Author      string
+	Categories  string
+	Seq         int64
 }
 
-type User struct {
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+}
+
+type PurgedPost struct {
+	Url      string
+	FeedID   uuid.UUID
+	PurgedAt time.Time
+}
+
+type RetentionSetting struct {
+	ID         int32
+	UpdatedAt  sql.NullTime
+	MaxAgeDays int32
+	MaxPosts   int32
+	KeepLatest int32
+}
+
+type Rule struct {
+	ID        uuid.UUID
+	CreatedAt sql.NullTime
+	UpdatedAt sql.NullTime
+	UserID    uuid.UUID
+	Field     string
+	Pattern   string
+	IsRegex   bool
+	Action    string
+}
+
+type SavedSearch struct {
 	ID        uuid.UUID
 	CreatedAt sql.NullTime
 	UpdatedAt sql.NullTime
 	Name      string
+	Query     string
+	UserID    uuid.UUID
+}
+
+type Session struct {
+	ID        uuid.UUID
+	CreatedAt sql.NullTime
+	ExpiresAt time.Time
+	TokenHash string
+	UserID    uuid.UUID
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    sql.NullTime
+	UpdatedAt    sql.NullTime
+	Name         string
+	PasswordHash string
+	IsAdmin      bool
 }
@@ -10,10 +10,143 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const browsePostsForUser = `-- name: BrowsePostsForUser :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    posts.content,
+    posts.author,
+    posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    categories.name AS category_name,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN categories
+ON categories.id = feed_follows.category_id
+WHERE feed_follows.user_id = $1
+AND ($2::text IS NULL OR feeds.url = $2 OR feeds.name = $2)
+AND ($3::text IS NULL OR categories.name = $3)
+AND ($4::timestamp IS NULL OR posts.published_at >= $4)
+AND ($5::timestamp IS NULL OR posts.published_at < $5)
+AND (NOT $6::bool OR post_states.read_at IS NULL)
+AND (NOT $7::bool OR post_states.starred_at IS NOT NULL)
+AND (
+    $8::timestamp IS NULL
+    OR ($9::bool AND (posts.published_at, posts.id) > ($8, $10::uuid))
+    OR (NOT $9::bool AND (posts.published_at, posts.id) < ($8, $10::uuid))
+)
+AND ($11::text[] IS NULL OR substr(replace(posts.id::text, '-', ''), 1, 16) = ANY($11::text[]))
+ORDER BY
+    CASE WHEN $9::bool THEN posts.published_at END ASC,
+    CASE WHEN $9::bool THEN posts.id END ASC,
+    CASE WHEN NOT $9::bool THEN posts.published_at END DESC,
+    CASE WHEN NOT $9::bool THEN posts.id END DESC
+LIMIT $12
+OFFSET $13
+`
+
+type BrowsePostsForUserParams struct {
+	UserID            uuid.UUID
+	Feed              sql.NullString
+	Category          sql.NullString
+	Since             sql.NullTime
+	Until             sql.NullTime
+	UnreadOnly        bool
+	StarredOnly       bool
+	CursorPublishedAt sql.NullTime
+	OldestFirst       bool
+	CursorID          uuid.NullUUID
+	ItemIds           []string
+	PostLimit         int32
+	PostOffset        int32
+}
+
+type BrowsePostsForUserRow struct {
+	ID           uuid.UUID
+	Title        string
+	Url          string
+	Description  string
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Content      string
+	Author       string
+	Categories   string
+	FeedName     string
+	FeedUrl      string
+	CategoryName sql.NullString
+	ReadAt       sql.NullTime
+	StarredAt    sql.NullTime
+}
+
+func (q *Queries) BrowsePostsForUser(ctx context.Context, arg BrowsePostsForUserParams) ([]BrowsePostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsForUser,
+		arg.UserID,
+		arg.Feed,
+		arg.Category,
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.CursorPublishedAt,
+		arg.OldestFirst,
+		arg.CursorID,
+		pq.Array(arg.ItemIds),
+		arg.PostLimit,
+		arg.PostOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsForUserRow
+	for rows.Next() {
+		var i BrowsePostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.CategoryName,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories)
 VALUES (
     $1,
     $2,
@@ -22,9 +155,12 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories, seq
 `
 
 type CreatePostParams struct {
@@ -36,6 +172,9 @@ type CreatePostParams struct {
 	Description string
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     string
+	Author      string
+	Categories  string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -48,6 +187,9 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
+		arg.Author,
+		arg.Categories,
 	)
 	var i Post
 	err := row.Scan(
@@ -59,6 +201,10 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		&i.Categories,
+		&i.Seq,
 	)
 	return i, err
 }
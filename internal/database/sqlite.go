@@ -0,0 +1,540 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteQueries runs the queries against SQLite. Most of the generated SQL
+// works there as it is; the queries below use Postgres features (casts,
+// arrays, intervals, CTEs that modify data, or sequences) and are rewritten.
+// Arrays are passed as JSON and read back with json_each.
+type SQLiteQueries struct {
+	*Queries
+}
+
+func NewSQLite(db DBTX) *SQLiteQueries {
+	return &SQLiteQueries{Queries: New(db)}
+}
+
+var _ Querier = (*SQLiteQueries)(nil)
+
+// jsonArray encodes values for json_each. A nil slice stays NULL, like
+// pq.Array does.
+func jsonArray[T any](values []T) (interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// sqliteTime scans a computed timestamp. The driver only turns text into
+// time.Time for columns declared as TIMESTAMP, so aggregates come back as
+// strings.
+type sqliteTime struct {
+	t *time.Time
+}
+
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func (s sqliteTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*s.t = v
+		return nil
+	case string:
+		for _, format := range sqliteTimeFormats {
+			if t, err := time.Parse(format, v); err == nil {
+				*s.t = t
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("can't scan %T %v as a time", value, value)
+}
+
+const sqliteBrowsePostsForUser = `-- name: BrowsePostsForUser :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    posts.content,
+    posts.author,
+    posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    categories.name AS category_name,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN categories
+ON categories.id = feed_follows.category_id
+WHERE feed_follows.user_id = $1
+AND ($2 IS NULL OR feeds.url = $2 OR feeds.name = $2)
+AND ($3 IS NULL OR categories.name = $3)
+AND ($4 IS NULL OR posts.published_at >= $4)
+AND ($5 IS NULL OR posts.published_at < $5)
+AND (NOT $6 OR post_states.read_at IS NULL)
+AND (NOT $7 OR post_states.starred_at IS NOT NULL)
+AND (
+    $8 IS NULL
+    OR ($9 AND (posts.published_at, posts.id) > ($8, $10))
+    OR (NOT $9 AND (posts.published_at, posts.id) < ($8, $10))
+)
+AND ($11 IS NULL OR substr(replace(posts.id, '-', ''), 1, 16) IN (SELECT value FROM json_each($11)))
+ORDER BY
+    CASE WHEN $9 THEN posts.published_at END ASC,
+    CASE WHEN $9 THEN posts.id END ASC,
+    CASE WHEN NOT $9 THEN posts.published_at END DESC,
+    CASE WHEN NOT $9 THEN posts.id END DESC
+LIMIT $12
+OFFSET $13
+`
+
+// sqliteBrowsePostsByItemID is used when item ids are given. SQLite plans a
+// query before it knows whether $11 is NULL, so only without that test can
+// it use posts_item_id_idx instead of scanning every post.
+var sqliteBrowsePostsByItemID = strings.Replace(sqliteBrowsePostsForUser, "$11 IS NULL OR ", "", 1)
+
+func (q *SQLiteQueries) BrowsePostsForUser(ctx context.Context, arg BrowsePostsForUserParams) ([]BrowsePostsForUserRow, error) {
+	itemIds, err := jsonArray(arg.ItemIds)
+	if err != nil {
+		return nil, err
+	}
+	query := sqliteBrowsePostsForUser
+	if itemIds != nil {
+		query = sqliteBrowsePostsByItemID
+	}
+	rows, err := q.db.QueryContext(ctx, query,
+		arg.UserID,
+		arg.Feed,
+		arg.Category,
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.CursorPublishedAt,
+		arg.OldestFirst,
+		arg.CursorID,
+		itemIds,
+		arg.PostLimit,
+		arg.PostOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsForUserRow
+	for rows.Next() {
+		var i BrowsePostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.CategoryName,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sqliteCreateFeedFollow = `-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING
+    id, created_at, updated_at, user_id, feed_id, category_id,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = feed_id) AS feed_name,
+    (SELECT users.name FROM users WHERE users.id = user_id) AS user_name
+`
+
+func (q *SQLiteQueries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
+	row := q.db.QueryRowContext(ctx, sqliteCreateFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	var i CreateFeedFollowRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.CategoryID,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+const sqliteCreatePost = `-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories, seq)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, (SELECT COALESCE(MAX(seq), 0) + 1 FROM posts))
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories, seq
+`
+
+func (q *SQLiteQueries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, sqliteCreatePost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Content,
+		arg.Author,
+		arg.Categories,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		&i.Categories,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const sqliteGetFeedPostStats = `-- name: GetFeedPostStats :one
+SELECT
+    COUNT(*) AS posts,
+    COALESCE(MIN(published_at), '1970-01-01') AS oldest_published_at,
+    COALESCE(MAX(published_at), '1970-01-01') AS newest_published_at
+FROM posts
+WHERE feed_id = $1
+`
+
+func (q *SQLiteQueries) GetFeedPostStats(ctx context.Context, feedID uuid.UUID) (GetFeedPostStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, sqliteGetFeedPostStats, feedID)
+	var i GetFeedPostStatsRow
+	err := row.Scan(&i.Posts, sqliteTime{&i.OldestPublishedAt}, sqliteTime{&i.NewestPublishedAt})
+	return i, err
+}
+
+const sqliteGetFeverItemIDs = `-- name: GetFeverItemIDs :many
+SELECT posts.seq
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND (NOT $2 OR post_states.read_at IS NULL)
+AND (NOT $3 OR post_states.starred_at IS NOT NULL)
+ORDER BY posts.seq
+`
+
+func (q *SQLiteQueries) GetFeverItemIDs(ctx context.Context, arg GetFeverItemIDsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, sqliteGetFeverItemIDs, arg.UserID, arg.UnreadOnly, arg.StarredOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var seq int64
+		if err := rows.Scan(&seq); err != nil {
+			return nil, err
+		}
+		items = append(items, seq)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sqliteGetFeverItems = `-- name: GetFeverItems :many
+SELECT
+    posts.seq,
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.content,
+    posts.author,
+    posts.published_at,
+    posts.feed_id,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.seq > $2
+AND ($3 = 0 OR posts.seq < $3)
+AND ($4 IS NULL OR posts.seq IN (SELECT value FROM json_each($4)))
+ORDER BY
+    CASE WHEN $3 > 0 THEN posts.seq END DESC,
+    posts.seq ASC
+LIMIT $5
+`
+
+func (q *SQLiteQueries) GetFeverItems(ctx context.Context, arg GetFeverItemsParams) ([]GetFeverItemsRow, error) {
+	withIds, err := jsonArray(arg.WithIds)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, sqliteGetFeverItems,
+		arg.UserID,
+		arg.SinceID,
+		arg.MaxID,
+		withIds,
+		arg.ItemLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverItemsRow
+	for rows.Next() {
+		var i GetFeverItemsRow
+		if err := rows.Scan(
+			&i.Seq,
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Content,
+			&i.Author,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sqliteGetPurgeablePosts = `-- name: GetPurgeablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.feed_id,
+        posts.published_at,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC NULLS LAST, posts.id) AS position
+    FROM posts
+)
+SELECT ranked.id, feeds.name AS feed_name
+FROM ranked
+INNER JOIN feeds
+ON feeds.id = ranked.feed_id
+WHERE ranked.position > $1
+AND (
+    (
+        COALESCE(feeds.retention_max_age_days, $2) > 0
+        AND ranked.published_at < datetime('now', '-' || COALESCE(feeds.retention_max_age_days, $2) || ' days')
+    )
+    OR (
+        COALESCE(feeds.retention_max_posts, $3) > 0
+        AND ranked.position > COALESCE(feeds.retention_max_posts, $3)
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = ranked.id AND post_states.starred_at IS NOT NULL
+)
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    LEFT JOIN post_states
+    ON post_states.post_id = ranked.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.feed_id = ranked.feed_id AND post_states.read_at IS NULL
+)
+ORDER BY feeds.name, ranked.published_at
+`
+
+func (q *SQLiteQueries) GetPurgeablePosts(ctx context.Context, arg GetPurgeablePostsParams) ([]GetPurgeablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, sqliteGetPurgeablePosts, arg.KeepLatest, arg.MaxAgeDays, arg.MaxPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPurgeablePostsRow
+	for rows.Next() {
+		var i GetPurgeablePostsRow
+		if err := rows.Scan(&i.ID, &i.FeedName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sqliteGetUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, COUNT(*) AS unread, COALESCE(MAX(posts.published_at), '1970-01-01') AS newest_published_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.read_at IS NULL
+GROUP BY posts.feed_id
+`
+
+func (q *SQLiteQueries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, sqliteGetUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Unread, sqliteTime{&i.NewestPublishedAt}); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sqliteMarkFeedsReadBefore = `-- name: MarkFeedsReadBefore :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, CURRENT_TIMESTAMP
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND posts.feed_id IN (SELECT value FROM json_each($2))
+AND posts.published_at < $3
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET read_at = COALESCE(post_states.read_at, CURRENT_TIMESTAMP)
+`
+
+func (q *SQLiteQueries) MarkFeedsReadBefore(ctx context.Context, arg MarkFeedsReadBeforeParams) error {
+	feedIds, err := jsonArray(arg.FeedIds)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.ExecContext(ctx, sqliteMarkFeedsReadBefore, arg.UserID, feedIds, arg.Before)
+	return err
+}
+
+const sqliteRecordPurgedPosts = `-- name: RecordPurgedPosts :exec
+INSERT INTO purged_posts (url, feed_id, purged_at)
+SELECT posts.url, posts.feed_id, $2
+FROM posts
+WHERE posts.id IN (SELECT value FROM json_each($1))
+ON CONFLICT (url) DO NOTHING
+`
+
+const sqliteDeletePurgedPosts = `-- name: DeletePurgedPosts :execrows
+DELETE FROM posts
+WHERE posts.id IN (SELECT value FROM json_each($1))
+`
+
+// PurgePosts records the posts' URLs and then deletes them. SQLite
+// can't delete inside a CTE, so it takes two statements; when the delete
+// fails the URLs are still skipped by agg and the posts can be purged
+// again later.
+func (q *SQLiteQueries) PurgePosts(ctx context.Context, arg PurgePostsParams) (int64, error) {
+	ids, err := jsonArray(arg.Ids)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := q.db.ExecContext(ctx, sqliteRecordPurgedPosts, ids, arg.PurgedAt); err != nil {
+		return 0, err
+	}
+	result, err := q.db.ExecContext(ctx, sqliteDeletePurgedPosts, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const sqlitePrunePurgedPosts = `-- name: PrunePurgedPosts :execrows
+DELETE FROM purged_posts
+WHERE feed_id = $1
+AND url NOT IN (SELECT value FROM json_each($2))
+`
+
+func (q *SQLiteQueries) PrunePurgedPosts(ctx context.Context, arg PrunePurgedPostsParams) (int64, error) {
+	urls, err := jsonArray(arg.Urls)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.db.ExecContext(ctx, sqlitePrunePurgedPosts, arg.FeedID, urls)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Transactor runs a group of queries in one transaction. Both *Queries and
+// *SQLiteQueries implement it when they were made from a *sql.DB.
+type Transactor interface {
+	InTx(ctx context.Context, f func(Querier) error) error
+}
+
+// InTx calls f with queries bound to a new transaction, which is committed
+// if f succeeds and rolled back if it returns an error.
+func (q *Queries) InTx(ctx context.Context, f func(Querier) error) error {
+	return inTx(ctx, q.db, func(tx *sql.Tx) Querier { return q.WithTx(tx) }, f)
+}
+
+// InTx is like Queries.InTx, keeping the SQLite versions of the queries.
+func (q *SQLiteQueries) InTx(ctx context.Context, f func(Querier) error) error {
+	return inTx(ctx, q.db, func(tx *sql.Tx) Querier { return &SQLiteQueries{Queries: q.WithTx(tx)} }, f)
+}
+
+func inTx(ctx context.Context, db DBTX, wrap func(*sql.Tx) Querier, f func(Querier) error) error {
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return errors.New("queries are already running in a transaction")
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(wrap(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+var (
+	_ Transactor = (*Queries)(nil)
+	_ Transactor = (*SQLiteQueries)(nil)
+)
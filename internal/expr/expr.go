@@ -0,0 +1,380 @@
+// Package expr implements the small filter language used by browse --where,
+// saved searches and rules, for example:
+//
+//	feed.name == "HN" && post.title matches "(?i)golang" && post.age < 24h
+//
+// Expressions are parsed and type-checked once by Compile, so mistakes are
+// reported before any post is looked at.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Type is the type of a value in an expression.
+type Type int
+
+const (
+	String Type = iota
+	Bool
+	Duration
+)
+
+func (t Type) String() string {
+	switch t {
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	}
+	return "duration"
+}
+
+// Env is the post and feed an expression is evaluated against.
+type Env struct {
+	Title       string
+	Description string
+	Content     string
+	Author      string
+	Categories  string
+	URL         string
+	PublishedAt time.Time
+	Read        bool
+	Starred     bool
+
+	FeedName string
+	FeedURL  string
+	Category string
+
+	// Now is used to work out post.age. The zero value means time.Now().
+	Now time.Time
+}
+
+type value struct {
+	s string
+	b bool
+	d time.Duration
+}
+
+type field struct {
+	typ Type
+	get func(*Env) value
+}
+
+var fields = map[string]field{
+	"post.title":       {String, func(e *Env) value { return value{s: e.Title} }},
+	"post.description": {String, func(e *Env) value { return value{s: e.Description} }},
+	"post.content":     {String, func(e *Env) value { return value{s: e.Content} }},
+	"post.author":      {String, func(e *Env) value { return value{s: e.Author} }},
+	"post.categories":  {String, func(e *Env) value { return value{s: e.Categories} }},
+	"post.url":         {String, func(e *Env) value { return value{s: e.URL} }},
+	"post.read":        {Bool, func(e *Env) value { return value{b: e.Read} }},
+	"post.starred":     {Bool, func(e *Env) value { return value{b: e.Starred} }},
+	"post.age": {Duration, func(e *Env) value {
+		now := e.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return value{d: now.Sub(e.PublishedAt)}
+	}},
+	"feed.name":     {String, func(e *Env) value { return value{s: e.FeedName} }},
+	"feed.url":      {String, func(e *Env) value { return value{s: e.FeedURL} }},
+	"feed.category": {String, func(e *Env) value { return value{s: e.Category} }},
+}
+
+// Fields lists the names that can be used in an expression.
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Error is a compile error, with the column it was found at.
+type Error struct {
+	Source string
+	Pos    int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s\n  %s\n  %s^", e.Pos+1, e.Msg, e.Source, strings.Repeat(" ", e.Pos))
+}
+
+// Program is a compiled expression.
+type Program struct {
+	source string
+	eval   func(*Env) value
+}
+
+// Compile parses and type-checks an expression. The whole expression must be
+// a bool.
+func Compile(source string) (*Program, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{source: source, tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok.pos, "unexpected %s", tok)
+	}
+	if n.typ != Bool {
+		return nil, p.errorf(n.pos, "expression must be true or false, but it is a %s", n.typ)
+	}
+	return &Program{source: source, eval: n.eval}, nil
+}
+
+// Match evaluates the program against env.
+func (p *Program) Match(env Env) bool {
+	return p.eval(&env).b
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+type node struct {
+	typ     Type
+	pos     int
+	eval    func(*Env) value
+	literal *string
+}
+
+type parser struct {
+	source string
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return &Error{Source: p.source, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return node{}, err
+	}
+	for p.peek().is(tokOp, "||") {
+		op := p.take()
+		right, err := p.parseAnd()
+		if err != nil {
+			return node{}, err
+		}
+		if err := p.expectBools(op, left, right); err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		left = node{typ: Bool, pos: left.pos, eval: func(e *Env) value { return value{b: l(e).b || r(e).b} }}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return node{}, err
+	}
+	for p.peek().is(tokOp, "&&") {
+		op := p.take()
+		right, err := p.parseNot()
+		if err != nil {
+			return node{}, err
+		}
+		if err := p.expectBools(op, left, right); err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		left = node{typ: Bool, pos: left.pos, eval: func(e *Env) value { return value{b: l(e).b && r(e).b} }}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().is(tokOp, "!") {
+		op := p.take()
+		operand, err := p.parseNot()
+		if err != nil {
+			return node{}, err
+		}
+		if operand.typ != Bool {
+			return node{}, p.errorf(operand.pos, "%s needs a bool, but this is a %s", op, operand.typ)
+		}
+		inner := operand.eval
+		return node{typ: Bool, pos: op.pos, eval: func(e *Env) value { return value{b: !inner(e).b} }}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+	op := p.peek()
+	if op.kind != tokOp || !isComparison(op.text) {
+		return left, nil
+	}
+	p.take()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+	l, r := left.eval, right.eval
+
+	switch op.text {
+	case "matches":
+		if left.typ != String {
+			return node{}, p.errorf(left.pos, "matches needs a string on the left, but this is a %s", left.typ)
+		}
+		if right.literal == nil {
+			return node{}, p.errorf(right.pos, "matches needs a quoted regular expression on the right")
+		}
+		re, err := regexp.Compile(*right.literal)
+		if err != nil {
+			return node{}, p.errorf(right.pos, "invalid regular expression: %v", err)
+		}
+		return node{typ: Bool, pos: left.pos, eval: func(e *Env) value { return value{b: re.MatchString(l(e).s)} }}, nil
+	case "contains":
+		if left.typ != String || right.typ != String {
+			return node{}, p.errorf(op.pos, "contains needs strings on both sides, but got %s and %s", left.typ, right.typ)
+		}
+		return node{typ: Bool, pos: left.pos, eval: func(e *Env) value {
+			return value{b: strings.Contains(strings.ToLower(l(e).s), strings.ToLower(r(e).s))}
+		}}, nil
+	}
+
+	if left.typ != right.typ {
+		return node{}, p.errorf(op.pos, "can't compare %s with %s", left.typ, right.typ)
+	}
+	if op.text != "==" && op.text != "!=" && left.typ != Duration {
+		return node{}, p.errorf(op.pos, "%s only works on durations, not %s", op.text, left.typ)
+	}
+	var compare func(a, b value) bool
+	switch op.text {
+	case "==":
+		compare = func(a, b value) bool { return a == b }
+	case "!=":
+		compare = func(a, b value) bool { return a != b }
+	case "<":
+		compare = func(a, b value) bool { return a.d < b.d }
+	case "<=":
+		compare = func(a, b value) bool { return a.d <= b.d }
+	case ">":
+		compare = func(a, b value) bool { return a.d > b.d }
+	case ">=":
+		compare = func(a, b value) bool { return a.d >= b.d }
+	}
+	return node{typ: Bool, pos: left.pos, eval: func(e *Env) value { return value{b: compare(l(e), r(e))} }}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.take()
+	switch tok.kind {
+	case tokString:
+		text := tok.text
+		v := value{s: text}
+		return node{typ: String, pos: tok.pos, literal: &text, eval: func(*Env) value { return v }}, nil
+	case tokDuration:
+		v := value{d: tok.duration}
+		return node{typ: Duration, pos: tok.pos, eval: func(*Env) value { return v }}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			v := value{b: tok.text == "true"}
+			return node{typ: Bool, pos: tok.pos, eval: func(*Env) value { return v }}, nil
+		}
+		f, ok := fields[tok.text]
+		if !ok {
+			if suggestion := closest(tok.text); suggestion != "" {
+				return node{}, p.errorf(tok.pos, "unknown field %s (did you mean %s?)", tok.text, suggestion)
+			}
+			return node{}, p.errorf(tok.pos, "unknown field %s (fields are %s)", tok.text, strings.Join(Fields(), ", "))
+		}
+		return node{typ: f.typ, pos: tok.pos, eval: f.get}, nil
+	case tokOp:
+		if tok.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return node{}, err
+			}
+			if closing := p.take(); !closing.is(tokOp, ")") {
+				return node{}, p.errorf(closing.pos, "expected ) to close the ( at column %d, found %s", tok.pos+1, closing)
+			}
+			return inner, nil
+		}
+	}
+	return node{}, p.errorf(tok.pos, "expected a field, string, duration or (, found %s", tok)
+}
+
+func (p *parser) expectBools(op token, left, right node) error {
+	if left.typ != Bool {
+		return p.errorf(left.pos, "%s needs bools on both sides, but this is a %s", op, left.typ)
+	}
+	if right.typ != Bool {
+		return p.errorf(right.pos, "%s needs bools on both sides, but this is a %s", op, right.typ)
+	}
+	return nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "matches", "contains":
+		return true
+	}
+	return false
+}
+
+// closest suggests the known field nearest to a misspelt name.
+func closest(name string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range Fields() {
+		if d := distance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
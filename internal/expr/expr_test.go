@@ -0,0 +1,203 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string // kind:text of each token before the end
+	}{
+		{`post.title == "Go"`, []string{"ident:post.title", "op:==", "string:Go"}},
+		{`"say \"hi\""`, []string{`string:say "hi"`}},
+		{`'it\'s'`, []string{"string:it's"}},
+		{`"a\tb\nc\\d"`, []string{"string:a\tb\nc\\d"}},
+		{`"\d+\.go"`, []string{`string:\d+\.go`}},
+		{`'"' "'"`, []string{`string:"`, "string:'"}},
+		{`24h 1.5d 90s`, []string{"duration:24h", "duration:1.5d", "duration:90s"}},
+		{`a and b or not c`, []string{"ident:a", "op:&&", "ident:b", "op:||", "op:!", "ident:c"}},
+		{`x matches y contains z`, []string{"ident:x", "op:matches", "ident:y", "op:contains", "ident:z"}},
+		{`(a<=b)>=c<d>e!=f!g`, []string{"op:(", "ident:a", "op:<=", "ident:b", "op:)", "op:>=", "ident:c", "op:<", "ident:d", "op:>", "ident:e", "op:!=", "ident:f", "op:!", "ident:g"}},
+		{"  \t\n", nil},
+	}
+	kinds := map[tokenKind]string{tokIdent: "ident", tokString: "string", tokDuration: "duration", tokOp: "op"}
+	for _, tt := range tests {
+		tokens, err := lex(tt.source)
+		if err != nil {
+			t.Errorf("lex(%q): %v", tt.source, err)
+			continue
+		}
+		if last := tokens[len(tokens)-1]; last.kind != tokEOF || last.pos != len([]rune(tt.source)) {
+			t.Errorf("lex(%q) should end with EOF at %d, got %+v", tt.source, len([]rune(tt.source)), last)
+		}
+		var got []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			got = append(got, kinds[tok.kind]+":"+tok.text)
+		}
+		if strings.Join(got, " | ") != strings.Join(tt.want, " | ") {
+			t.Errorf("lex(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+
+	tokens, err := lex(`post.age < 1.5d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[2].duration != 36*time.Hour || tokens[2].pos != 11 {
+		t.Errorf("1.5d = %v at %d, want 36h at 11", tokens[2].duration, tokens[2].pos)
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		pos    int
+		msg    string
+	}{
+		{`post.title == "Go`, 14, "string is never closed"},
+		{`'it\'s`, 0, "string is never closed"},
+		{`"ends in a backslash\"`, 0, "string is never closed"},
+		{`post.age < 10`, 11, "10 is not a duration (use a number followed by s, m, h, d or w, like 24h)"},
+		{`post.age < 3y`, 11, "3y is not a duration"},
+		{`post.age < 1.2.3h`, 11, "invalid number 1.2.3"},
+		{`post.read & post.starred`, 10, `unexpected character '&'`},
+		{`post.title = "Go"`, 11, `unexpected character '='`},
+		{`"é" @`, 4, `unexpected character '@'`},
+	}
+	for _, tt := range tests {
+		_, err := lex(tt.source)
+		var exprErr *Error
+		if !errors.As(err, &exprErr) {
+			t.Errorf("lex(%q) = %v, want an *Error", tt.source, err)
+			continue
+		}
+		if exprErr.Pos != tt.pos || !strings.HasPrefix(exprErr.Msg, tt.msg) {
+			t.Errorf("lex(%q) error at %d %q, want at %d %q", tt.source, exprErr.Pos, exprErr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	env := Env{
+		Title:       "Go 1.25 is released",
+		Description: "Release notes",
+		Author:      "Gopher",
+		Categories:  "go, releases",
+		URL:         "https://go.dev/blog/go1.25",
+		PublishedAt: now.Add(-30 * time.Hour),
+		Starred:     true,
+		FeedName:    "Go Blog",
+		FeedURL:     "https://go.dev/blog/feed.atom",
+		Category:    "Tech",
+		Now:         now,
+	}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`true`, true},
+		{`post.starred`, true},
+		{`post.read`, false},
+		{`feed.name == "Go Blog"`, true},
+		{`feed.name != "Go Blog"`, false},
+		{`feed.category == "Tech"`, true},
+		{`post.title contains "RELEASED"`, true},
+		{`post.categories contains "rust"`, false},
+		{`post.title matches "^Go \\d+\\.\\d+"`, true},
+		{`post.url matches "(?i)GO\.DEV"`, true},
+		{`post.age > 1d`, true},
+		{`post.age < 24h`, false},
+		{`post.age <= 30h && post.age >= 1800m`, true},
+		{`post.age == 30h`, true},
+		// && binds tighter than ||, and ! tighter than both.
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && false || true`, true},
+		{`!false && false`, false},
+		{`!(false && false)`, true},
+		{`not post.read and post.starred`, true},
+		{`!!post.starred`, true},
+		{`post.read or feed.name == "Go Blog" and post.age < 1w`, true},
+		{`(((post.starred)))`, true},
+	}
+	for _, tt := range tests {
+		program, err := Compile(tt.source)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.source, err)
+			continue
+		}
+		if got := program.Match(env); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.source, got, tt.want)
+		}
+		if program.String() != tt.source {
+			t.Errorf("String() = %q, want %q", program.String(), tt.source)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		pos    int
+		msg    string
+	}{
+		{`post.titel == "Go"`, 0, "unknown field post.titel (did you mean post.title?)"},
+		{`feed.nmae contains "x"`, 0, "unknown field feed.nmae (did you mean feed.name?)"},
+		{`author == "x"`, 0, "unknown field author (fields are feed.category, feed.name, feed.url, post.age, "},
+		{`post.title`, 0, "expression must be true or false, but it is a string"},
+		{`post.age`, 0, "expression must be true or false, but it is a duration"},
+		{`post.age < "1d"`, 9, "can't compare duration with string"},
+		{`post.read == "yes"`, 10, "can't compare bool with string"},
+		{`post.title < "m"`, 11, "< only works on durations, not string"},
+		{`post.read >= true`, 10, ">= only works on durations, not bool"},
+		{`post.read && post.title`, 13, "&& needs bools on both sides, but this is a string"},
+		{`post.age || post.read`, 0, "|| needs bools on both sides, but this is a duration"},
+		{`!post.title`, 1, "! needs a bool, but this is a string"},
+		{`post.read matches "x"`, 0, "matches needs a string on the left, but this is a bool"},
+		{`post.title matches feed.name`, 19, "matches needs a quoted regular expression on the right"},
+		{`post.title matches "(go"`, 19, "invalid regular expression: error parsing regexp: missing closing )"},
+		{`post.age contains "x"`, 9, "contains needs strings on both sides, but got duration and string"},
+		{`(post.read`, 10, "expected ) to close the ( at column 1, found end of expression"},
+		{`post.read (post.starred)`, 10, "unexpected ("},
+		{`post.read post.starred`, 10, "unexpected post.starred"},
+		{`post.read &&`, 12, "expected a field, string, duration or (, found end of expression"},
+		{`== "x"`, 0, "expected a field, string, duration or (, found =="},
+		{`"é" == post.titel`, 7, "unknown field post.titel"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.source)
+		var exprErr *Error
+		if !errors.As(err, &exprErr) {
+			t.Errorf("Compile(%q) = %v, want an *Error", tt.source, err)
+			continue
+		}
+		if exprErr.Pos != tt.pos || !strings.HasPrefix(exprErr.Msg, tt.msg) {
+			t.Errorf("Compile(%q) error at %d %q, want at %d %q", tt.source, exprErr.Pos, exprErr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	_, err := Compile(`post.read && post.titel == "Go"`)
+	want := "column 14: unknown field post.titel (did you mean post.title?)\n" +
+		"  post.read && post.titel == \"Go\"\n" +
+		"               ^"
+	if err == nil || err.Error() != want {
+		t.Errorf("got\n%v\nwant\n%v", err, want)
+	}
+}
+
+func TestAgeUsesNow(t *testing.T) {
+	program, err := Compile(`post.age < 1h`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !program.Match(Env{PublishedAt: time.Now().Add(-time.Minute)}) {
+		t.Error("without Now, post.age should be measured from the current time")
+	}
+}
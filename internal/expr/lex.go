@@ -0,0 +1,150 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokDuration
+	tokOp
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	pos      int
+	duration time.Duration
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return t.text
+}
+
+// words that can be used instead of symbols.
+var keywordOps = map[string]string{
+	"and":      "&&",
+	"or":       "||",
+	"not":      "!",
+	"matches":  "matches",
+	"contains": "contains",
+}
+
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	errorf := func(pos int, format string, args ...any) error {
+		return &Error{Source: source, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						b.WriteRune('\n')
+					case 't':
+						b.WriteRune('\t')
+					case '"', '\'', '\\':
+						b.WriteRune(runes[i])
+					default:
+						// Keep unknown escapes so regexes like "\d" work.
+						b.WriteRune('\\')
+						b.WriteRune(runes[i])
+					}
+					continue
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errorf(start, "string is never closed")
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			number := string(runes[start:i])
+			unitStart := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			unit := string(runes[unitStart:i])
+			size, ok := durationUnits[unit]
+			if !ok {
+				return nil, errorf(start, "%s%s is not a duration (use a number followed by s, m, h, d or w, like 24h)", number, unit)
+			}
+			amount, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return nil, errorf(start, "invalid number %s", number)
+			}
+			tokens = append(tokens, token{kind: tokDuration, text: number + unit, pos: start, duration: time.Duration(amount * float64(size))})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			word := string(runes[start:i])
+			if op, ok := keywordOps[word]; ok {
+				tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokIdent, text: word, pos: start})
+			}
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch {
+			case two == "==" || two == "!=" || two == "<=" || two == ">=" || two == "&&" || two == "||":
+				tokens = append(tokens, token{kind: tokOp, text: two, pos: start})
+				i += 2
+			case strings.ContainsRune("<>!()", r):
+				tokens = append(tokens, token{kind: tokOp, text: string(r), pos: start})
+				i++
+			default:
+				return nil, errorf(start, "unexpected character %q", r)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes)})
+	return tokens, nil
+}
@@ -0,0 +1,166 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+)
+
+// Fields a rule can match against. "any" matches if any of the others do,
+// and "expr" takes a filter expression (see package expr) as its pattern.
+var Fields = []string{"title", "description", "content", "author", "category", "any", "expr"}
+
+// Actions a rule can take on a matching post.
+const (
+	ActionMute      = "mute"
+	ActionHighlight = "highlight"
+	ActionStar      = "star"
+	ActionRead      = "read"
+)
+
+var Actions = []string{ActionMute, ActionHighlight, ActionStar, ActionRead}
+
+// Post holds the parts of a post, and the feed it came from, that rules look at.
+type Post struct {
+	Title       string
+	Description string
+	Content     string
+	Author      string
+	Categories  string
+	URL         string
+	PublishedAt time.Time
+	Read        bool
+	Starred     bool
+	FeedName    string
+	FeedURL     string
+	Category    string
+}
+
+// Env is the post as seen by filter expressions.
+func (p Post) Env() expr.Env {
+	return expr.Env{
+		Title:       p.Title,
+		Description: p.Description,
+		Content:     p.Content,
+		Author:      p.Author,
+		Categories:  p.Categories,
+		URL:         p.URL,
+		PublishedAt: p.PublishedAt,
+		Read:        p.Read,
+		Starred:     p.Starred,
+		FeedName:    p.FeedName,
+		FeedURL:     p.FeedURL,
+		Category:    p.Category,
+	}
+}
+
+// Rule is a compiled rule, ready to be matched against posts.
+type Rule struct {
+	Field   string
+	Action  string
+	match   func(string) bool
+	program *expr.Program
+}
+
+// Result is the combined outcome of every rule that matched a post.
+type Result struct {
+	Muted       bool
+	Highlighted bool
+	Starred     bool
+	Read        bool
+}
+
+// Compile checks a rule and prepares its pattern. Plain patterns match as a
+// case-insensitive substring; regex patterns use Go's regexp syntax.
+func Compile(field, pattern string, isRegex bool, action string) (Rule, error) {
+	if !slices.Contains(Fields, field) {
+		return Rule{}, fmt.Errorf("unknown field %q (use %s)", field, strings.Join(Fields, ", "))
+	}
+	if !slices.Contains(Actions, action) {
+		return Rule{}, fmt.Errorf("unknown action %q (use %s)", action, strings.Join(Actions, ", "))
+	}
+	if pattern == "" {
+		return Rule{}, fmt.Errorf("pattern can't be empty")
+	}
+	rule := Rule{Field: field, Action: action}
+	if field == "expr" {
+		program, err := expr.Compile(pattern)
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.program = program
+		return rule, nil
+	}
+	if isRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		rule.match = re.MatchString
+	} else {
+		lower := strings.ToLower(pattern)
+		rule.match = func(text string) bool {
+			return strings.Contains(strings.ToLower(text), lower)
+		}
+	}
+	return rule, nil
+}
+
+// CompileAll compiles rules loaded from the database.
+func CompileAll(rules []database.Rule) ([]Rule, error) {
+	compiled := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := Compile(rule.Field, rule.Pattern, rule.IsRegex, rule.Action)
+		if err != nil {
+			return nil, fmt.Errorf("rule %v: %w", rule.ID, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// Matches reports whether the rule's pattern is found in the post.
+func (r Rule) Matches(post Post) bool {
+	switch r.Field {
+	case "expr":
+		return r.program.Match(post.Env())
+	case "title":
+		return r.match(post.Title)
+	case "description":
+		return r.match(post.Description)
+	case "content":
+		return r.match(post.Content)
+	case "author":
+		return r.match(post.Author)
+	case "category":
+		return r.match(post.Categories)
+	}
+	return r.match(post.Title) || r.match(post.Description) || r.match(post.Content) ||
+		r.match(post.Author) || r.match(post.Categories)
+}
+
+// Evaluate runs every rule against the post.
+func Evaluate(rules []Rule, post Post) Result {
+	result := Result{}
+	for _, rule := range rules {
+		if !rule.Matches(post) {
+			continue
+		}
+		switch rule.Action {
+		case ActionMute:
+			result.Muted = true
+		case ActionHighlight:
+			result.Highlighted = true
+		case ActionStar:
+			result.Starred = true
+		case ActionRead:
+			result.Read = true
+		}
+	}
+	return result
+}
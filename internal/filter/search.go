@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+	"github.com/google/uuid"
+)
+
+const scanPageSize = 500
+
+// unreadCacheAge is how long UnreadCache keeps counts whose inputs look
+// unchanged. Searches on post.age drift as time passes, and a post arriving
+// in a feed just as another is read leaves that feed's count the same.
+const unreadCacheAge = 10 * time.Minute
+
+// FromBrowseRow converts a row from BrowsePostsForUser for matching.
+func FromBrowseRow(row database.BrowsePostsForUserRow) Post {
+	return Post{
+		Title:       row.Title,
+		Description: row.Description,
+		Content:     row.Content,
+		Author:      row.Author,
+		Categories:  row.Categories,
+		URL:         row.Url,
+		PublishedAt: row.PublishedAt.Time,
+		Read:        row.ReadAt.Valid,
+		Starred:     row.StarredAt.Valid,
+		FeedName:    row.FeedName,
+		FeedURL:     row.FeedUrl,
+		Category:    row.CategoryName.String,
+	}
+}
+
+// UnreadCounts counts the user's unread posts matching each program, so
+// saved searches can show unread counts like real feeds.
+func UnreadCounts(ctx context.Context, db database.Querier, userID uuid.UUID, programs []*expr.Program) ([]int64, error) {
+	counts := make([]int64, len(programs))
+	if len(programs) == 0 {
+		return counts, nil
+	}
+	params := database.BrowsePostsForUserParams{UserID: userID, UnreadOnly: true, PostLimit: scanPageSize}
+	for {
+		rows, err := db.BrowsePostsForUser(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			env := FromBrowseRow(row).Env()
+			for i, program := range programs {
+				if program.Match(env) {
+					counts[i]++
+				}
+			}
+		}
+		if !NextPage(&params, rows) {
+			return counts, nil
+		}
+	}
+}
+
+// NextPage moves the cursor in params past rows, the page it returned. It
+// reports false when there's nothing after the page: it wasn't full, or
+// its last post has no publish date to continue from. A NULL cursor means
+// the first page, so paging on from one would never end.
+func NextPage(params *database.BrowsePostsForUserParams, rows []database.BrowsePostsForUserRow) bool {
+	if len(rows) < int(params.PostLimit) {
+		return false
+	}
+	last := rows[len(rows)-1]
+	if !last.PublishedAt.Valid {
+		return false
+	}
+	params.CursorPublishedAt = last.PublishedAt
+	params.CursorID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	params.PostOffset = 0
+	return true
+}
+
+// UnreadCache remembers saved search unread counts between refreshes, so a
+// reader polling for new posts doesn't scan every unread post each time.
+// The counts are recomputed when the searches, the feeds' unread counts or
+// newest posts, or the follows' categories change, which happens whenever a
+// post arrives or is read, after Invalidate, and otherwise once they are
+// unreadCacheAge old. It's safe for concurrent use.
+type UnreadCache struct {
+	mu      sync.Mutex
+	key     string
+	counts  []int64
+	counted time.Time
+	now     func() time.Time
+}
+
+// Counts is UnreadCounts, reusing the last counts when feedCounts (from
+// GetUnreadCountsForUser), follows (from GetFeedFollowsForUser) and the
+// programs are the same as last time.
+func (c *UnreadCache) Counts(ctx context.Context, db database.Querier, userID uuid.UUID, programs []*expr.Program, feedCounts []database.GetUnreadCountsForUserRow, follows []database.GetFeedFollowsForUserRow) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	key := unreadCacheKey(userID, programs, feedCounts, follows)
+	if key == c.key && now().Sub(c.counted) < unreadCacheAge {
+		return slices.Clone(c.counts), nil
+	}
+	counts, err := UnreadCounts(ctx, db, userID, programs)
+	if err != nil {
+		return nil, err
+	}
+	c.key, c.counts, c.counted = key, counts, now()
+	return slices.Clone(counts), nil
+}
+
+// Invalidate makes the next Counts recount, for changes the counts it's given
+// don't show, like a post being starred.
+func (c *UnreadCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.key = ""
+}
+
+func unreadCacheKey(userID uuid.UUID, programs []*expr.Program, feedCounts []database.GetUnreadCountsForUserRow, follows []database.GetFeedFollowsForUserRow) string {
+	var b strings.Builder
+	b.WriteString(userID.String())
+	for _, program := range programs {
+		fmt.Fprintf(&b, "\x00%v", program)
+	}
+	// The rows come in no particular order.
+	feeds := []string{}
+	for _, count := range feedCounts {
+		feeds = append(feeds, fmt.Sprintf("%v=%d@%v", count.FeedID, count.Unread, count.NewestPublishedAt))
+	}
+	slices.Sort(feeds)
+	b.WriteString("\x00\x00" + strings.Join(feeds, ","))
+	categories := []string{}
+	for _, follow := range follows {
+		categories = append(categories, fmt.Sprintf("%v=%q", follow.FeedID, follow.CategoryName.String))
+	}
+	slices.Sort(categories)
+	b.WriteString("\x00\x00" + strings.Join(categories, ","))
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+	"github.com/google/uuid"
+)
+
+// countingDB serves unread posts and counts how often they're scanned.
+type countingDB struct {
+	database.Querier
+	posts []database.BrowsePostsForUserRow
+	scans int
+}
+
+func (db *countingDB) BrowsePostsForUser(ctx context.Context, arg database.BrowsePostsForUserParams) ([]database.BrowsePostsForUserRow, error) {
+	db.scans++
+	return db.posts, nil
+}
+
+func TestUnreadCache(t *testing.T) {
+	userID, feedID := uuid.New(), uuid.New()
+	db := &countingDB{posts: []database.BrowsePostsForUserRow{
+		{ID: uuid.New(), Title: "Go 1.30 released", FeedID: feedID},
+		{ID: uuid.New(), Title: "Weekly notes", FeedID: feedID},
+	}}
+	programs := []*expr.Program{}
+	for _, source := range []string{`post.title contains "go"`, `true`, `post.starred`, `feed.category == "news"`} {
+		program, err := expr.Compile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs = append(programs, program)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := &UnreadCache{now: func() time.Time { return now }}
+	feedCounts := []database.GetUnreadCountsForUserRow{{FeedID: feedID, Unread: 2, NewestPublishedAt: now}}
+	follows := []database.GetFeedFollowsForUserRow{{FeedID: feedID}}
+
+	steps := []struct {
+		name   string
+		change func()
+		want   []int64
+		scans  int
+	}{
+		{"first count", func() {}, []int64{1, 2, 0, 0}, 1},
+		{"nothing changed", func() { now = now.Add(time.Minute) }, []int64{1, 2, 0, 0}, 1},
+		{"a post arrived", func() {
+			db.posts = append(db.posts, database.BrowsePostsForUserRow{ID: uuid.New(), Title: "Go tips", FeedID: feedID})
+			feedCounts = []database.GetUnreadCountsForUserRow{{FeedID: feedID, Unread: 3, NewestPublishedAt: now}}
+		}, []int64{2, 3, 0, 0}, 2},
+		{"same counts in another order", func() {
+			feedCounts = append(feedCounts, database.GetUnreadCountsForUserRow{FeedID: uuid.New(), Unread: 0})
+			slices.Reverse(feedCounts)
+		}, []int64{2, 3, 0, 0}, 3},
+		{"reordered again", func() { slices.Reverse(feedCounts) }, []int64{2, 3, 0, 0}, 3},
+		{"a post was starred", func() {
+			db.posts[1].StarredAt = sql.NullTime{Time: now, Valid: true}
+			cache.Invalidate()
+		}, []int64{2, 3, 1, 0}, 4},
+		{"the feed moved to a category", func() {
+			for i := range db.posts {
+				db.posts[i].CategoryName = sql.NullString{String: "news", Valid: true}
+			}
+			follows = []database.GetFeedFollowsForUserRow{{FeedID: feedID, CategoryName: sql.NullString{String: "news", Valid: true}}}
+		}, []int64{2, 3, 1, 3}, 5},
+		{"the searches changed", func() { programs = programs[1:] }, []int64{3, 1, 3}, 6},
+		{"the counts are old", func() {
+			db.posts = db.posts[1:]
+			now = now.Add(unreadCacheAge)
+		}, []int64{2, 1, 2}, 7},
+	}
+	for _, step := range steps {
+		step.change()
+		got, err := cache.Counts(context.Background(), db, userID, programs, feedCounts, follows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, step.want) || db.scans != step.scans {
+			t.Errorf("%v: got %v after %d scans, want %v after %d", step.name, got, db.scans, step.want, step.scans)
+		}
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	dated := database.BrowsePostsForUserRow{ID: uuid.New(), PublishedAt: sql.NullTime{Time: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	undated := database.BrowsePostsForUserRow{ID: uuid.New()}
+	tests := []struct {
+		name string
+		rows []database.BrowsePostsForUserRow
+		want bool
+	}{
+		{"full page", []database.BrowsePostsForUserRow{undated, dated}, true},
+		{"short page", []database.BrowsePostsForUserRow{dated}, false},
+		{"ends with a post without a date", []database.BrowsePostsForUserRow{dated, undated}, false},
+	}
+	for _, tt := range tests {
+		params := database.BrowsePostsForUserParams{PostLimit: 2, PostOffset: 4}
+		if got := NextPage(&params, tt.rows); got != tt.want {
+			t.Errorf("%v: NextPage() = %v, want %v", tt.name, got, tt.want)
+		}
+		if tt.want && (params.CursorPublishedAt != dated.PublishedAt || params.CursorID.UUID != dated.ID || params.PostOffset != 0) {
+			t.Errorf("%v: the cursor should move past the last post: %+v", tt.name, params)
+		}
+	}
+}
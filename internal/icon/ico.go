@@ -0,0 +1,142 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// decodeICO decodes the largest image in an ICO file. Each image is either
+// a PNG or a BMP without its file header, whose height counts the 1 bit
+// transparency mask that follows the pixels.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[0:]) != 0 {
+		return nil, errors.New("not an ICO file")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errors.New("ICO file has no images")
+	}
+	// Pick the biggest image, then the one with the most colours.
+	best, bestArea, bestDepth := -1, 0, 0
+	for i := range count {
+		entry := data[6+16*i:]
+		w, h := int(entry[0]), int(entry[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		depth := int(binary.LittleEndian.Uint16(entry[6:]))
+		if w*h > bestArea || (w*h == bestArea && depth > bestDepth) {
+			best, bestArea, bestDepth = i, w*h, depth
+		}
+	}
+	entry := data[6+16*best:]
+	size := int(binary.LittleEndian.Uint32(entry[8:]))
+	offset := int(binary.LittleEndian.Uint32(entry[12:]))
+	if offset < 0 || size <= 0 || offset > len(data) || size > len(data)-offset {
+		return nil, errors.New("ICO image is outside the file")
+	}
+	entryData := data[offset : offset+size]
+	if bytes.HasPrefix(entryData, []byte("\x89PNG\r\n\x1a\n")) {
+		return decode(entryData)
+	}
+	return decodeDIB(entryData)
+}
+
+// decodeDIB decodes an uncompressed 1, 4, 8, 24 or 32 bit icon bitmap.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errors.New("ICO bitmap is too short")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:]))
+	w := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	h := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	depth := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	colors := int(binary.LittleEndian.Uint32(data[32:]))
+	if headerSize < 40 || headerSize > len(data) || w <= 0 || h <= 0 || w > 256 || h > 256 {
+		return nil, errors.New("ICO bitmap has a bad header")
+	}
+	// 32 bit icons sometimes say BI_BITFIELDS but are still BGRA.
+	if compression != 0 && !(compression == 3 && depth == 32) {
+		return nil, errors.New("compressed ICO bitmaps aren't supported")
+	}
+
+	var palette []color.NRGBA
+	pos := headerSize
+	switch depth {
+	case 1, 4, 8:
+		if colors == 0 || colors > 1<<depth {
+			colors = 1 << depth
+		}
+		if len(data) < pos+4*colors {
+			return nil, errors.New("ICO bitmap palette is truncated")
+		}
+		for i := range colors {
+			c := data[pos+4*i:]
+			palette = append(palette, color.NRGBA{R: c[2], G: c[1], B: c[0], A: 0xff})
+		}
+		pos += 4 * colors
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("%d bit ICO bitmaps aren't supported", depth)
+	}
+
+	// Rows are stored bottom up and padded to 4 bytes, first the colours,
+	// then the mask where a set bit is a transparent pixel.
+	stride := (w*depth + 31) / 32 * 4
+	maskStride := (w + 31) / 32 * 4
+	pixels := data[pos:]
+	if len(pixels) < stride*h {
+		return nil, errors.New("ICO bitmap pixels are truncated")
+	}
+	mask := pixels[stride*h:]
+	if len(mask) < maskStride*h {
+		mask = nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	hasAlpha := false
+	for y := range h {
+		row := pixels[(h-1-y)*stride:]
+		for x := range w {
+			var c color.NRGBA
+			switch depth {
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 0xff}
+			default:
+				bit := x * depth
+				index := int(row[bit/8]>>(8-depth-bit%8)) & (1<<depth - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	// 32 bit icons carry their own alpha; older ones only have the mask.
+	if depth == 32 && hasAlpha {
+		return img, nil
+	}
+	for y := range h {
+		for x := range w {
+			if mask != nil && mask[(h-1-y)*maskStride+x/8]&(0x80>>(x%8)) != 0 {
+				img.SetNRGBA(x, y, color.NRGBA{})
+			} else if depth == 32 {
+				c := img.NRGBAAt(x, y)
+				c.A = 0xff
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,228 @@
+// Package icon finds and fetches the small image that identifies a feed: the
+// channel's <image>, an icon the site's home page links to, or its
+// /favicon.ico. PNG, JPEG, GIF and ICO images are scaled down to Size and
+// stored as PNG.
+package icon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// Size is the largest width or height of a stored icon.
+	Size = 64
+	// maxBytes is the most that is downloaded for an icon or home page.
+	maxBytes = 1 << 20
+	// maxDimension is the largest width or height of an image that is
+	// decoded. A small file can declare a huge image, so it's checked first.
+	maxDimension = 2048
+	// requestTimeout limits each download, and fetchTimeout all of them, so
+	// a slow site can't hold up the aggregator.
+	requestTimeout = 10 * time.Second
+	fetchTimeout   = 30 * time.Second
+)
+
+var client = &http.Client{Timeout: requestTimeout}
+
+// Icon is an image ready to be stored and served.
+type Icon struct {
+	URL         string
+	ContentType string
+	Data        []byte
+	Hash        string
+}
+
+// Fetch returns the first usable icon for a feed, trying imageURL (usually
+// the channel's <image>), then the icons siteURL's home page links to, then
+// siteURL's /favicon.ico.
+func Fetch(ctx context.Context, imageURL, siteURL string) (*Icon, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	candidates := []string{}
+	if imageURL != "" {
+		candidates = append(candidates, imageURL)
+	}
+	if site, err := url.Parse(siteURL); err == nil && (site.Scheme == "http" || site.Scheme == "https") {
+		candidates = append(candidates, pageIcons(ctx, site)...)
+		candidates = append(candidates, site.ResolveReference(&url.URL{Path: "/favicon.ico"}).String())
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("no image or site link to find an icon from")
+	}
+	var errs []error
+	for _, candidate := range candidates {
+		icon, err := fetchImage(ctx, candidate)
+		if err == nil {
+			return icon, nil
+		}
+		errs = append(errs, fmt.Errorf("%v: %w", candidate, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// pageIcons lists the icons a home page links to, best first. Errors are
+// ignored, since /favicon.ico is still worth trying.
+func pageIcons(ctx context.Context, site *url.URL) []string {
+	data, err := get(ctx, site.String())
+	if err != nil {
+		return nil
+	}
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	var touch, icons []string
+	for node := range doc.Descendants() {
+		if node.Type != html.ElementNode || node.DataAtom != atom.Link {
+			continue
+		}
+		var rel, href string
+		for _, attr := range node.Attr {
+			switch attr.Key {
+			case "rel":
+				rel = strings.ToLower(attr.Val)
+			case "href":
+				href = strings.TrimSpace(attr.Val)
+			}
+		}
+		ref, err := url.Parse(href)
+		if href == "" || err != nil {
+			continue
+		}
+		resolved := site.ResolveReference(ref).String()
+		for _, value := range strings.Fields(rel) {
+			if value == "apple-touch-icon" {
+				touch = append(touch, resolved)
+				break
+			}
+			if value == "icon" {
+				icons = append(icons, resolved)
+				break
+			}
+		}
+	}
+	// Touch icons are bigger, so they scale down better than favicons.
+	return append(touch, icons...)
+}
+
+func fetchImage(ctx context.Context, imageURL string) (*Icon, error) {
+	data, err := get(ctx, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	var img image.Image
+	switch contentType := http.DetectContentType(data); contentType {
+	case "image/png", "image/jpeg", "image/gif":
+		img, err = decode(data)
+	case "image/x-icon":
+		img, err = decodeICO(data)
+	default:
+		return nil, fmt.Errorf("not an icon (%v)", contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Resize(img, Size)); err != nil {
+		return nil, err
+	}
+	icon := &Icon{URL: imageURL, ContentType: "image/png", Data: buf.Bytes()}
+	sum := sha256.Sum256(icon.Data)
+	icon.Hash = hex.EncodeToString(sum[:])
+	return icon, nil
+}
+
+// decode decodes a PNG, JPEG or GIF image, as long as it isn't too big.
+func decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, fmt.Errorf("image is too large (%dx%d)", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+func get(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", "gator")
+	res, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBytes {
+		return nil, fmt.Errorf("larger than %d bytes", maxBytes)
+	}
+	return data, nil
+}
+
+// Resize scales img down to fit in a size by size square, keeping its shape,
+// by averaging the pixels each new pixel covers. Smaller images are returned
+// as they are.
+func Resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	dw, dh := size, size
+	if w > h {
+		dh = max(1, h*size/w)
+	} else {
+		dw = max(1, w*size/h)
+	}
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		y0, y1 := bounds.Min.Y+y*h/dh, bounds.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := range dw {
+			x0, x1 := bounds.Min.X+x*w/dw, bounds.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			out.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return out
+}
@@ -0,0 +1,421 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const defaultWidth = 80
+
+// Options controls how post HTML is turned into terminal text.
+type Options struct {
+	// Width is the column the text is wrapped at. Zero means 80.
+	Width int
+	// ANSI adds bold, italic, underline and colour escape codes.
+	ANSI bool
+}
+
+type style uint8
+
+const (
+	bold style = 1 << iota
+	italic
+	underline
+	code
+)
+
+type span struct {
+	text  string
+	style style
+}
+
+// word is a run of text without whitespace, possibly mixing styles.
+type word []span
+
+// prefix is the indentation added by a list item or blockquote. A list
+// item shows its bullet on its first line only.
+type prefix struct {
+	text   string
+	width  int
+	bullet string
+}
+
+type list struct {
+	ordered bool
+	count   int
+}
+
+type renderer struct {
+	opts Options
+
+	lines       []string
+	needBlank   bool
+	blankPrefix string
+
+	words []word
+	space bool
+	style style
+
+	prefixes []prefix
+	lists    []list
+
+	links []string
+}
+
+// HTML renders a post body as wrapped text. Links are numbered in the text
+// and listed as footnotes at the end, code blocks keep their layout and list
+// items get bullets. Text without any markup is treated as paragraphs
+// separated by blank lines.
+func HTML(source string, opts Options) string {
+	if opts.Width <= 0 {
+		opts.Width = defaultWidth
+	}
+	if !strings.Contains(source, "<") {
+		source = plainToHTML(source)
+	}
+	doc, err := nethtml.Parse(strings.NewReader(source))
+	if err != nil {
+		return source
+	}
+
+	r := &renderer{opts: opts}
+	r.walk(doc)
+	r.flush()
+	if len(r.links) > 0 {
+		r.block()
+		for i, link := range r.links {
+			r.emit(r.styled(fmt.Sprintf("[%d]", i+1), code) + " " + link)
+		}
+	}
+	return strings.Join(r.lines, "\n")
+}
+
+// StripControl removes control characters other than newlines and tabs, so
+// text from a feed can't retitle the terminal, clear it or fake links with
+// escape sequences.
+func StripControl(text string) string {
+	return strings.Map(func(c rune) rune {
+		if c != '\n' && c != '\t' && unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, text)
+}
+
+func plainToHTML(text string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		b.WriteString("<p>" + html.EscapeString(paragraph) + "</p>")
+	}
+	return b.String()
+}
+
+func (r *renderer) walk(n *nethtml.Node) {
+	switch n.Type {
+	case nethtml.TextNode:
+		r.text(n.Data)
+		return
+	case nethtml.ElementNode:
+	default:
+		r.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Noscript, atom.Iframe:
+	case atom.Br:
+		r.flush()
+	case atom.Hr:
+		r.block()
+		r.emit(r.styled(strings.Repeat("─", max(r.width(), 3)), code))
+		r.block()
+	case atom.Pre:
+		r.block()
+		r.pre(n)
+		r.block()
+	case atom.Img:
+		alt := strings.TrimSpace(StripControl(attr(n, "alt")))
+		if alt == "" {
+			alt = "image"
+		}
+		r.text(" ")
+		r.appendSpan(span{text: "[" + alt + "]", style: r.style | italic})
+		r.footnote(attr(n, "src"))
+		r.text(" ")
+	case atom.A:
+		r.withStyle(underline, n)
+		r.footnote(attr(n, "href"))
+	case atom.B, atom.Strong:
+		r.withStyle(bold, n)
+	case atom.I, atom.Em, atom.Cite:
+		r.withStyle(italic, n)
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		r.withStyle(code, n)
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.block()
+		r.withStyle(bold, n)
+		r.block()
+	case atom.Ul, atom.Ol:
+		if len(r.lists) == 0 {
+			r.block()
+		} else {
+			r.flush()
+		}
+		r.lists = append(r.lists, list{ordered: n.DataAtom == atom.Ol})
+		r.children(n)
+		r.flush()
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.block()
+		}
+	case atom.Li:
+		r.flush()
+		bullet := "• "
+		if len(r.lists) > 0 {
+			current := &r.lists[len(r.lists)-1]
+			current.count++
+			if current.ordered {
+				bullet = fmt.Sprintf("%d. ", current.count)
+			}
+		}
+		width := utf8.RuneCountInString(bullet)
+		r.prefixes = append(r.prefixes, prefix{text: strings.Repeat(" ", width), width: width, bullet: bullet})
+		r.children(n)
+		r.flush()
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+	case atom.Blockquote:
+		r.block()
+		r.prefixes = append(r.prefixes, prefix{text: r.styled("│", code) + " ", width: 2})
+		r.children(n)
+		r.flush()
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+		r.needBlank = false
+		r.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Aside,
+		atom.Figure, atom.Figcaption, atom.Table, atom.Dl, atom.Dt, atom.Dd:
+		r.block()
+		r.children(n)
+		r.block()
+	case atom.Tr:
+		r.flush()
+		r.children(n)
+		r.flush()
+	case atom.Td, atom.Th:
+		r.text(" ")
+		r.children(n)
+		r.text(" ")
+	default:
+		r.children(n)
+	}
+}
+
+func (r *renderer) children(n *nethtml.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+func (r *renderer) withStyle(s style, n *nethtml.Node) {
+	previous := r.style
+	r.style |= s
+	r.children(n)
+	r.style = previous
+}
+
+// text adds inline text, collapsing whitespace the way a browser would.
+// A non-breaking space is kept inside its word. Control characters are
+// dropped.
+func (r *renderer) text(data string) {
+	data = StripControl(data)
+	if data == "" {
+		return
+	}
+	if isSpace([]rune(data)[0]) {
+		r.space = true
+	}
+	for _, field := range strings.FieldsFunc(data, isSpace) {
+		r.appendSpan(span{text: strings.ReplaceAll(field, "\u00a0", " "), style: r.style})
+		r.space = true
+	}
+	if last, _ := utf8.DecodeLastRuneInString(data); !isSpace(last) {
+		r.space = false
+	}
+}
+
+func isSpace(c rune) bool {
+	return c != '\u00a0' && unicode.IsSpace(c)
+}
+
+// appendSpan starts a new word after whitespace, otherwise it joins the
+// previous one so "foo<b>bar</b>" stays a single word.
+func (r *renderer) appendSpan(s span) {
+	if r.space || len(r.words) == 0 {
+		r.words = append(r.words, word{s})
+	} else {
+		r.words[len(r.words)-1] = append(r.words[len(r.words)-1], s)
+	}
+	r.space = false
+}
+
+// footnote records a link and marks it in the text with its number.
+func (r *renderer) footnote(href string) {
+	href = strings.TrimSpace(StripControl(href))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return
+	}
+	number := 0
+	for i, link := range r.links {
+		if link == href {
+			number = i + 1
+		}
+	}
+	if number == 0 {
+		r.links = append(r.links, href)
+		number = len(r.links)
+	}
+	r.appendSpan(span{text: fmt.Sprintf("[%d]", number), style: code})
+}
+
+func (r *renderer) pre(n *nethtml.Node) {
+	var b strings.Builder
+	var collect func(*nethtml.Node)
+	collect = func(n *nethtml.Node) {
+		if n.Type == nethtml.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteString("\n")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+
+	text := strings.Trim(strings.ReplaceAll(StripControl(b.String()), "\t", "    "), "\n")
+	for _, line := range strings.Split(text, "\n") {
+		r.emit(r.firstIndent() + "    " + r.styled(strings.TrimRight(line, " \r"), code))
+	}
+}
+
+// block ends the current paragraph and asks for a blank line before the next one.
+func (r *renderer) block() {
+	r.flush()
+	if !r.needBlank {
+		r.needBlank = true
+		r.blankPrefix = strings.TrimRight(r.indent(), " ")
+	}
+}
+
+// flush wraps the pending words into lines.
+func (r *renderer) flush() {
+	if len(r.words) == 0 {
+		return
+	}
+	first, rest := r.firstIndent(), r.indent()
+	width := max(r.width()-r.indentWidth(), 10)
+	var line strings.Builder
+	lineWidth := 0
+	for _, w := range r.words {
+		wordWidth := 0
+		for _, s := range w {
+			wordWidth += utf8.RuneCountInString(s.text)
+		}
+		if lineWidth > 0 && lineWidth+1+wordWidth > width {
+			r.emit(first + line.String())
+			first = rest
+			line.Reset()
+			lineWidth = 0
+		}
+		if lineWidth > 0 {
+			line.WriteString(" ")
+			lineWidth++
+		}
+		for _, s := range w {
+			line.WriteString(r.styled(s.text, s.style))
+		}
+		lineWidth += wordWidth
+	}
+	r.emit(first + line.String())
+	r.words = nil
+	r.space = false
+}
+
+func (r *renderer) emit(line string) {
+	if r.needBlank && len(r.lines) > 0 {
+		r.lines = append(r.lines, r.blankPrefix)
+	}
+	r.needBlank = false
+	r.lines = append(r.lines, line)
+}
+
+func (r *renderer) indent() string {
+	var b strings.Builder
+	for _, p := range r.prefixes {
+		b.WriteString(p.text)
+	}
+	return b.String()
+}
+
+// firstIndent is the indentation for the next line, using up any bullets
+// that haven't been shown yet.
+func (r *renderer) firstIndent() string {
+	var b strings.Builder
+	for i, p := range r.prefixes {
+		if p.bullet != "" {
+			b.WriteString(r.styled(p.bullet, bold))
+			r.prefixes[i].bullet = ""
+		} else {
+			b.WriteString(p.text)
+		}
+	}
+	return b.String()
+}
+
+func (r *renderer) indentWidth() int {
+	width := 0
+	for _, p := range r.prefixes {
+		width += p.width
+	}
+	return width
+}
+
+func (r *renderer) width() int {
+	return r.opts.Width
+}
+
+// styled wraps text in the escape codes for s when ANSI output is enabled.
+func (r *renderer) styled(text string, s style) string {
+	if !r.opts.ANSI || s == 0 || text == "" {
+		return text
+	}
+	var codes []string
+	if s&bold != 0 {
+		codes = append(codes, "1")
+	}
+	if s&italic != 0 {
+		codes = append(codes, "3")
+	}
+	if s&underline != 0 {
+		codes = append(codes, "4")
+	}
+	if s&code != 0 {
+		codes = append(codes, "36")
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
+
+func attr(n *nethtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
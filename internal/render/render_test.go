@@ -0,0 +1,256 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		width  int
+		want   []string
+	}{
+		{
+			name: "paragraphs",
+			source: `<p>First paragraph.</p><p>Second   paragraph
+			with a line break in the source.</p>`,
+			want: []string{"First paragraph.", "", "Second paragraph with a line break in the source."},
+		},
+		{
+			name:   "wrapping",
+			source: `<p>the quick brown fox jumps over the lazy dog</p>`,
+			width:  15,
+			want:   []string{"the quick brown", "fox jumps over", "the lazy dog"},
+		},
+		{
+			name:   "long words aren't split",
+			source: `<p>a supercalifragilisticexpialidocious word</p>`,
+			width:  12,
+			want:   []string{"a", "supercalifragilisticexpialidocious", "word"},
+		},
+		{
+			name:   "inline tags don't split words",
+			source: `<p>foo<b>bar</b> baz</p>`,
+			want:   []string{"foobar baz"},
+		},
+		{
+			name:   "line breaks",
+			source: `<p>one<br>two<br/>three</p>`,
+			want:   []string{"one", "two", "three"},
+		},
+		{
+			name:   "plain text",
+			source: "First paragraph\nstill first.\n\nSecond & last.",
+			want:   []string{"First paragraph still first.", "", "Second & last."},
+		},
+		{
+			name:   "entities",
+			source: `<p>Fish &amp; chips &lt;3 &mdash; caf&eacute; &#8364;5 &nbsp;x</p>`,
+			want:   []string{"Fish & chips <3 — café €5  x"},
+		},
+		{
+			name:   "non-breaking spaces hold words together",
+			source: `<p>it is 10&nbsp;km away</p>`,
+			width:  10,
+			want:   []string{"it is", "10 km away"},
+		},
+		{
+			name:   "footnotes are numbered in order",
+			source: `<p>See <a href="https://a.example">one</a> and <a href="https://b.example">two</a>.</p>`,
+			want:   []string{"See one[1] and two[2].", "", "[1] https://a.example", "[2] https://b.example"},
+		},
+		{
+			name:   "repeated links share a footnote",
+			source: `<p><a href="https://a.example">a</a> <a href="https://b.example">b</a> <a href="https://a.example">again</a></p>`,
+			want:   []string{"a[1] b[2] again[1]", "", "[1] https://a.example", "[2] https://b.example"},
+		},
+		{
+			name:   "anchors and scripts get no footnote",
+			source: `<p><a href="#top">top</a> <a href="javascript:void(0)">js</a> <a>none</a></p>`,
+			want:   []string{"top js none"},
+		},
+		{
+			name:   "images",
+			source: `<p>Look: <img src="https://a.example/cat.png" alt="a cat"> <img src="https://a.example/x.png"></p>`,
+			want:   []string{"Look: [a cat][1] [image][2]", "", "[1] https://a.example/cat.png", "[2] https://a.example/x.png"},
+		},
+		{
+			name:   "unordered list",
+			source: `<p>Before</p><ul><li>one</li><li>two</li></ul><p>After</p>`,
+			want:   []string{"Before", "", "• one", "• two", "", "After"},
+		},
+		{
+			name:   "ordered list",
+			source: `<ol><li>first</li><li>second</li><li>third</li></ol>`,
+			want:   []string{"1. first", "2. second", "3. third"},
+		},
+		{
+			name:   "nested lists",
+			source: `<ul><li>outer<ol><li>inner</li><li>inner two</li></ol></li><li>last</li></ul>`,
+			want:   []string{"• outer", "  1. inner", "  2. inner two", "• last"},
+		},
+		{
+			name:   "list items wrap under their text",
+			source: `<ol><li>one two three four five six</li></ol>`,
+			width:  16,
+			want:   []string{"1. one two three", "   four five six"},
+		},
+		{
+			name:   "blockquote",
+			source: `<blockquote><p>Quoted text.</p><p>More.</p></blockquote><p>After</p>`,
+			want:   []string{"│ Quoted text.", "│", "│ More.", "", "After"},
+		},
+		{
+			name:   "pre keeps its layout",
+			source: "<p>Code:</p><pre><code>func main() {\n\tfmt.Println(\"hi\")   \n}\n</code></pre><p>Done</p>",
+			want:   []string{"Code:", "", "    func main() {", "        fmt.Println(\"hi\")", "    }", "", "Done"},
+		},
+		{
+			name:   "pre isn't wrapped",
+			source: "<pre>a very long line that goes past the width</pre>",
+			width:  10,
+			want:   []string{"    a very long line that goes past the width"},
+		},
+		{
+			name:   "pre with markup inside",
+			source: "<pre><b>bold</b> &amp; <i>italic</i><br>next</pre>",
+			want:   []string{"    bold & italic", "    next"},
+		},
+		{
+			name:   "inline code",
+			source: `<p>Run <code>go test ./...</code> first.</p>`,
+			want:   []string{"Run go test ./... first."},
+		},
+		{
+			name:   "headings",
+			source: `<h1>Title</h1><p>Body</p><h2>Section</h2>`,
+			want:   []string{"Title", "", "Body", "", "Section"},
+		},
+		{
+			name:   "horizontal rule",
+			source: `<p>a</p><hr><p>b</p>`,
+			width:  5,
+			want:   []string{"a", "", "─────", "", "b"},
+		},
+		{
+			name:   "hidden elements",
+			source: `<p>a</p><script>alert(1)</script><style>p{}</style><noscript>enable js</noscript><p>b</p>`,
+			want:   []string{"a", "", "b"},
+		},
+		{
+			name:   "table rows",
+			source: `<table><tr><th>Name</th><th>Age</th></tr><tr><td>Ann</td><td>30</td></tr></table>`,
+			want:   []string{"Name Age", "Ann 30"},
+		},
+		{
+			name:   "escape sequences are dropped",
+			source: "<p>a\x1b]0;pwn\x07b &#27;[2Jc\u009b31m</p><pre>\x1b[2Jx</pre><p><a href=\"https://a.example/\x1b]8;;evil\x07\">d</a> <img src=\"x.png\" alt=\"\x1b[8mcat\"></p>",
+			want:   []string{"a]0;pwnb [2Jc31m", "", "    [2Jx", "", "d[1] [[8mcat][2]", "", "[1] https://a.example/]8;;evil", "[2] x.png"},
+		},
+		{
+			name:   "escape sequences in plain text",
+			source: "title\x1b]0;pwn\x07\x1b[2J",
+			want:   []string{"title]0;pwn[2J"},
+		},
+		{
+			name:   "empty",
+			source: "",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.source, Options{Width: tt.width})
+			want := strings.Join(tt.want, "\n")
+			if got != want {
+				t.Errorf("HTML(%q)\ngot:\n%s\nwant:\n%s", tt.source, got, want)
+			}
+		})
+	}
+}
+
+func TestStripControl(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain title", "plain title"},
+		{"\x1b]0;pwn\x07title", "]0;pwntitle"},
+		{"\x1b[2Jcleared", "[2Jcleared"},
+		{"c1\u009b2J", "c12J"},
+		{"lines\nand\ttabs\r\x00\x7f", "lines\nand\ttabs"},
+		{"café — ok", "café — ok"},
+	}
+	for _, tt := range tests {
+		if got := StripControl(tt.text); got != tt.want {
+			t.Errorf("StripControl(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLDefaultWidth(t *testing.T) {
+	got := HTML("<p>"+strings.Repeat("word ", 30)+"</p>", Options{})
+	for _, line := range strings.Split(got, "\n") {
+		if len(line) > defaultWidth {
+			t.Errorf("line is %d columns, want at most %d: %q", len(line), defaultWidth, line)
+		}
+	}
+	if lines := strings.Count(got, "\n") + 1; lines != 2 {
+		t.Errorf("got %d lines, want 2:\n%s", lines, got)
+	}
+}
+
+func TestHTMLANSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		width  int
+		want   []string
+	}{
+		{
+			name:   "inline styles",
+			source: `<p><b>bold</b> <i>italic</i> <code>code</code> <b><i>both</i></b></p>`,
+			want:   []string{"\x1b[1mbold\x1b[0m \x1b[3mitalic\x1b[0m \x1b[36mcode\x1b[0m \x1b[1;3mboth\x1b[0m"},
+		},
+		{
+			name:   "links and footnotes",
+			source: `<p><a href="https://a.example">link</a></p>`,
+			want:   []string{"\x1b[4mlink\x1b[0m\x1b[36m[1]\x1b[0m", "", "\x1b[36m[1]\x1b[0m https://a.example"},
+		},
+		{
+			name:   "headings and bullets",
+			source: `<h2>Title</h2><ol><li>one</li></ol>`,
+			want:   []string{"\x1b[1mTitle\x1b[0m", "", "\x1b[1m1. \x1b[0mone"},
+		},
+		{
+			name:   "escape codes don't count towards the width",
+			source: `<p><b>aaaa</b> <b>bbbb</b> <b>cccc</b></p>`,
+			width:  10,
+			want:   []string{"\x1b[1maaaa\x1b[0m \x1b[1mbbbb\x1b[0m", "\x1b[1mcccc\x1b[0m"},
+		},
+		{
+			name:   "pre",
+			source: "<pre>x := 1</pre>",
+			want:   []string{"    \x1b[36mx := 1\x1b[0m"},
+		},
+		{
+			name:   "blockquote bar",
+			source: "<blockquote>quote</blockquote>",
+			want:   []string{"\x1b[36m│\x1b[0m quote"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.source, Options{Width: tt.width, ANSI: true})
+			want := strings.Join(tt.want, "\n")
+			if got != want {
+				t.Errorf("HTML(%q)\ngot:  %q\nwant: %q", tt.source, got, want)
+			}
+			if plain := HTML(tt.source, Options{Width: tt.width}); strings.Contains(plain, "\x1b[") {
+				t.Errorf("without ANSI, HTML(%q) = %q, which has escape codes", tt.source, plain)
+			}
+		})
+	}
+}
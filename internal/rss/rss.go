@@ -10,19 +10,36 @@ import (
 )
 
 type RSSFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
 	Channel struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
+		Language    string    `xml:"language,omitempty"`
+		Generator   string    `xml:"generator,omitempty"`
+		Image       *RSSImage `xml:"image,omitempty"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
+// RSSImage is the channel's logo.
+type RSSImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title,omitempty"`
+	Link  string `xml:"link,omitempty"`
+}
+
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	GUID        string   `xml:"guid,omitempty"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded,omitempty"`
+	Author      string   `xml:"author,omitempty"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Categories  []string `xml:"category,omitempty"`
 }
 
 func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
@@ -51,6 +68,9 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i := range feed.Channel.Item {
 		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
 		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+		if feed.Channel.Item[i].Author == "" {
+			feed.Channel.Item[i].Author = feed.Channel.Item[i].Creator
+		}
 	}
 	return &feed, nil
 }
@@ -0,0 +1,139 @@
+package rss
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Channel describes a feed generated by gator.
+type Channel struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Updated     time.Time
+}
+
+// Entry is a post in a feed generated by gator.
+type Entry struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	Author      string
+	Categories  []string
+	Published   time.Time
+}
+
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  AtomPerson  `xml:"author"`
+	Links   []AtomLink  `xml:"link"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []AtomLink     `xml:"link"`
+	Author     *AtomPerson    `xml:"author,omitempty"`
+	Summary    *AtomText      `xml:"summary,omitempty"`
+	Content    *AtomText      `xml:"content,omitempty"`
+	Categories []AtomCategory `xml:"category,omitempty"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// WriteRSS writes the channel and entries as an RSS 2.0 document.
+func WriteRSS(w io.Writer, channel Channel, entries []Entry) error {
+	feed := RSSFeed{Version: "2.0"}
+	feed.Channel.Title = channel.Title
+	feed.Channel.Link = channel.Link
+	feed.Channel.Description = channel.Description
+	feed.Channel.Generator = "gator"
+	for _, entry := range entries {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Description,
+			PubDate:     entry.Published.Format(time.RFC1123Z),
+			GUID:        entry.ID,
+			Content:     entry.Content,
+			Creator:     entry.Author,
+			Categories:  entry.Categories,
+		})
+	}
+	return writeXML(w, feed)
+}
+
+// WriteAtom writes the channel and entries as an Atom 1.0 document.
+func WriteAtom(w io.Writer, channel Channel, entries []Entry) error {
+	feed := AtomFeed{
+		ID:      channel.ID,
+		Title:   channel.Title,
+		Updated: channel.Updated.Format(time.RFC3339),
+		Author:  AtomPerson{Name: "gator"},
+	}
+	if channel.Link != "" {
+		feed.Links = append(feed.Links, AtomLink{Href: channel.Link})
+	}
+	for _, entry := range entries {
+		atomEntry := AtomEntry{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			Updated:   entry.Published.Format(time.RFC3339),
+			Published: entry.Published.Format(time.RFC3339),
+			Links:     []AtomLink{{Href: entry.Link, Rel: "alternate"}},
+		}
+		if entry.Author != "" {
+			atomEntry.Author = &AtomPerson{Name: entry.Author}
+		}
+		if entry.Description != "" {
+			atomEntry.Summary = &AtomText{Type: "html", Body: entry.Description}
+		}
+		if entry.Content != "" {
+			atomEntry.Content = &AtomText{Type: "html", Body: entry.Content}
+		}
+		for _, category := range entry.Categories {
+			atomEntry.Categories = append(atomEntry.Categories, AtomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, atomEntry)
+	}
+	return writeXML(w, feed)
+}
+
+func writeXML(w io.Writer, document any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
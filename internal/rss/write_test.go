@@ -0,0 +1,145 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testPublished = time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+
+	testChannel = Channel{
+		ID:          "urn:uuid:0b0d8c0e-6a43-5b77-9d6e-0f6cbb1b1f6a",
+		Title:       "susan's timeline",
+		Link:        "https://gator.example/",
+		Description: "Posts collected by gator for susan",
+		Updated:     testPublished,
+	}
+
+	testEntries = []Entry{
+		{
+			ID:          "https://example.org/go-1-30",
+			Title:       "Go 1.30 & more",
+			Link:        "https://example.org/go-1-30",
+			Description: "<p>What's new in <b>Go</b></p>",
+			Content:     "<p>Everything that changed.</p>",
+			Author:      "Gopher",
+			Categories:  []string{"go", "releases"},
+			Published:   testPublished,
+		},
+		{
+			ID:        "https://example.org/notes",
+			Title:     "Notes",
+			Link:      "https://example.org/notes",
+			Published: testPublished.Add(-24 * time.Hour),
+		},
+	}
+)
+
+func TestWriteRSS(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteRSS(&b, testChannel, testEntries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), xml.Header+"<rss version=\"2.0\">") {
+		t.Errorf("the document should start with the XML header and rss element:\n%s", b.String())
+	}
+	var feed RSSFeed
+	if err := xml.Unmarshal(b.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	channel := feed.Channel
+	if channel.Title != testChannel.Title || channel.Link != testChannel.Link || channel.Description != testChannel.Description || channel.Generator != "gator" {
+		t.Errorf("unexpected channel %+v", channel)
+	}
+	if len(channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Item))
+	}
+	item := channel.Item[0]
+	want := RSSItem{
+		Title:       "Go 1.30 & more",
+		Link:        "https://example.org/go-1-30",
+		Description: "<p>What's new in <b>Go</b></p>",
+		PubDate:     "Mon, 02 Jun 2025 10:00:00 +0000",
+		GUID:        "https://example.org/go-1-30",
+		Content:     "<p>Everything that changed.</p>",
+		Creator:     "Gopher",
+		Categories:  []string{"go", "releases"},
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("got item\n%+v\nwant\n%+v", item, want)
+	}
+	if _, err := time.Parse(time.RFC1123Z, channel.Item[1].PubDate); err != nil {
+		t.Errorf("pubDate should be RFC 1123: %v", err)
+	}
+	// Only the first entry has content, an author and categories.
+	for element, want := range map[string]int{"/rss/1.0/modules/content/": 1, "/dc/elements/1.1/": 1, "<category>": 2} {
+		if got := strings.Count(b.String(), element); got != want {
+			t.Errorf("%v appears %d times, want %d:\n%s", element, got, want, b.String())
+		}
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteAtom(&b, testChannel, testEntries); err != nil {
+		t.Fatal(err)
+	}
+	var feed AtomFeed
+	if err := xml.Unmarshal(b.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.ID != testChannel.ID || feed.Title != testChannel.Title || feed.Updated != "2025-06-02T10:00:00Z" || feed.Author.Name != "gator" {
+		t.Errorf("unexpected feed %+v", feed)
+	}
+	if len(feed.Links) != 1 || feed.Links[0].Href != testChannel.Link {
+		t.Errorf("feed links = %+v, want %v", feed.Links, testChannel.Link)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.ID != "https://example.org/go-1-30" || entry.Title != "Go 1.30 & more" || entry.Published != "2025-06-02T10:00:00Z" || entry.Updated != entry.Published {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if len(entry.Links) != 1 || entry.Links[0] != (AtomLink{Href: "https://example.org/go-1-30", Rel: "alternate"}) {
+		t.Errorf("entry links = %+v", entry.Links)
+	}
+	if entry.Author == nil || entry.Author.Name != "Gopher" {
+		t.Errorf("entry author = %+v", entry.Author)
+	}
+	if entry.Summary == nil || *entry.Summary != (AtomText{Type: "html", Body: "<p>What's new in <b>Go</b></p>"}) {
+		t.Errorf("entry summary = %+v", entry.Summary)
+	}
+	if entry.Content == nil || *entry.Content != (AtomText{Type: "html", Body: "<p>Everything that changed.</p>"}) {
+		t.Errorf("entry content = %+v", entry.Content)
+	}
+	if len(entry.Categories) != 2 || entry.Categories[1].Term != "releases" {
+		t.Errorf("entry categories = %+v", entry.Categories)
+	}
+
+	bare := feed.Entries[1]
+	if bare.Author != nil || bare.Summary != nil || bare.Content != nil || len(bare.Categories) != 0 {
+		t.Errorf("an entry without an author, summary, content or categories shouldn't get empty ones: %+v", bare)
+	}
+}
+
+func TestWriteAtomWithoutLink(t *testing.T) {
+	channel := testChannel
+	channel.Link = ""
+	var b bytes.Buffer
+	if err := WriteAtom(&b, channel, nil); err != nil {
+		t.Fatal(err)
+	}
+	var feed AtomFeed
+	if err := xml.Unmarshal(b.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Links) != 0 || len(feed.Entries) != 0 {
+		t.Errorf("an empty feed without a link should have neither: %+v", feed)
+	}
+}
@@ -0,0 +1,204 @@
+// Package sanitize cleans feed HTML so it can be shown in the web UI. Only
+// a small set of formatting tags and attributes is kept, scripts, styles and
+// embeds are dropped with their contents, and links and images must point
+// at http, https or mailto URLs.
+package sanitize
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowed maps each kept tag to the attributes it may keep.
+var allowed = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          nil,
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// dropped tags are removed along with everything inside them.
+var dropped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Form:     true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Head:     true,
+	atom.Title:    true,
+}
+
+var voidTags = map[atom.Atom]bool{atom.Br: true, atom.Embed: true, atom.Hr: true, atom.Img: true}
+
+// HTML returns a safe copy of source. Relative links and image sources are
+// resolved against base, which may be nil. Text without any markup is split
+// into paragraphs at blank lines.
+func HTML(source string, base *url.URL) string {
+	if !strings.Contains(source, "<") {
+		return plainToHTML(source)
+	}
+	var b strings.Builder
+	z := nethtml.NewTokenizer(strings.NewReader(source))
+	skipping := 0
+	open := []atom.Atom{}
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case nethtml.TextToken:
+			if skipping == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if dropped[tok.DataAtom] {
+				if tt == nethtml.StartTagToken && !voidTags[tok.DataAtom] {
+					skipping++
+				}
+				continue
+			}
+			attrs, ok := allowed[tok.DataAtom]
+			if !ok || skipping > 0 {
+				continue
+			}
+			b.WriteString("<" + tok.DataAtom.String())
+			for _, attr := range tok.Attr {
+				if !slices.Contains(attrs, attr.Key) {
+					continue
+				}
+				value := attr.Val
+				if attr.Key == "href" || attr.Key == "src" {
+					safe, ok := safeURL(value, base)
+					if !ok {
+						continue
+					}
+					value = safe
+				}
+				b.WriteString(" " + attr.Key + `="` + html.EscapeString(value) + `"`)
+			}
+			if tok.DataAtom == atom.A {
+				b.WriteString(` rel="nofollow noopener noreferrer" target="_blank"`)
+			}
+			if tok.DataAtom == atom.Img {
+				b.WriteString(` loading="lazy"`)
+			}
+			b.WriteString(">")
+			if !voidTags[tok.DataAtom] && tt == nethtml.StartTagToken {
+				open = append(open, tok.DataAtom)
+			}
+		case nethtml.EndTagToken:
+			if voidTags[tok.DataAtom] {
+				continue
+			}
+			if dropped[tok.DataAtom] {
+				if skipping > 0 {
+					skipping--
+				}
+				continue
+			}
+			if skipping > 0 {
+				continue
+			}
+			// Close the tag only if it's open, closing anything left open
+			// inside it first, so the output is always balanced.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tok.DataAtom {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j].String() + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i].String() + ">")
+	}
+	return b.String()
+}
+
+func safeURL(raw string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return u.String(), true
+	case "":
+		// A relative URL with nothing to resolve it against stays relative
+		// to the page, which is harmless.
+		return u.String(), !strings.HasPrefix(u.String(), "//")
+	}
+	return "", false
+}
+
+func plainToHTML(source string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>") + "</p>")
+	}
+	return b.String()
+}
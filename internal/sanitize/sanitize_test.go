@@ -0,0 +1,111 @@
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"formatting is kept", `<p>Hello <b>bold</b> <em>world</em></p>`, `<p>Hello <b>bold</b> <em>world</em></p>`},
+		{"unknown tags are unwrapped", `<article><p>Text</p><custom-tag>more</custom-tag></article>`, `<p>Text</p>more`},
+		{"links get rel and target", `<a href="https://example.com/a?b=1&amp;c=2" title="A">link</a>`, `<a href="https://example.com/a?b=1&amp;c=2" title="A" rel="nofollow noopener noreferrer" target="_blank">link</a>`},
+		{"images load lazily", `<img src="https://example.com/a.png" alt="A" width="10">`, `<img src="https://example.com/a.png" alt="A" width="10" loading="lazy">`},
+		{"mailto links", `<a href="mailto:me@example.com">mail</a>`, `<a href="mailto:me@example.com" rel="nofollow noopener noreferrer" target="_blank">mail</a>`},
+		{"upper case schemes", `<a href="HTTPS://example.com/">x</a>`, `<a href="https://example.com/" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"relative links stay relative", `<a href="/about">x</a>`, `<a href="/about" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+
+		// Dangerous URL schemes, however they are written.
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"mixed case javascript", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"javascript after spaces", `<a href="  javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"entity encoded scheme", `<a href="&#106;&#97;vascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"hex entity encoded scheme", `<a href="&#x6A;avascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"entity encoded colon", `<a href="javascript&colon;alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"tab inside the scheme", `<a href="java&#x09;script:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"newline inside the scheme", "<a href=\"java\nscript:alert(1)\">x</a>", `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"vbscript href", `<a href="vbscript:msgbox(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"data href", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"data image", `<img src="data:image/svg+xml;base64,PHN2Zz48L3N2Zz4=">`, `<img loading="lazy">`},
+		{"javascript image", `<img src="javascript:alert(1)">`, `<img loading="lazy">`},
+		{"protocol relative without a base", `<a href="//evil.example/">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+
+		// Attributes that can run code or load things are dropped.
+		{"event handlers", `<p onclick="alert(1)" onmouseover=alert(1)>x</p>`, `<p>x</p>`},
+		{"image error handler", `<img src=x onerror=alert(1)>`, `<img src="x" loading="lazy">`},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">x</p>`, `<p>x</p>`},
+		{"srcset", `<img src="https://example.com/a.png" srcset="javascript:alert(1) 2x">`, `<img src="https://example.com/a.png" loading="lazy">`},
+		{"id and class", `<div id="login" class="modal">x</div>`, `<div>x</div>`},
+
+		// Dropped elements go with everything inside them.
+		{"script", `<p>a</p><script>alert(1)</script><p>b</p>`, `<p>a</p><p>b</p>`},
+		{"style element", `<style>body{display:none}</style>x`, `x`},
+		{"svg", `<svg onload="alert(1)"><script>alert(1)</script><a href="https://example.com">in svg</a></svg>after`, `after`},
+		{"math", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>after`, `after`},
+		{"nested dropped elements", `<svg><svg></svg>inside</svg>after`, `after`},
+		{"iframe, object and embed", `<iframe src="https://evil.example"></iframe><object data="x"></object><embed src="x">ok`, `ok`},
+		{"form", `<form action="https://evil.example"><input name="password"></form>ok`, `ok`},
+		{"self closing svg", `<svg/>ok`, `ok`},
+
+		// Broken markup.
+		{"unclosed tags are closed", `<p><b>bold <i>both`, `<p><b>bold <i>both</i></b></p>`},
+		{"misnested tags", `<b><i>x</b>y</i>`, `<b><i>x</i></b>y`},
+		{"stray end tags", `</p>text</div>`, `text`},
+		{"unclosed script", `before<script>alert(1)`, `before`},
+		{"unclosed comment", `before<!-- <script>alert(1)</script>`, `before`},
+		{"split script tag", `<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{"stray embed end tag", `<svg></embed><a href="https://example.com">x</a></svg>ok`, `ok`},
+		{"text that looks like markup", `1 < 2 && 3 > 2`, `1 &lt; 2 &amp;&amp; 3 &gt; 2`},
+
+		// Quotes in attribute values can't break out of the attribute.
+		{"double quote in a title", `<a title='x" onclick="alert(1)'>x</a>`, `<a title="x&#34; onclick=&#34;alert(1)" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"quote in a URL", `<a href='https://example.com/"onmouseover="alert(1)'>x</a>`, `<a href="https://example.com/%22onmouseover=%22alert%281%29" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{"angle brackets in alt", `<img alt="<script>alert(1)</script>">`, `<img alt="&lt;script&gt;alert(1)&lt;/script&gt;" loading="lazy">`},
+		{"unquoted attribute with a quote", `<abbr title=a"b>x</abbr>`, `<abbr title="a&#34;b">x</abbr>`},
+
+		// Plain text.
+		{"paragraphs", "First line\nsecond line\n\nNext paragraph", "<p>First line<br>second line</p><p>Next paragraph</p>"},
+		{"plain text is escaped", "Fish & chips", "<p>Fish &amp; chips</p>"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.source, nil)
+			if got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+			lower := strings.ToLower(got)
+			for _, bad := range []string{"<script", "javascript:", "onerror=", "onclick=\"", "<svg", "<iframe", "style="} {
+				if strings.Contains(lower, bad) {
+					t.Errorf("HTML(%q) = %q, which contains %q", tt.source, got, bad)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLResolvesAgainstBase(t *testing.T) {
+	base, err := url.Parse("https://blog.example/posts/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`<img src="cat.png">`, `<img src="https://blog.example/posts/cat.png" loading="lazy">`},
+		{`<a href="/about">x</a>`, `<a href="https://blog.example/about" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{`<a href="//cdn.example/x">x</a>`, `<a href="https://cdn.example/x" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+	}
+	for _, tt := range tests {
+		if got := HTML(tt.source, base); got != tt.want {
+			t.Errorf("HTML(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+	"github.com/curtisbraxdale/blog-gator/internal/filter"
+	"github.com/curtisbraxdale/blog-gator/internal/render"
+	"github.com/google/uuid"
+)
+
+const postLimit = 500
+
+type pane int
+
+const (
+	feedsPane pane = iota
+	postsPane
+	bodyPane
+)
+
+// feedItem is an entry in the feeds pane: everything, a saved search, a
+// category, or a feed.
+type feedItem struct {
+	name     string
+	url      string
+	category string
+	search   *expr.Program
+	indent   bool
+	unread   int64
+}
+
+type model struct {
+	db      database.Querier
+	user    database.User
+	refresh time.Duration
+	// searchCounts is shared by every copy of the model.
+	searchCounts *filter.UnreadCache
+	// postsRequested counts the post loads started, and is shared too, so
+	// posts from a load that was overtaken by a newer one are dropped.
+	postsRequested *int
+
+	width  int
+	height int
+	focus  pane
+
+	feeds      []feedItem
+	feedCursor int
+	posts      []database.BrowsePostsForUserRow
+	postCursor int
+	bodyScroll int
+	unreadOnly bool
+
+	status string
+}
+
+type feedsLoadedMsg []feedItem
+
+// postsLoadedMsg carries the posts for the request-th call to loadPosts.
+type postsLoadedMsg struct {
+	request int
+	posts   []database.BrowsePostsForUserRow
+}
+
+type tickMsg time.Time
+
+type stateChangedMsg struct{}
+
+type errMsg struct{ err error }
+
+var (
+	borderStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	focusedStyle  = borderStyle.BorderForeground(lipgloss.Color("63"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+)
+
+// Run starts the reader for the given user and blocks until it exits. New
+// posts written by the aggregator are picked up every refresh interval.
+func Run(db database.Querier, user database.User, refresh time.Duration) error {
+	m := newModel(db, user, refresh)
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func newModel(db database.Querier, user database.User, refresh time.Duration) model {
+	return model{db: db, user: user, refresh: refresh, searchCounts: &filter.UnreadCache{}, postsRequested: new(int)}
+}
+
+func (m model) Init() tea.Cmd {
+	return tea.Batch(m.loadFeeds(), m.loadPosts(), m.tick())
+}
+
+func (m model) tick() tea.Cmd {
+	return tea.Tick(m.refresh, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+func (m model) loadFeeds() tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		follows, err := m.db.GetFeedFollowsForUser(ctx, m.user.ID)
+		if err != nil {
+			return errMsg{err}
+		}
+		counts, err := m.db.GetUnreadCountsForUser(ctx, m.user.ID)
+		if err != nil {
+			return errMsg{err}
+		}
+		unread := make(map[uuid.UUID]int64)
+		var total int64
+		for _, count := range counts {
+			unread[count.FeedID] = count.Unread
+			total += count.Unread
+		}
+		feeds := []feedItem{{name: "All feeds", unread: total}}
+
+		searches, err := m.db.GetSavedSearchesForUser(ctx, m.user.ID)
+		if err != nil {
+			return errMsg{err}
+		}
+		programs := []*expr.Program{}
+		for _, search := range searches {
+			program, err := expr.Compile(search.Query)
+			if err != nil {
+				continue
+			}
+			programs = append(programs, program)
+			feeds = append(feeds, feedItem{name: search.Name, search: program})
+		}
+		searchUnread, err := m.searchCounts.Counts(ctx, m.db, m.user.ID, programs, counts, follows)
+		if err != nil {
+			return errMsg{err}
+		}
+		for i := range programs {
+			feeds[i+1].unread = searchUnread[i]
+		}
+
+		categoryIndex := -1
+		for _, follow := range follows {
+			if follow.CategoryName.Valid && (categoryIndex < 0 || feeds[categoryIndex].category != follow.CategoryName.String) {
+				feeds = append(feeds, feedItem{name: follow.CategoryName.String, category: follow.CategoryName.String})
+				categoryIndex = len(feeds) - 1
+			}
+			feed := feedItem{name: render.StripControl(follow.FeedName), url: follow.FeedUrl, unread: unread[follow.FeedID]}
+			if follow.CategoryName.Valid {
+				feed.indent = true
+				feeds[categoryIndex].unread += feed.unread
+			}
+			feeds = append(feeds, feed)
+		}
+		return feedsLoadedMsg(feeds)
+	}
+}
+
+func (m model) loadPosts() tea.Cmd {
+	params := database.BrowsePostsForUserParams{UserID: m.user.ID, UnreadOnly: m.unreadOnly, PostLimit: postLimit}
+	if feed := m.selectedFeed(); feed.url != "" {
+		params.Feed.String = feed.url
+		params.Feed.Valid = true
+	} else if feed.category != "" {
+		params.Category.String = feed.category
+		params.Category.Valid = true
+	}
+	search := m.selectedFeed().search
+	*m.postsRequested++
+	request := *m.postsRequested
+	return func() tea.Msg {
+		var posts []database.BrowsePostsForUserRow
+		var err error
+		if search != nil {
+			posts, err = searchPosts(m.db, params, search)
+		} else {
+			posts, err = m.db.BrowsePostsForUser(context.Background(), params)
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return postsLoadedMsg{request: request, posts: posts}
+	}
+}
+
+// searchPosts pages through the user's posts collecting the ones that match
+// a saved search, until there are postLimit of them.
+func searchPosts(db database.Querier, params database.BrowsePostsForUserParams, search *expr.Program) ([]database.BrowsePostsForUserRow, error) {
+	matches := []database.BrowsePostsForUserRow{}
+	for {
+		rows, err := db.BrowsePostsForUser(context.Background(), params)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if search.Match(filter.FromBrowseRow(row).Env()) {
+				matches = append(matches, row)
+			}
+		}
+		if len(matches) >= postLimit || !filter.NextPage(&params, rows) {
+			return matches[:min(len(matches), postLimit)], nil
+		}
+	}
+}
+
+func (m model) selectedFeed() feedItem {
+	if m.feedCursor < len(m.feeds) {
+		return m.feeds[m.feedCursor]
+	}
+	return feedItem{}
+}
+
+func (m model) selectedPost() (database.BrowsePostsForUserRow, bool) {
+	if m.postCursor < len(m.posts) {
+		return m.posts[m.postCursor], true
+	}
+	return database.BrowsePostsForUserRow{}, false
+}
+
+// setRead marks the post read or unread; the panes reload once it's saved.
+func (m model) setRead(post database.BrowsePostsForUserRow, read bool) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if read {
+			err = m.db.MarkPostRead(context.Background(), database.MarkPostReadParams{UserID: m.user.ID, PostID: post.ID})
+		} else {
+			err = m.db.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{UserID: m.user.ID, PostID: post.ID})
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return stateChangedMsg{}
+	}
+}
+
+func (m model) setStarred(post database.BrowsePostsForUserRow, starred bool) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if starred {
+			err = m.db.StarPost(context.Background(), database.StarPostParams{UserID: m.user.ID, PostID: post.ID})
+		} else {
+			err = m.db.UnstarPost(context.Background(), database.UnstarPostParams{UserID: m.user.ID, PostID: post.ID})
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return stateChangedMsg{}
+	}
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case feedsLoadedMsg:
+		m.feeds = msg
+		m.feedCursor = clamp(m.feedCursor, len(m.feeds))
+	case postsLoadedMsg:
+		if msg.request != *m.postsRequested {
+			break
+		}
+		selected, ok := m.selectedPost()
+		m.posts = msg.posts
+		m.postCursor = clamp(m.postCursor, len(m.posts))
+		for i, post := range m.posts {
+			if ok && post.ID == selected.ID {
+				m.postCursor = i
+			}
+		}
+		m.status = ""
+	case stateChangedMsg:
+		// Starring doesn't change the unread counts the cache watches.
+		m.searchCounts.Invalidate()
+		return m, tea.Batch(m.loadFeeds(), m.loadPosts())
+	case tickMsg:
+		return m, tea.Batch(m.loadFeeds(), m.loadPosts(), m.tick())
+	case errMsg:
+		m.status = "Error: " + msg.err.Error()
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab", "l", "right":
+		m.focus = min(m.focus+1, bodyPane)
+	case "shift+tab", "h", "left":
+		m.focus = max(m.focus-1, feedsPane)
+	case "j", "down":
+		return m.move(1)
+	case "k", "up":
+		return m.move(-1)
+	case "ctrl+d":
+		return m.move(m.paneHeight() / 2)
+	case "ctrl+u":
+		return m.move(-m.paneHeight() / 2)
+	case "g":
+		return m.move(-postLimit)
+	case "G":
+		return m.move(postLimit)
+	case "enter":
+		switch m.focus {
+		case feedsPane:
+			m.focus = postsPane
+		case postsPane:
+			m.focus = bodyPane
+			m.bodyScroll = 0
+			if post, ok := m.selectedPost(); ok && !post.ReadAt.Valid {
+				return m, m.setRead(post, true)
+			}
+		}
+	case "m":
+		if post, ok := m.selectedPost(); ok {
+			return m, m.setRead(post, !post.ReadAt.Valid)
+		}
+	case "s":
+		if post, ok := m.selectedPost(); ok {
+			return m, m.setStarred(post, !post.StarredAt.Valid)
+		}
+	case "u":
+		m.unreadOnly = !m.unreadOnly
+		m.postCursor = 0
+		return m, m.loadPosts()
+	case "r":
+		m.status = "Refreshing..."
+		return m, tea.Batch(m.loadFeeds(), m.loadPosts())
+	}
+	return m, nil
+}
+
+// move shifts the cursor (or the scroll position of the body) of the focused pane.
+func (m model) move(delta int) (tea.Model, tea.Cmd) {
+	switch m.focus {
+	case feedsPane:
+		previous := m.feedCursor
+		m.feedCursor = clamp(m.feedCursor+delta, len(m.feeds))
+		if m.feedCursor != previous {
+			m.postCursor = 0
+			return m, m.loadPosts()
+		}
+	case postsPane:
+		previous := m.postCursor
+		m.postCursor = clamp(m.postCursor+delta, len(m.posts))
+		if m.postCursor != previous {
+			m.bodyScroll = 0
+		}
+	case bodyPane:
+		m.bodyScroll = max(m.bodyScroll+delta, 0)
+	}
+	return m, nil
+}
+
+func (m model) paneHeight() int {
+	return max(m.height-3, 1)
+}
+
+func (m model) View() string {
+	if m.width == 0 {
+		return "Loading..."
+	}
+	feedsWidth := max(m.width/5, 20)
+	postsWidth := (m.width - feedsWidth) * 2 / 5
+	bodyWidth := m.width - feedsWidth - postsWidth
+	height := m.paneHeight()
+
+	var feedLines []string
+	for _, feed := range m.feeds {
+		line := feed.name
+		if feed.unread > 0 {
+			line = fmt.Sprintf("%s (%d)", feed.name, feed.unread)
+		}
+		if feed.indent {
+			line = "  " + line
+		} else if feed.category != "" {
+			line = "▸ " + line
+		} else if feed.search != nil {
+			line = "◆ " + line
+		}
+		feedLines = append(feedLines, line)
+	}
+
+	var postLines []string
+	for _, post := range m.posts {
+		marker := " "
+		if !post.ReadAt.Valid {
+			marker = "●"
+		}
+		if post.StarredAt.Valid {
+			marker = "★"
+		}
+		postLines = append(postLines, marker+" "+render.StripControl(post.Title))
+	}
+	if len(m.posts) == 0 {
+		postLines = append(postLines, dimStyle.Render("No posts"))
+	}
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.renderPane(feedsPane, listView(feedLines, m.feedCursor, feedsWidth-2, height, m.focus == feedsPane), feedsWidth, height),
+		m.renderPane(postsPane, listView(postLines, m.postCursor, postsWidth-2, height, m.focus == postsPane), postsWidth, height),
+		m.renderPane(bodyPane, m.bodyView(bodyWidth-2, height), bodyWidth, height),
+	)
+	return panes + "\n" + m.footer()
+}
+
+func (m model) renderPane(p pane, content string, width, height int) string {
+	style := borderStyle
+	if m.focus == p {
+		style = focusedStyle
+	}
+	return style.Width(width - 2).Height(height).MaxHeight(height + 2).Render(content)
+}
+
+func (m model) bodyView(width, height int) string {
+	post, ok := m.selectedPost()
+	if !ok {
+		return ""
+	}
+	lines := []string{}
+	for _, line := range wrap(render.StripControl(post.Title), width) {
+		lines = append(lines, titleStyle.Render(line))
+	}
+	lines = append(lines, dimStyle.Render(truncate(render.StripControl(post.FeedName)+" · "+post.PublishedAt.Time.Format("2006-01-02 15:04"), width)))
+	lines = append(lines, dimStyle.Render(truncate(render.StripControl(post.Url), width)), "")
+	lines = append(lines, strings.Split(render.HTML(post.Description, render.Options{Width: width, ANSI: true}), "\n")...)
+
+	scroll := min(m.bodyScroll, max(len(lines)-height, 0))
+	end := min(scroll+height, len(lines))
+	return strings.Join(lines[scroll:end], "\n")
+}
+
+func (m model) footer() string {
+	if m.status != "" {
+		return m.status
+	}
+	filter := "all"
+	if m.unreadOnly {
+		filter = "unread"
+	}
+	return dimStyle.Render(fmt.Sprintf("j/k move · h/l pane · enter open · m read · s star · u filter (%s) · r refresh · q quit", filter))
+}
+
+// listView renders the window of lines around the cursor, highlighting it.
+func listView(lines []string, cursor, width, height int, focused bool) string {
+	start := 0
+	if cursor >= height {
+		start = cursor - height + 1
+	}
+	end := min(start+height, len(lines))
+	rendered := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		line := truncate(lines[i], width)
+		if i == cursor && focused {
+			line = selectedStyle.Render(line + strings.Repeat(" ", max(width-len([]rune(line)), 0)))
+		} else if i == cursor {
+			line = titleStyle.Render(line)
+		}
+		rendered = append(rendered, line)
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// wrap breaks text into lines no wider than width, on word boundaries.
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, truncate(line, width))
+				line = word
+			}
+		}
+		lines = append(lines, truncate(line, width))
+	}
+	return lines
+}
+
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func clamp(i, n int) int {
+	return max(min(i, n-1), 0)
+}
@@ -0,0 +1,289 @@
+package tui
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	goURL    = "https://go.example/feed.xml"
+	notesURL = "https://notes.example/feed.xml"
+)
+
+// readerDB serves one user's follows, saved searches and posts, and keeps
+// track of what they read and star.
+type readerDB struct {
+	database.Querier
+	follows  []database.GetFeedFollowsForUserRow
+	searches []database.SavedSearch
+	posts    []database.BrowsePostsForUserRow
+}
+
+func newReaderDB() *readerDB {
+	goID, notesID := uuid.New(), uuid.New()
+	tech := sql.NullString{String: "Tech", Valid: true}
+	published := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	post := func(title string, feedID uuid.UUID, feedName, feedURL string, category sql.NullString, age int) database.BrowsePostsForUserRow {
+		return database.BrowsePostsForUserRow{
+			ID: uuid.New(), Title: title, FeedID: feedID, FeedName: feedName, FeedUrl: feedURL, CategoryName: category,
+			PublishedAt: sql.NullTime{Time: published.Add(-time.Duration(age) * time.Hour), Valid: true},
+		}
+	}
+	return &readerDB{
+		follows: []database.GetFeedFollowsForUserRow{
+			{FeedID: goID, FeedName: "Go Blog", FeedUrl: goURL, CategoryName: tech},
+			{FeedID: notesID, FeedName: "Notes", FeedUrl: notesURL},
+		},
+		searches: []database.SavedSearch{{Name: "Starred", Query: "post.starred"}},
+		posts: []database.BrowsePostsForUserRow{
+			post("Go 1.30", goID, "Go Blog", goURL, tech, 1),
+			post("Notes on Go", notesID, "Notes", notesURL, sql.NullString{}, 2),
+			post("Go 1.29", goID, "Go Blog", goURL, tech, 3),
+		},
+	}
+}
+
+func (db *readerDB) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	return db.follows, nil
+}
+
+func (db *readerDB) GetSavedSearchesForUser(ctx context.Context, userID uuid.UUID) ([]database.SavedSearch, error) {
+	return db.searches, nil
+}
+
+func (db *readerDB) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	counts := []database.GetUnreadCountsForUserRow{}
+	for _, follow := range db.follows {
+		count := database.GetUnreadCountsForUserRow{FeedID: follow.FeedID}
+		for _, post := range db.posts {
+			if post.FeedID == follow.FeedID && !post.ReadAt.Valid {
+				count.Unread++
+			}
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// BrowsePostsForUser returns everything on the first page, so there is
+// never a second one.
+func (db *readerDB) BrowsePostsForUser(ctx context.Context, arg database.BrowsePostsForUserParams) ([]database.BrowsePostsForUserRow, error) {
+	rows := []database.BrowsePostsForUserRow{}
+	if arg.CursorID.Valid {
+		return rows, nil
+	}
+	for _, post := range db.posts {
+		switch {
+		case arg.Feed.Valid && post.FeedUrl != arg.Feed.String:
+		case arg.Category.Valid && post.CategoryName != sql.NullString{String: arg.Category.String, Valid: true}:
+		case arg.UnreadOnly && post.ReadAt.Valid:
+		default:
+			rows = append(rows, post)
+		}
+	}
+	return rows[:min(len(rows), int(arg.PostLimit))], nil
+}
+
+func (db *readerDB) setState(id uuid.UUID, set func(post *database.BrowsePostsForUserRow)) error {
+	for i := range db.posts {
+		if db.posts[i].ID == id {
+			set(&db.posts[i])
+		}
+	}
+	return nil
+}
+
+func (db *readerDB) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	return db.setState(arg.PostID, func(post *database.BrowsePostsForUserRow) {
+		post.ReadAt = sql.NullTime{Time: time.Now(), Valid: true}
+	})
+}
+
+func (db *readerDB) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	return db.setState(arg.PostID, func(post *database.BrowsePostsForUserRow) { post.ReadAt = sql.NullTime{} })
+}
+
+func (db *readerDB) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	return db.setState(arg.PostID, func(post *database.BrowsePostsForUserRow) {
+		post.StarredAt = sql.NullTime{Time: time.Now(), Valid: true}
+	})
+}
+
+func (db *readerDB) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	return db.setState(arg.PostID, func(post *database.BrowsePostsForUserRow) { post.StarredAt = sql.NullTime{} })
+}
+
+// loadedModel is the reader as it looks once its first loads are done.
+func loadedModel(t *testing.T, db *readerDB) model {
+	t.Helper()
+	m := newModel(db, database.User{ID: uuid.New(), Name: "susan"}, time.Minute)
+	m.width, m.height = 120, 40
+	return drain(t, m, tea.Batch(m.loadFeeds(), m.loadPosts()))
+}
+
+// drain runs cmd and everything it leads to, feeding each message back into
+// the model the way the bubbletea runtime would.
+func drain(t *testing.T, m model, cmd tea.Cmd) model {
+	t.Helper()
+	for queue := []tea.Cmd{cmd}; len(queue) > 0; {
+		cmd, queue = queue[0], queue[1:]
+		if cmd == nil {
+			continue
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		case errMsg:
+			t.Fatal(msg.err)
+		default:
+			var next tea.Cmd
+			m, next = update(m, msg)
+			queue = append(queue, next)
+		}
+	}
+	return m
+}
+
+func update(m model, msg tea.Msg) (model, tea.Cmd) {
+	next, cmd := m.Update(msg)
+	return next.(model), cmd
+}
+
+// press sends keys to the model, running whatever each one starts.
+func press(t *testing.T, m model, keys ...string) model {
+	t.Helper()
+	for _, k := range keys {
+		var cmd tea.Cmd
+		m, cmd = update(m, key(k))
+		m = drain(t, m, cmd)
+	}
+	return m
+}
+
+func key(k string) tea.KeyMsg {
+	if k == "enter" {
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+func titles(posts []database.BrowsePostsForUserRow) []string {
+	names := []string{}
+	for _, post := range posts {
+		names = append(names, post.Title)
+	}
+	return names
+}
+
+func (m model) feedUnread(name string) int64 {
+	for _, feed := range m.feeds {
+		if feed.name == name {
+			return feed.unread
+		}
+	}
+	return -1
+}
+
+func TestNavigation(t *testing.T) {
+	m := loadedModel(t, newReaderDB())
+	names := []string{}
+	for _, feed := range m.feeds {
+		names = append(names, feed.name)
+	}
+	if want := []string{"All feeds", "Starred", "Tech", "Go Blog", "Notes"}; !slices.Equal(names, want) {
+		t.Fatalf("feeds = %v, want %v", names, want)
+	}
+	if got, want := titles(m.posts), []string{"Go 1.30", "Notes on Go", "Go 1.29"}; !slices.Equal(got, want) {
+		t.Errorf("posts = %v, want %v", got, want)
+	}
+	if m.feedUnread("All feeds") != 3 || m.feedUnread("Tech") != 2 || m.feedUnread("Starred") != 0 {
+		t.Errorf("unexpected unread counts: %+v", m.feeds)
+	}
+
+	steps := []struct {
+		keys   []string
+		focus  pane
+		feed   string
+		posts  []string
+		cursor int
+	}{
+		{[]string{"j", "j"}, feedsPane, "Tech", []string{"Go 1.30", "Go 1.29"}, 0},
+		{[]string{"j"}, feedsPane, "Go Blog", []string{"Go 1.30", "Go 1.29"}, 0},
+		{[]string{"G"}, feedsPane, "Notes", []string{"Notes on Go"}, 0},
+		// The post selected in Notes stays selected.
+		{[]string{"g", "l"}, postsPane, "All feeds", []string{"Go 1.30", "Notes on Go", "Go 1.29"}, 1},
+		{[]string{"j", "j", "j"}, postsPane, "All feeds", []string{"Go 1.30", "Notes on Go", "Go 1.29"}, 2},
+		{[]string{"k"}, postsPane, "All feeds", []string{"Go 1.30", "Notes on Go", "Go 1.29"}, 1},
+		{[]string{"enter"}, bodyPane, "All feeds", []string{"Go 1.30", "Notes on Go", "Go 1.29"}, 1},
+		{[]string{"h", "h", "h", "j", "j", "j", "j"}, feedsPane, "Notes", []string{"Notes on Go"}, 0},
+	}
+	for _, step := range steps {
+		m = press(t, m, step.keys...)
+		if m.focus != step.focus || m.selectedFeed().name != step.feed || !slices.Equal(titles(m.posts), step.posts) || m.postCursor != step.cursor {
+			t.Fatalf("after %v: focus %v, feed %q, posts %v, cursor %d; want %v, %q, %v, %d",
+				step.keys, m.focus, m.selectedFeed().name, titles(m.posts), m.postCursor, step.focus, step.feed, step.posts, step.cursor)
+		}
+	}
+	// Opening "Notes on Go" marked it read.
+	if !m.posts[0].ReadAt.Valid || m.feedUnread("Notes") != 0 || m.feedUnread("All feeds") != 2 {
+		t.Errorf("the opened post should be read: %+v %+v", m.posts[0], m.feeds)
+	}
+}
+
+func TestStalePostsLoad(t *testing.T) {
+	m := loadedModel(t, newReaderDB())
+	// The cursor moves on to Notes before the posts for Go Blog arrive.
+	m = press(t, m, "j", "j")
+	m, goBlog := update(m, key("j"))
+	m, notes := update(m, key("j"))
+	m = drain(t, m, notes)
+	m = drain(t, m, goBlog)
+	if m.selectedFeed().name != "Notes" || !slices.Equal(titles(m.posts), []string{"Notes on Go"}) {
+		t.Errorf("feed %q shows posts %v, want only its own", m.selectedFeed().name, titles(m.posts))
+	}
+
+	// A refresh of the same feed that is overtaken is dropped too.
+	m, stale := update(m, key("r"))
+	db := m.db.(*readerDB)
+	db.posts[1].ReadAt = sql.NullTime{Time: time.Now(), Valid: true}
+	db.posts = append(db.posts, database.BrowsePostsForUserRow{ID: uuid.New(), Title: "More notes", FeedUrl: notesURL})
+	m, fresh := update(m, key("u"))
+	m = drain(t, m, fresh)
+	m = drain(t, m, stale)
+	if got := titles(m.posts); !slices.Equal(got, []string{"More notes"}) {
+		t.Errorf("posts = %v, want only the unread ones from the newer load", got)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	db := newReaderDB()
+	m := press(t, loadedModel(t, db), "l")
+
+	m = press(t, m, "m")
+	if !db.posts[0].ReadAt.Valid || !m.posts[0].ReadAt.Valid || m.feedUnread("All feeds") != 2 || m.feedUnread("Go Blog") != 1 {
+		t.Fatalf("m should mark the post read: %+v %+v", m.posts[0], m.feeds)
+	}
+	m = press(t, m, "m")
+	if db.posts[0].ReadAt.Valid || m.posts[0].ReadAt.Valid || m.feedUnread("All feeds") != 3 {
+		t.Fatalf("m again should mark the post unread: %+v %+v", m.posts[0], m.feeds)
+	}
+
+	// Showing unread posts only hides the one just read.
+	m = press(t, m, "j", "m", "u", "j")
+	if got := titles(m.posts); !slices.Equal(got, []string{"Go 1.30", "Go 1.29"}) || m.postCursor != 1 {
+		t.Errorf("unread posts = %v with the cursor on %d, want the read one hidden", got, m.postCursor)
+	}
+
+	// Starring counts towards the saved search straight away.
+	m = press(t, m, "s")
+	if !db.posts[2].StarredAt.Valid || m.feedUnread("Starred") != 1 {
+		t.Errorf("s should star the post and count it in Starred: %+v", m.feeds)
+	}
+}
@@ -4,23 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/curtisbraxdale/blog-gator/internal/config"
 	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/render"
 	"github.com/curtisbraxdale/blog-gator/internal/rss"
+	"github.com/curtisbraxdale/blog-gator/internal/tui"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
-	_ "github.com/lib/pq"
 )
 
 type state struct {
-	db     *database.Queries
+	db     database.Querier
 	config *config.Config
+	output string
 }
 
 type command struct {
@@ -41,7 +44,7 @@ func (c *commands) run(s *state, cmd command) error {
 		}
 		return nil
 	}
-	return errors.New("Command not found.")
+	return invalidArgs("Command not found.")
 }
 
 func (c *commands) register(name string, f func(*state, command) error) {
@@ -49,143 +52,282 @@ func (c *commands) register(name string, f func(*state, command) error) {
 }
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command in cliArguments and returns the exit code. It never
+// exits itself, so closing the database and any other defers always happen.
+func run(cliArguments []string) int {
 	fig, err := config.Read()
 	if err != nil {
-		fmt.Println("Error reading config file.")
-		return
+		fmt.Fprintln(os.Stderr, "Error reading config file.")
+		return exitFailure
+	}
+
+	output, cliArguments, err := extractOutputFlag(cliArguments)
+	if err != nil {
+		return reportError(err)
+	}
+	if len(cliArguments) < 1 {
+		return reportError(invalidArgs("Not enough arguments."))
+	}
+
+	appState := state{config: &fig, output: output}
+	db, driver, err := openDatabase(fig.DbUrl)
+	if err != nil {
+		return reportError(err)
+	}
+	defer db.Close()
+	appState.db = newQueries(db, driver)
+	cliCommands := newCommands()
+
+	commandName := cliArguments[0]
+	commandArguments := cliArguments[1:]
+	newCommand := command{name: commandName, arguments: commandArguments}
+	if commandName != "migrate" {
+		if err := checkSchema(db, driver); err != nil {
+			return reportError(err)
+		}
+	}
+	return reportError(cliCommands.run(&appState, newCommand))
+}
+
+// reportError prints err, if there is one, and returns its exit code.
+func reportError(err error) int {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorMessage(err))
 	}
+	return exitCode(err)
+}
 
-	appState := state{config: &fig}
-	db, err := sql.Open("postgres", fig.DbUrl)
-	dbQueries := database.New(db)
-	appState.db = dbQueries
+// newCommands registers every command gator has.
+func newCommands() commands {
 	cliCommands := commands{make(map[string]func(*state, command) error)}
 	cliCommands.register("login", handlerLogin)
+	cliCommands.register("logout", handlerLogout)
+	cliCommands.register("passwd", middlewareLoggedIn(handlerPasswd))
 	cliCommands.register("register", handlerRegister)
-	cliCommands.register("reset", handlerReset)
+	cliCommands.register("reset", middlewareAdmin(handlerReset))
 	cliCommands.register("users", handlerUsers)
+	cliCommands.register("user", middlewareLoggedIn(handlerUser))
 	cliCommands.register("agg", handlerAgg)
 	cliCommands.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	cliCommands.register("feeds", handlerFeeds)
+	cliCommands.register("feed", middlewareLoggedIn(handlerFeed))
 	cliCommands.register("follow", middlewareLoggedIn(handlerFollow))
 	cliCommands.register("following", middlewareLoggedIn(handlerFollowing))
 	cliCommands.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	cliCommands.register("browse", middlewareLoggedIn(handlerBrowse))
-
-	cliArguments := os.Args
-	if len(cliArguments) < 2 {
-		err = fmt.Errorf("Not enough arguments.")
-		fmt.Printf("Error Found: %v\n", err)
-		os.Exit(1)
-	}
-
-	commandName := cliArguments[1]
-	commandArguments := cliArguments[2:]
-	newCommand := command{name: commandName, arguments: commandArguments}
-	err = cliCommands.run(&appState, newCommand)
-	if err != nil {
-		fmt.Printf("Error Found: %v\n", err)
-		os.Exit(1)
-	}
+	cliCommands.register("tui", middlewareLoggedIn(handlerTUI))
+	cliCommands.register("category", middlewareLoggedIn(handlerCategory))
+	cliCommands.register("rule", middlewareLoggedIn(handlerRule))
+	cliCommands.register("saved-search", middlewareLoggedIn(handlerSavedSearch))
+	cliCommands.register("export", middlewareLoggedIn(handlerExport))
+	cliCommands.register("serve", handlerServe)
+	cliCommands.register("fever-key", middlewareLoggedIn(handlerFeverKey))
+	cliCommands.register("token", middlewareLoggedIn(handlerToken))
+	cliCommands.register("admin", middlewareAdmin(handlerAdmin))
+	cliCommands.register("retention", middlewareLoggedIn(handlerRetention))
+	cliCommands.register("purge", middlewareAdmin(handlerPurge))
+	cliCommands.register("migrate", handlerMigrate)
+	return cliCommands
 }
 
+// readOnlyCommands can be run with a read token in GATOR_TOKEN.
+var readOnlyCommands = map[string]bool{"browse": true, "following": true, "export": true}
+
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.config.CurrentUserName)
+		user, scope, err := currentUser(s)
 		if err != nil {
 			return err
 		}
+		if scope == tokenScopeRead && !readOnlyCommands[cmd.name] {
+			return unauthorized("The token in %v is read-only and %v can change data.", tokenEnv, cmd.name)
+		}
 		return handler(s, cmd, user)
 	}
 }
 
 func handlerLogin(s *state, cmd command) error {
 	if len(cmd.arguments) < 1 {
-		return errors.New("No Arguments")
+		return invalidArgs("usage: login <name>")
 	}
 	username := cmd.arguments[0]
-	_, err := s.db.GetUser(context.Background(), username)
+	user, err := s.db.GetUser(context.Background(), username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && user.PasswordHash == "" {
+		// Accounts from before passwords existed can't prove who they are, so
+		// an admin has to give them their first password.
+		return unauthorized("%v has no password yet. Ask an admin to set one with: gator user passwd %v", username, username)
+	}
+	password, err := readPassword("Password: ")
 	if err != nil {
-		os.Exit(1)
+		return err
 	}
-	err = s.config.SetUser(username)
+	if !checkPassword(user, password) {
+		return errBadLogin
+	}
+	token, err := createSession(context.Background(), s.db, user, sessionLength)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("User has been set to: %v", username)
+	if s.config.SessionToken != "" {
+		// Logging in again replaces the old session.
+		s.db.DeleteSession(context.Background(), hashToken(s.config.SessionToken))
+	}
+	err = s.config.SetSession(token)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User has been set to: %v\n", username)
 	return nil
 }
 
 func handlerRegister(s *state, cmd command) error {
 	if len(cmd.arguments) < 1 {
-		return errors.New("No Arguments")
+		return invalidArgs("usage: register <name>")
 	}
 	username := cmd.arguments[0]
-	userParams := database.CreateUserParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: username}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	userParams := database.CreateUserParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: username, PasswordHash: hash, CanBeAdmin: true}
 	newUser, err := s.db.CreateUser(context.Background(), userParams)
+	if isUniqueViolation(err) {
+		return alreadyExists("There's already a user named %v.", username)
+	}
+	if err != nil {
+		return err
+	}
+	token, err := createSession(context.Background(), s.db, newUser, sessionLength)
 	if err != nil {
-		fmt.Println("User already exists.")
-		os.Exit(1)
+		return err
 	}
-	err = s.config.SetUser(username)
+	err = s.config.SetSession(token)
 	if err != nil {
 		return err
 	}
 	fmt.Println("User Created:")
-	fmt.Print(newUser)
+	fmt.Printf("%v (%v)\n", newUser.Name, newUser.ID)
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
+func handlerReset(s *state, cmd command, user database.User) error {
+	args, yes := takeYes(cmd.arguments)
+	if len(args) > 0 {
+		return invalidArgs("usage: reset [--yes]")
+	}
+	if err := confirmAction(yes, "This deletes every user, feed and post."); err != nil {
+		return err
+	}
 	err := s.db.ResetDB(context.Background())
 	if err != nil {
-		os.Exit(1)
+		return err
 	}
 	fmt.Println("Succesfully reset table.")
-	os.Exit(0)
 	return nil
 }
 
 func handlerUsers(s *state, cmd command) error {
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
-		os.Exit(1)
+		return err
+	}
+	// Not being logged in just means no user is marked as current.
+	current, _, _ := currentUser(s)
+	if s.output != outputText {
+		records := []userRecord{}
+		for _, user := range users {
+			records = append(records, userRecord{Name: user, Current: user == current.Name})
+		}
+		return writeRecords(os.Stdout, s.output, records)
 	}
 	for _, user := range users {
-		if user == s.config.CurrentUserName {
+		if user == current.Name {
 			fmt.Printf("* %v (current)\n", user)
 		} else {
 			fmt.Printf("* %v\n", user)
 		}
 	}
-	os.Exit(0)
 	return nil
 }
 
 func handlerAgg(s *state, cmd command) error {
 	if len(cmd.arguments) < 1 {
-		return errors.New("Not enough arguments.")
+		return invalidArgs("Not enough arguments.")
 	}
 	timeBetweenRequests, err := time.ParseDuration(cmd.arguments[0])
 	if err != nil {
-		return err
+		return invalidArgs("%v", err)
+	}
+	flags := flag.NewFlagSet("agg", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	purge := flags.Bool("purge", false, "purge posts the retention policy doesn't keep once a day")
+	if err := flags.Parse(cmd.arguments[1:]); err != nil {
+		return invalidArgs("%v\nusage: agg <interval> [--purge]", err)
 	}
 	fmt.Printf("Collecting feeds every %v\n", timeBetweenRequests)
 	ticker := time.NewTicker(timeBetweenRequests)
+	var lastPurge time.Time
 	for ; ; <-ticker.C {
 		scrapeFeeds(s)
+		if *purge && time.Since(lastPurge) >= purgeInterval {
+			lastPurge = time.Now()
+			candidates, err := purgeCandidates(context.Background(), s.db)
+			if err == nil {
+				var purged int64
+				purged, err = purgePosts(context.Background(), s.db, candidates)
+				log.Printf("purged %d posts", purged)
+			}
+			if err != nil {
+				log.Printf("failed to purge posts: %v", err)
+			}
+		}
 	}
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.arguments) < 2 {
-		return errors.New("Not enough arguments.")
+	if len(cmd.arguments) < 1 {
+		return invalidArgs("Not enough arguments.")
+	}
+	var name, url string
+	var fetched *rss.RSSFeed
+	if len(cmd.arguments) == 1 {
+		// With only a URL, the feed is fetched now to name it after its title.
+		url = cmd.arguments[0]
+		feed, err := rss.FetchFeed(context.Background(), url)
+		if err != nil {
+			return err
+		}
+		name, fetched = strings.TrimSpace(feed.Channel.Title), feed
+		if name == "" {
+			return invalidArgs("The feed has no title. Give it a name: gator addfeed <name> <url>")
+		}
+	} else {
+		name, url = cmd.arguments[0], cmd.arguments[1]
 	}
-	feed_params := database.CreateFeedParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: cmd.arguments[0], Url: cmd.arguments[1], UserID: user.ID}
+	feed_params := database.CreateFeedParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, Url: url, UserID: user.ID}
 	new_feed, err := s.db.CreateFeed(context.Background(), feed_params)
+	if isUniqueViolation(err) {
+		return alreadyExists("There's already a feed with URL %v.", url)
+	}
 	if err != nil {
 		return err
 	}
+	if fetched != nil {
+		err = s.db.UpdateFeedMetadata(context.Background(), feedMetadata(new_feed.ID, fetched))
+		if err != nil {
+			return err
+		}
+	}
 
 	feedFollowParams := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: user.ID, FeedID: new_feed.ID}
 	_, err = s.db.CreateFeedFollow(context.Background(), feedFollowParams)
@@ -194,7 +336,7 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	}
 
 	fmt.Println("Feed created:")
-	fmt.Print(new_feed)
+	fmt.Printf("%v (%v)\n", new_feed.Name, new_feed.Url)
 	return nil
 }
 
@@ -203,28 +345,51 @@ func handlerFeeds(s *state, cmd command) error {
 	if err != nil {
 		return err
 	}
+	records := []feedRecord{}
 	for _, feedRow := range feedRows {
 		user, err := s.db.GetUsername(context.Background(), feedRow.UserID)
 		if err != nil {
 			return err
 		}
+		if s.output != outputText {
+			records = append(records, feedRecord{Name: feedRow.Name, URL: feedRow.Url, User: user.Name, Title: feedRow.Title, SiteURL: feedRow.SiteUrl, Description: feedRow.Description})
+			continue
+		}
 		fmt.Printf("Name: %s\n", feedRow.Name)
 		fmt.Printf("URL: %s\n", feedRow.Url)
+		if feedRow.Title != "" && feedRow.Title != feedRow.Name {
+			fmt.Printf("Title: %s\n", feedRow.Title)
+		}
+		if feedRow.SiteUrl != "" {
+			fmt.Printf("Site: %s\n", feedRow.SiteUrl)
+		}
+		if feedRow.Description != "" {
+			fmt.Printf("Description: %s\n", feedRow.Description)
+		}
 		fmt.Printf("Username: %s\n\n", user.Name)
 	}
+	if s.output != outputText {
+		return writeRecords(os.Stdout, s.output, records)
+	}
 	return nil
 }
 
 func handlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) < 1 {
-		return errors.New("Not enough arguments.")
+		return invalidArgs("Not enough arguments.")
 	}
 	feed_id, err := s.db.GetFeedID(context.Background(), cmd.arguments[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No feed with URL %v.", cmd.arguments[0])
+	}
 	if err != nil {
 		return err
 	}
 	feedFollowParams := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: user.ID, FeedID: feed_id}
 	newFeedFollows, err := s.db.CreateFeedFollow(context.Background(), feedFollowParams)
+	if isUniqueViolation(err) {
+		return alreadyExists("You already follow %v.", cmd.arguments[0])
+	}
 	if err != nil {
 		return err
 	}
@@ -237,19 +402,46 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%v follows:\n", s.config.CurrentUserName)
+	if s.output != outputText {
+		records := []followRecord{}
+		for _, followRow := range following {
+			records = append(records, followRecord{Feed: followRow.FeedName, URL: followRow.FeedUrl, Category: followRow.CategoryName.String})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	fmt.Printf("%v follows:\n", user.Name)
+	grouped := false
 	for _, followRow := range following {
-		fmt.Printf("%v\n", followRow.FeedName)
+		grouped = grouped || followRow.CategoryName.Valid
+	}
+	heading := ""
+	for i, followRow := range following {
+		if !grouped {
+			fmt.Printf("%v\n", followRow.FeedName)
+			continue
+		}
+		category := followRow.CategoryName.String
+		if !followRow.CategoryName.Valid {
+			category = "Uncategorized"
+		}
+		if i == 0 || category != heading {
+			heading = category
+			fmt.Printf("\n%v:\n", heading)
+		}
+		fmt.Printf("  %v\n", followRow.FeedName)
 	}
 	return nil
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) < 1 {
-		return errors.New("Not enough arguments.")
+		return invalidArgs("Not enough arguments.")
 	}
 	feed_url := cmd.arguments[0]
 	feed_id, err := s.db.GetFeedID(context.Background(), feed_url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No feed with URL %v.", feed_url)
+	}
 	if err != nil {
 		return err
 	}
@@ -261,7 +453,7 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func scrapeFeeds(s *state) error {
+func scrapeFeeds(s *state) (err error) {
 	next_feed, err := s.db.GetNextFeedToFetch(context.Background())
 	if err != nil {
 		return err
@@ -270,47 +462,128 @@ func scrapeFeeds(s *state) error {
 	if err != nil {
 		return err
 	}
+	// Keep the outcome of this fetch so feed info can show what went wrong.
+	defer func() {
+		lastError := ""
+		if err != nil {
+			lastError = err.Error()
+		}
+		params := database.SetFeedLastErrorParams{ID: next_feed.ID, LastError: lastError}
+		if recordErr := s.db.SetFeedLastError(context.Background(), params); recordErr != nil {
+			log.Printf("failed to record fetch result: %v", recordErr)
+		}
+	}()
 	feed, err := rss.FetchFeed(context.Background(), next_feed.Url)
 	if err != nil {
 		return err
 	}
+	metadata := feedMetadata(next_feed.ID, feed)
+	err = s.db.UpdateFeedMetadata(context.Background(), metadata)
+	if err != nil {
+		return err
+	}
+	ruleRows, err := s.db.GetRulesForFeedFollowers(context.Background(), next_feed.ID)
+	if err != nil {
+		return err
+	}
+	rules := compileFeedRules(ruleRows)
+	purgedURLs, err := s.db.GetPurgedPostURLs(context.Background(), next_feed.ID)
+	if err != nil {
+		return err
+	}
+	purged := map[string]bool{}
+	for _, url := range purgedURLs {
+		purged[url] = true
+	}
+	// Once a purged post has dropped out of the feed it can't come back,
+	// so its URL no longer needs to be remembered.
+	if len(purgedURLs) > 0 && len(feed.Channel.Item) > 0 {
+		links := []string{}
+		for _, item := range feed.Channel.Item {
+			links = append(links, item.Link)
+		}
+		params := database.PrunePurgedPostsParams{FeedID: next_feed.ID, Urls: links}
+		if _, err := s.db.PrunePurgedPosts(context.Background(), params); err != nil {
+			return err
+		}
+	}
 	for _, item := range feed.Channel.Item {
+		if purged[item.Link] {
+			continue
+		}
 		pub_date, err := time.Parse(time.RFC1123Z, item.PubDate)
 		if err != nil {
 			return err
 		}
-		post := database.CreatePostParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Title: item.Title, Url: item.Link, Description: item.Description, PublishedAt: sql.NullTime{Time: pub_date, Valid: true}, FeedID: next_feed.ID}
-		_, err = s.db.CreatePost(context.Background(), post)
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		post := database.CreatePostParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Title: item.Title, Url: item.Link, Description: item.Description, PublishedAt: sql.NullTime{Time: pub_date, Valid: true}, FeedID: next_feed.ID, Content: item.Content, Author: item.Author, Categories: strings.Join(item.Categories, ", ")}
+		new_post, err := s.db.CreatePost(context.Background(), post)
+		if isUniqueViolation(err) {
 			continue
 		}
 		if err != nil {
 			log.Printf("failed to create post: %v", err)
+			continue
 		}
+		applyRules(s, rules, next_feed, new_post)
+	}
+	if err := refreshFeedIcon(context.Background(), s.db, metadata); err != nil {
+		log.Printf("failed to store icon: %v", err)
 	}
 	return nil
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
-	limit := int32(2)
-	if len(cmd.arguments) > 0 {
-		limit64, err := strconv.ParseInt(cmd.arguments[0], 10, 32)
-		if err != nil {
-			return err
-		}
-		limit = int32(limit64)
+	opts, err := parseBrowseOptions(cmd.arguments)
+	if err != nil {
+		return err
 	}
-	get_post_params := database.GetPostsForUserParams{UserID: user.ID, Limit: int32(limit)}
-	posts, err := s.db.GetPostsForUser(context.Background(), get_post_params)
+	browseParams, err := opts.params(user.ID)
 	if err != nil {
 		return err
 	}
+	wheres, err := opts.wheres(s, user)
+	if err != nil {
+		return err
+	}
+	posts, more, err := loadBrowsePosts(s, user, browseParams, wheres, opts.showMuted)
+	if err != nil {
+		return err
+	}
+	if s.output != outputText {
+		records := []postRecord{}
+		for _, post := range posts {
+			records = append(records, newPostRecord(post))
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	renderOptions := terminalRenderOptions()
 	fmt.Print("Browsing Posts\n\n")
 	for _, post := range posts {
-		fmt.Printf("\nTitle: %v\nDescription: %v\nPublished: %v\n", post.Title, post.Description, post.PublishedAt.Time.Format("2006-01-02"))
+		title := render.StripControl(post.Title)
+		if post.highlighted {
+			title = "» " + title
+		}
+		fmt.Printf("\nTitle: %v\nFeed: %v\nPublished: %v\n\n", title, render.StripControl(post.FeedName), post.PublishedAt.Time.Format("2006-01-02"))
+		fmt.Println(render.HTML(post.Description, renderOptions))
 	}
 	if len(posts) == 0 {
 		fmt.Println("No posts found for your feeds!")
 	}
+	if more {
+		last := posts[len(posts)-1]
+		fmt.Printf("\nNext page: --cursor %v\n", encodeCursor(last.PublishedAt.Time, last.ID))
+	}
 	return nil
 }
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	refresh := 30 * time.Second
+	if len(cmd.arguments) > 0 {
+		interval, err := time.ParseDuration(cmd.arguments[0])
+		if err != nil {
+			return invalidArgs("%v", err)
+		}
+		refresh = interval
+	}
+	return tui.Run(s.db, user, refresh)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The schema migrations in sql/schema (and their SQLite versions in
+// sql/sqlite/schema) are built into gator. Applied versions are kept in
+// goose's goose_db_version table, so databases set up with goose before this
+// command existed carry on where they left off.
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var schemaFiles embed.FS
+
+// schemaDirs has the migrations for each driver. Both sets have the same
+// versions.
+var schemaDirs = map[string]string{
+	driverPostgres: "sql/schema",
+	driverSQLite:   "sql/sqlite/schema",
+}
+
+const migrateUsage = "usage: migrate up | down [--yes] | status"
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+type migrationRecord struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// loadMigrations parses the driver's embedded migrations, oldest first. Each
+// file is named <version>_<name>.sql and has "-- +goose Up" and
+// "-- +goose Down" sections.
+func loadMigrations(driver string) ([]migration, error) {
+	files, err := fs.Glob(schemaFiles, schemaDirs[driver]+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations := []migration{}
+	for _, file := range files {
+		name := path.Base(file)
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %v doesn't start with a version number", name)
+		}
+		source, err := schemaFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		up, down, ok := strings.Cut(string(source), "-- +goose Down")
+		if !ok || !strings.Contains(up, "-- +goose Up") {
+			return nil, fmt.Errorf("migration %v needs -- +goose Up and -- +goose Down sections", name)
+		}
+		migrations = append(migrations, migration{version: version, name: name, up: strings.Replace(up, "-- +goose Up", "", 1), down: down})
+	}
+	// fs.Glob sorts by name and the versions are zero padded.
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version <= migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %v and %v are out of order", migrations[i-1].name, migrations[i].name)
+		}
+	}
+	return migrations, nil
+}
+
+// appliedMigrations maps each applied version to when it was applied. A
+// database without the version table has nothing applied.
+func appliedMigrations(db *sql.DB, driver string) (map[int64]time.Time, error) {
+	exists, err := versionTableExists(db, driver)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]time.Time{}
+	if !exists {
+		return applied, nil
+	}
+	// Older goose versions record a down migration as a new row with
+	// is_applied false, so only the latest row for each version counts.
+	rows, err := db.Query("SELECT version_id, is_applied, tstamp FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied && version > 0 {
+			applied[version] = tstamp.Time
+		}
+	}
+	return applied, rows.Err()
+}
+
+// pendingMigrations lists the migrations that haven't been applied yet.
+func pendingMigrations(db *sql.DB, driver string) ([]migration, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return nil, err
+	}
+	pending := []migration{}
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// checkSchema refuses to go on when the database is missing migrations
+// or has ones this version of gator doesn't know about.
+func checkSchema(db *sql.DB, driver string) error {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return fmt.Errorf("Couldn't read the database schema version: %w", err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if len(applied) == 0 {
+		return errors.New("The database schema hasn't been set up yet. Create it with: gator migrate up")
+	}
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("The database has migration %d, which is newer than this version of gator knows about (%d). Upgrade gator.", version, latest)
+		}
+	}
+	missing := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; !ok {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("The database is %d migrations behind this version of gator. Update it with: gator migrate up", missing)
+	}
+	return nil
+}
+
+// migrateUp applies every pending migration, each in its own transaction.
+func migrateUp(db *sql.DB, driver string) ([]migration, error) {
+	pending, err := pendingMigrations(db, driver)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	if err := createVersionTable(db, driver); err != nil {
+		return nil, err
+	}
+	for i, m := range pending {
+		err := inTransaction(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.up); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)", m.version)
+			return err
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("%v: %w", m.name, err)
+		}
+	}
+	return pending, nil
+}
+
+// migrateDown rolls back the latest applied migration.
+func migrateDown(db *sql.DB, driver string) (migration, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return migration{}, err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return migration{}, err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+		err := inTransaction(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM goose_db_version WHERE version_id = $1", m.version)
+			return err
+		})
+		if err != nil {
+			return migration{}, fmt.Errorf("%v: %w", m.name, err)
+		}
+		return m, nil
+	}
+	return migration{}, errors.New("No migrations to roll back.")
+}
+
+func versionTableExists(db *sql.DB, driver string) (bool, error) {
+	query := "SELECT to_regclass('goose_db_version')::text"
+	if driver == driverSQLite {
+		query = "SELECT MAX(name) FROM sqlite_master WHERE type = 'table' AND name = 'goose_db_version'"
+	}
+	var table sql.NullString
+	if err := db.QueryRow(query).Scan(&table); err != nil {
+		return false, err
+	}
+	return table.Valid, nil
+}
+
+// createVersionTable sets up goose_db_version the way goose does, with a
+// row for version 0.
+func createVersionTable(db *sql.DB, driver string) error {
+	exists, err := versionTableExists(db, driver)
+	if err != nil || exists {
+		return err
+	}
+	create := `CREATE TABLE goose_db_version (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT now()
+)`
+	if driver == driverSQLite {
+		create = `CREATE TABLE goose_db_version (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`
+	}
+	return inTransaction(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(create)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, TRUE)")
+		return err
+	})
+}
+
+func inTransaction(db *sql.DB, f func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// handlerMigrate creates or updates the database tables. It's the only
+// command that runs against an out of date schema.
+func handlerMigrate(s *state, cmd command) error {
+	args, yes := takeYes(cmd.arguments)
+	if len(args) != 1 {
+		return invalidArgs(migrateUsage)
+	}
+	db, driver, err := openDatabase(s.config.DbUrl)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	switch args[0] {
+	case "up":
+		applied, err := migrateUp(db, driver)
+		for _, m := range applied {
+			fmt.Printf("Applied %v\n", m.name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("The database is up to date.")
+		}
+		return nil
+	case "down":
+		if err := confirmAction(yes, "This rolls back the latest migration, which may delete data."); err != nil {
+			return err
+		}
+		m, err := migrateDown(db, driver)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %v\n", m.name)
+		return nil
+	case "status":
+		return migrateStatus(s, db, driver)
+	}
+	return invalidArgs(migrateUsage)
+}
+
+func migrateStatus(s *state, db *sql.DB, driver string) error {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return err
+	}
+	records := []migrationRecord{}
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.version]
+		records = append(records, migrationRecord{Version: m.version, Name: m.name, Applied: ok, AppliedAt: nullTime(ok && !appliedAt.IsZero(), appliedAt)})
+	}
+	if s.output != outputText {
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	for _, record := range records {
+		switch {
+		case record.AppliedAt != nil:
+			fmt.Printf("* %v (applied %v)\n", record.Name, record.AppliedAt.Format("2006-01-02 15:04"))
+		case record.Applied:
+			fmt.Printf("* %v (applied)\n", record.Name)
+		default:
+			fmt.Printf("* %v (pending)\n", record.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	postgres, err := loadMigrations(driverPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postgres) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	sqlite, err := loadMigrations(driverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("%d SQLite migrations for %d Postgres ones", len(sqlite), len(postgres))
+	}
+	for i, m := range postgres {
+		if m.version != int64(i+1) {
+			t.Errorf("%v has version %d, expected %d", m.name, m.version, i+1)
+		}
+		if sqlite[i].name != m.name {
+			t.Errorf("the SQLite version of %v is named %v", m.name, sqlite[i].name)
+		}
+		for _, m := range []migration{m, sqlite[i]} {
+			if strings.TrimSpace(m.up) == "" || strings.TrimSpace(m.down) == "" {
+				t.Errorf("%v is missing its up or down section", m.name)
+			}
+			if strings.Contains(m.up, "+goose") || strings.Contains(m.down, "+goose") {
+				t.Errorf("%v still has goose annotations", m.name)
+			}
+		}
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	eachSQLBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+
+		if err := checkSchema(db, driver); err != nil {
+			t.Fatalf("freshly migrated database: %v", err)
+		}
+		rolledBack, err := migrateDown(db, driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkSchema(db, driver)
+		if err == nil || !strings.Contains(err.Error(), "gator migrate up") {
+			t.Errorf("expected the check to ask for migrate up, got %v", err)
+		}
+		applied, err := migrateUp(db, driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applied) != 1 || applied[0].version != rolledBack.version {
+			t.Errorf("expected only %v to be applied again, got %v", rolledBack.name, applied)
+		}
+		if err := checkSchema(db, driver); err != nil {
+			t.Errorf("after migrating up again: %v", err)
+		}
+		if applied, err := migrateUp(db, driver); err != nil || len(applied) != 0 {
+			t.Errorf("an up to date database shouldn't change, got %v, %v", applied, err)
+		}
+	})
+}
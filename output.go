@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	outputText  = ""
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+	outputTable = "table"
+)
+
+// maxTableField keeps long fields like post descriptions from stretching
+// every row of a table.
+const maxTableField = 60
+
+// extractOutputFlag pulls the global --output (or -o) option from in front
+// of the command name and returns the command and its arguments. Everything
+// after the command name is left alone, so an argument that happens to be
+// -o, like a rule pattern, still reaches the command.
+func extractOutputFlag(args []string) (string, []string, error) {
+	format := outputText
+	for len(args) > 0 {
+		switch arg := args[0]; {
+		case arg == "--output" || arg == "-o":
+			if len(args) < 2 {
+				return "", nil, invalidArgs("--output needs a format: json, csv, tsv or table")
+			}
+			format, args = args[1], args[2:]
+		case strings.HasPrefix(arg, "--output="):
+			format, args = strings.TrimPrefix(arg, "--output="), args[1:]
+		default:
+			return format, args, nil
+		}
+		switch format {
+		case outputJSON, outputCSV, outputTSV, outputTable:
+		default:
+			return "", nil, invalidArgs("unknown output format %q (use json, csv, tsv or table)", format)
+		}
+	}
+	return format, args, nil
+}
+
+type userRecord struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+}
+
+type feedRecord struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	User        string `json:"user"`
+	Title       string `json:"title"`
+	SiteURL     string `json:"site_url"`
+	Description string `json:"description"`
+}
+
+type followRecord struct {
+	Feed     string `json:"feed"`
+	URL      string `json:"url"`
+	Category string `json:"category"`
+}
+
+type postRecord struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Feed        string     `json:"feed"`
+	Category    string     `json:"category"`
+	PublishedAt *time.Time `json:"published_at"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+	Highlighted bool       `json:"highlighted"`
+	Description string     `json:"description"`
+	Cursor      string     `json:"cursor"`
+}
+
+// writeRecords prints a slice of structs in the given machine-readable
+// format. Column names come from the json tags, so every format uses the
+// same field names.
+func writeRecords[T any](w io.Writer, format string, records []T) error {
+	if format == outputJSON {
+		if records == nil {
+			records = []T{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(records)
+	}
+
+	recordType := reflect.TypeFor[T]()
+	header := make([]string, recordType.NumField())
+	for i := range header {
+		header[i], _, _ = strings.Cut(recordType.Field(i).Tag.Get("json"), ",")
+	}
+	rows := make([][]string, len(records))
+	for i, record := range records {
+		value := reflect.ValueOf(record)
+		rows[i] = make([]string, value.NumField())
+		for j := range rows[i] {
+			rows[i][j] = formatField(value.Field(j))
+		}
+	}
+
+	switch format {
+	case outputCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	case outputTSV:
+		// TSV has no quoting, so tabs and newlines inside a field become spaces.
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, row := range rows {
+			for j := range row {
+				row[j] = strings.Join(strings.FieldsFunc(row[j], isTSVSeparator), " ")
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return nil
+	case outputTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			for j := range row {
+				row[j] = truncateField(strings.Join(strings.Fields(row[j]), " "), maxTableField)
+			}
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func isTSVSeparator(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r'
+}
+
+func truncateField(field string, width int) string {
+	runes := []rune(field)
+	if len(runes) <= width {
+		return field
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func formatField(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// nullTime turns an optional database timestamp into a pointer so it's
+// written as null (or an empty field) when missing.
+func nullTime(valid bool, t time.Time) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
+
+func newPostRecord(post browsedPost) postRecord {
+	return postRecord{
+		ID:          post.ID.String(),
+		Title:       post.Title,
+		URL:         post.Url,
+		Feed:        post.FeedName,
+		Category:    post.CategoryName.String,
+		PublishedAt: nullTime(post.PublishedAt.Valid, post.PublishedAt.Time),
+		Read:        post.ReadAt.Valid,
+		Starred:     post.StarredAt.Valid,
+		Highlighted: post.highlighted,
+		Description: post.Description,
+		Cursor:      encodeCursor(post.PublishedAt.Time, post.ID),
+	}
+}
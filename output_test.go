@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractOutputFlag(t *testing.T) {
+	tests := []struct {
+		args   []string
+		format string
+		rest   []string
+		err    bool
+	}{
+		{[]string{"feeds"}, outputText, []string{"feeds"}, false},
+		{[]string{"-o", "json", "feeds"}, outputJSON, []string{"feeds"}, false},
+		{[]string{"--output", "csv", "browse", "10"}, outputCSV, []string{"browse", "10"}, false},
+		{[]string{"--output=tsv", "following"}, outputTSV, []string{"following"}, false},
+		{[]string{"-o", "json", "-o", "table", "users"}, outputTable, []string{"users"}, false},
+		// After the command name, -o belongs to the command.
+		{[]string{"rule", "add", "read", "title", "-o"}, outputText, []string{"rule", "add", "read", "title", "-o"}, false},
+		{[]string{"-o", "json", "addfeed", "-o", "https://example.com/feed.xml"}, outputJSON, []string{"addfeed", "-o", "https://example.com/feed.xml"}, false},
+		{[]string{"feeds", "--output=json"}, outputText, []string{"feeds", "--output=json"}, false},
+		{[]string{"-o", "json"}, outputJSON, []string{}, false},
+		{[]string{"-o"}, "", nil, true},
+		{[]string{"-o", "xml", "feeds"}, "", nil, true},
+		{[]string{"--output=", "feeds"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		format, rest, err := extractOutputFlag(tt.args)
+		if tt.err {
+			if exitCode(err) != exitInvalidArgs {
+				t.Errorf("extractOutputFlag(%q) = %v, want invalid arguments", tt.args, err)
+			}
+			continue
+		}
+		if err != nil || format != tt.format || !slices.Equal(rest, tt.rest) {
+			t.Errorf("extractOutputFlag(%q) = %q, %q, %v, want %q, %q", tt.args, format, rest, err, tt.format, tt.rest)
+		}
+	}
+}
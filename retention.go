@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const retentionUsage = "usage: retention [show] | set [--max-age 90d|off] [--max-posts 500|off] [--keep-latest 20] | feed <url> [--max-age 90d|off|default] [--max-posts 500|off|default]"
+
+const (
+	// purgeBatchSize is how many posts one delete removes.
+	purgeBatchSize = 1000
+	// purgeInterval is how often agg --purge runs a purge.
+	purgeInterval = 24 * time.Hour
+)
+
+type retentionRecord struct {
+	Feed       string `json:"feed"`
+	URL        string `json:"url"`
+	MaxAgeDays *int32 `json:"max_age_days"`
+	MaxPosts   *int32 `json:"max_posts"`
+	KeepLatest *int32 `json:"keep_latest"`
+}
+
+type purgeRecord struct {
+	Feed  string `json:"feed"`
+	Posts int    `json:"posts"`
+}
+
+// handlerRetention shows and changes the retention policy, which decides
+// which posts purge may delete. A post goes once it is older than the max
+// age or further down its feed than the max post count, but starred posts,
+// posts someone following the feed hasn't read and the latest posts of
+// every feed are always kept. Feeds can override the max age and count;
+// zero means no limit.
+func handlerRetention(s *state, cmd command, user database.User) error {
+	args := cmd.arguments
+	switch {
+	case len(args) == 0, len(args) == 1 && args[0] == "show":
+		return retentionShow(s)
+	case args[0] == "set":
+		return retentionSet(s, user, args[1:])
+	case args[0] == "feed" && len(args) >= 2:
+		return retentionFeed(s, user, args[1], args[2:])
+	}
+	return invalidArgs(retentionUsage)
+}
+
+func retentionShow(s *state) error {
+	settings, err := s.db.GetRetentionSettings(context.Background())
+	if err != nil {
+		return err
+	}
+	feeds, err := s.db.GetFeedRetentions(context.Background())
+	if err != nil {
+		return err
+	}
+	if s.output != outputText {
+		records := []retentionRecord{{MaxAgeDays: &settings.MaxAgeDays, MaxPosts: &settings.MaxPosts, KeepLatest: &settings.KeepLatest}}
+		for _, feed := range feeds {
+			records = append(records, retentionRecord{
+				Feed:       feed.Name,
+				URL:        feed.Url,
+				MaxAgeDays: nullInt32(feed.RetentionMaxAgeDays),
+				MaxPosts:   nullInt32(feed.RetentionMaxPosts),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	fmt.Printf("Max age:     %v\n", describeMaxAge(settings.MaxAgeDays))
+	fmt.Printf("Max posts:   %v\n", describeMaxPosts(settings.MaxPosts))
+	fmt.Printf("Keep latest: %d posts per feed\n", settings.KeepLatest)
+	if len(feeds) > 0 {
+		fmt.Println("Feed overrides:")
+	}
+	for _, feed := range feeds {
+		fmt.Printf("* %v (%v)", feed.Name, feed.Url)
+		if feed.RetentionMaxAgeDays.Valid {
+			fmt.Printf(", max age %v", describeMaxAge(feed.RetentionMaxAgeDays.Int32))
+		}
+		if feed.RetentionMaxPosts.Valid {
+			fmt.Printf(", max posts %v", describeMaxPosts(feed.RetentionMaxPosts.Int32))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// retentionSet changes the policy for every feed. Only admins can, since
+// it deletes other users' posts.
+func retentionSet(s *state, user database.User, args []string) error {
+	if !user.IsAdmin {
+		return unauthorized("Only admins can change the retention policy.")
+	}
+	settings, err := s.db.GetRetentionSettings(context.Background())
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("retention set", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	maxAge := flags.String("max-age", "", "delete read posts older than this, like 90d, or off")
+	maxPosts := flags.String("max-posts", "", "keep at most this many posts per feed, or off")
+	keepLatest := flags.Int("keep-latest", int(settings.KeepLatest), "always keep this many of each feed's latest posts")
+	if err := flags.Parse(args); err != nil {
+		return invalidArgs("%v\n%v", err, retentionUsage)
+	}
+	if flags.NArg() > 0 || flags.NFlag() == 0 {
+		return invalidArgs(retentionUsage)
+	}
+	params := database.SetRetentionSettingsParams{UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, MaxAgeDays: settings.MaxAgeDays, MaxPosts: settings.MaxPosts}
+	if *maxAge != "" {
+		if params.MaxAgeDays, err = parseMaxAge(*maxAge); err != nil {
+			return err
+		}
+	}
+	if *maxPosts != "" {
+		if params.MaxPosts, err = parseMaxPosts(*maxPosts); err != nil {
+			return err
+		}
+	}
+	if *keepLatest < 0 {
+		return invalidArgs("--keep-latest can't be negative.")
+	}
+	params.KeepLatest = int32(*keepLatest)
+	if err := s.db.SetRetentionSettings(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("Retention set: max age %v, max posts %v, keeping the latest %d posts per feed.\n", describeMaxAge(params.MaxAgeDays), describeMaxPosts(params.MaxPosts), params.KeepLatest)
+	return nil
+}
+
+// retentionFeed overrides the max age or post count for one feed.
+// "default" goes back to the global setting.
+func retentionFeed(s *state, user database.User, url string, args []string) error {
+	feed, err := s.db.GetFeedByURL(context.Background(), url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("No feed with URL %v.", url)
+	}
+	if err != nil {
+		return err
+	}
+	if err := canManageFeed(user, feed); err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("retention feed", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	maxAge := flags.String("max-age", "", "delete read posts older than this, like 90d, off or default")
+	maxPosts := flags.String("max-posts", "", "keep at most this many posts, off or default")
+	if err := flags.Parse(args); err != nil {
+		return invalidArgs("%v\n%v", err, retentionUsage)
+	}
+	if flags.NArg() > 0 || flags.NFlag() == 0 {
+		return invalidArgs(retentionUsage)
+	}
+	params := database.SetFeedRetentionParams{ID: feed.ID, RetentionMaxAgeDays: feed.RetentionMaxAgeDays, RetentionMaxPosts: feed.RetentionMaxPosts, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	if *maxAge != "" {
+		if params.RetentionMaxAgeDays, err = parseOverride(*maxAge, parseMaxAge); err != nil {
+			return err
+		}
+	}
+	if *maxPosts != "" {
+		if params.RetentionMaxPosts, err = parseOverride(*maxPosts, parseMaxPosts); err != nil {
+			return err
+		}
+	}
+	if err := s.db.SetFeedRetention(context.Background(), params); err != nil {
+		return err
+	}
+	age, posts := "default", "default"
+	if params.RetentionMaxAgeDays.Valid {
+		age = describeMaxAge(params.RetentionMaxAgeDays.Int32)
+	}
+	if params.RetentionMaxPosts.Valid {
+		posts = describeMaxPosts(params.RetentionMaxPosts.Int32)
+	}
+	fmt.Printf("Retention for %v: max age %v, max posts %v.\n", feed.Name, age, posts)
+	return nil
+}
+
+// parseMaxAge reads a max age in whole days. "off" and "0" mean no limit.
+func parseMaxAge(value string) (int32, error) {
+	if value == "off" || value == "0" {
+		return 0, nil
+	}
+	lifetime, err := parseLifetime(value)
+	if err != nil {
+		return 0, err
+	}
+	if lifetime < 24*time.Hour {
+		return 0, invalidArgs("max age %q is less than a day", value)
+	}
+	return int32(lifetime / (24 * time.Hour)), nil
+}
+
+// parseMaxPosts reads a post count. "off" and "0" mean no limit.
+func parseMaxPosts(value string) (int32, error) {
+	if value == "off" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 0 {
+		return 0, invalidArgs("invalid post count %q (use a number or off)", value)
+	}
+	return int32(n), nil
+}
+
+func parseOverride(value string, parse func(string) (int32, error)) (sql.NullInt32, error) {
+	if value == "default" {
+		return sql.NullInt32{}, nil
+	}
+	n, err := parse(value)
+	if err != nil {
+		return sql.NullInt32{}, err
+	}
+	return sql.NullInt32{Int32: n, Valid: true}, nil
+}
+
+func describeMaxAge(days int32) string {
+	if days == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func describeMaxPosts(posts int32) string {
+	if posts == 0 {
+		return "off"
+	}
+	return strconv.Itoa(int(posts))
+}
+
+func nullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+// handlerPurge deletes the posts the retention policy no longer keeps.
+// --dry-run only reports what would go.
+func handlerPurge(s *state, cmd command, user database.User) error {
+	args, yes := takeYes(cmd.arguments)
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dryRun := flags.Bool("dry-run", false, "only report what would be deleted")
+	if err := flags.Parse(args); err != nil {
+		return invalidArgs("%v\nusage: purge [--dry-run] [--yes]", err)
+	}
+	if flags.NArg() > 0 {
+		return invalidArgs("unexpected argument %q\nusage: purge [--dry-run] [--yes]", flags.Arg(0))
+	}
+	candidates, err := purgeCandidates(context.Background(), s.db)
+	if err != nil {
+		return err
+	}
+	report := purgeReport(candidates)
+	if s.output != outputText {
+		if !*dryRun {
+			if err := confirmAction(yes, fmt.Sprintf("This deletes %d posts.", len(candidates))); err != nil {
+				return err
+			}
+			if _, err := purgePosts(context.Background(), s.db, candidates); err != nil {
+				return err
+			}
+		}
+		return writeRecords(os.Stdout, s.output, report)
+	}
+	if len(candidates) == 0 {
+		fmt.Println("Nothing to purge.")
+		return nil
+	}
+	if *dryRun {
+		fmt.Printf("Would purge %d posts:\n", len(candidates))
+		for _, record := range report {
+			fmt.Printf("* %v: %d\n", record.Feed, record.Posts)
+		}
+		return nil
+	}
+	if err := confirmAction(yes, fmt.Sprintf("This deletes %d posts from %d feeds.", len(candidates), len(report))); err != nil {
+		return err
+	}
+	purged, err := purgePosts(context.Background(), s.db, candidates)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d posts.\n", purged)
+	return nil
+}
+
+func purgeCandidates(ctx context.Context, db database.Querier) ([]database.GetPurgeablePostsRow, error) {
+	settings, err := db.GetRetentionSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := database.GetPurgeablePostsParams{KeepLatest: settings.KeepLatest, MaxAgeDays: settings.MaxAgeDays, MaxPosts: settings.MaxPosts}
+	return db.GetPurgeablePosts(ctx, params)
+}
+
+// purgeReport counts the posts to purge per feed. The candidates come
+// sorted by feed name.
+func purgeReport(candidates []database.GetPurgeablePostsRow) []purgeRecord {
+	report := []purgeRecord{}
+	for _, candidate := range candidates {
+		if len(report) == 0 || report[len(report)-1].Feed != candidate.FeedName {
+			report = append(report, purgeRecord{Feed: candidate.FeedName})
+		}
+		report[len(report)-1].Posts++
+	}
+	return report
+}
+
+// purgePosts deletes the candidates in batches. Their URLs are kept so agg
+// doesn't add them again while they're still in the feed.
+func purgePosts(ctx context.Context, db database.Querier, candidates []database.GetPurgeablePostsRow) (int64, error) {
+	var purged int64
+	for start := 0; start < len(candidates); start += purgeBatchSize {
+		ids := []uuid.UUID{}
+		for _, candidate := range candidates[start:min(start+purgeBatchSize, len(candidates))] {
+			ids = append(ids, candidate.ID)
+		}
+		n, err := db.PurgePosts(ctx, database.PurgePostsParams{Ids: ids, PurgedAt: time.Now().UTC()})
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
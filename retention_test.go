@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestParseRetention(t *testing.T) {
+	ages := map[string]int32{"off": 0, "0": 0, "1d": 1, "90d": 90, "48h": 2, "36h": 1}
+	for value, want := range ages {
+		if got, err := parseMaxAge(value); err != nil || got != want {
+			t.Errorf("parseMaxAge(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "12h", "-3d", "soon", "default"} {
+		if _, err := parseMaxAge(value); err == nil {
+			t.Errorf("parseMaxAge(%q) should fail", value)
+		}
+	}
+	posts := map[string]int32{"off": 0, "0": 0, "500": 500}
+	for value, want := range posts {
+		if got, err := parseMaxPosts(value); err != nil || got != want {
+			t.Errorf("parseMaxPosts(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "-1", "lots", "99999999999"} {
+		if _, err := parseMaxPosts(value); err == nil {
+			t.Errorf("parseMaxPosts(%q) should fail", value)
+		}
+	}
+	if got, err := parseOverride("default", parseMaxPosts); err != nil || got.Valid {
+		t.Errorf("default should clear the override, got %v, %v", got, err)
+	}
+	if got, err := parseOverride("off", parseMaxPosts); err != nil || !got.Valid || got.Int32 != 0 {
+		t.Errorf("off should override with no limit, got %v, %v", got, err)
+	}
+}
+
+func TestPurgeReport(t *testing.T) {
+	candidates := []database.GetPurgeablePostsRow{
+		{ID: uuid.New(), FeedName: "A"},
+		{ID: uuid.New(), FeedName: "A"},
+		{ID: uuid.New(), FeedName: "B"},
+	}
+	want := []purgeRecord{{Feed: "A", Posts: 2}, {Feed: "B", Posts: 1}}
+	if got := purgeReport(candidates); !slices.Equal(got, want) {
+		t.Errorf("purgeReport() = %v, want %v", got, want)
+	}
+	if got := purgeReport(nil); len(got) != 0 {
+		t.Errorf("purgeReport(nil) = %v, want nothing", got)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		ctx := context.Background()
+		s := &state{db: db, output: outputText}
+		david, susan := testUser(t, db, "david"), testUser(t, db, "susan")
+		url := "https://example.com/feed.xml"
+		if err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"Blog", url}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if err := handlerFollow(s, command{name: "follow", arguments: []string{url}}, susan); err != nil {
+			t.Fatal(err)
+		}
+		feed, err := db.GetFeedByURL(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Six read posts, newest last, except that susan hasn't read post 1
+		// and david starred post 2.
+		published := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i := range 6 {
+			params := database.CreatePostParams{
+				ID:          uuid.New(),
+				CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+				UpdatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+				Title:       fmt.Sprintf("Post %d", i),
+				Url:         fmt.Sprintf("https://example.com/%d", i),
+				PublishedAt: sql.NullTime{Time: published.Add(time.Duration(i) * time.Hour), Valid: true},
+				FeedID:      feed.ID,
+			}
+			post, err := db.CreatePost(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range []database.User{david, susan} {
+				if i == 1 && user.ID == susan.ID {
+					continue
+				}
+				if err := markRead(ctx, db, user.ID, post.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if i == 2 {
+				if err := star(ctx, db, david.ID, post.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		remaining := func() int64 {
+			t.Helper()
+			stats, err := db.GetFeedPostStats(ctx, feed.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return stats.Posts
+		}
+
+		if err := handlerRetention(s, command{name: "retention", arguments: []string{"set", "--max-posts", "2"}}, susan); err == nil {
+			t.Error("only admins can set the global policy")
+		}
+		if err := handlerRetention(s, command{name: "retention", arguments: []string{"set", "--max-posts", "2", "--keep-latest", "1"}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if err := handlerPurge(s, command{name: "purge", arguments: []string{"--dry-run"}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if got := remaining(); got != 6 {
+			t.Fatalf("a dry run shouldn't delete anything, %d posts left", got)
+		}
+
+		// Posts 3 and 0 are past the limit; 2 is starred and 1 is unread.
+		if err := handlerPurge(s, command{name: "purge", arguments: []string{"--yes"}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if got := remaining(); got != 4 {
+			t.Fatalf("expected 4 posts after the purge, got %d", got)
+		}
+		purged, err := db.GetPurgedPostURLs(ctx, feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(purged)
+		if want := []string{"https://example.com/0", "https://example.com/3"}; !slices.Equal(purged, want) {
+			t.Errorf("purged URLs = %v, want %v", purged, want)
+		}
+
+		// Limiting the feed to one post only lets post 4 go.
+		if err := handlerRetention(s, command{name: "retention", arguments: []string{"feed", url, "--max-posts", "1"}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if err := handlerPurge(s, command{name: "purge", arguments: []string{"--yes"}}, david); err != nil {
+			t.Fatal(err)
+		}
+		if got := remaining(); got != 3 {
+			t.Errorf("expected 3 posts after the feed override, got %d", got)
+		}
+	})
+}
+
+func TestPurgedPosts(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		ctx := context.Background()
+		david := testUser(t, db, "david")
+		feed := testFeed(t, db, david, "Blog", "https://example.com/feed.xml")
+		newPost := func(url string) uuid.UUID {
+			t.Helper()
+			now := sql.NullTime{Time: time.Now(), Valid: true}
+			params := database.CreatePostParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Title: url, Url: url, FeedID: feed.ID}
+			post, err := db.CreatePost(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return post.ID
+		}
+
+		// A post that comes back after being purged is counted again, even
+		// though its URL is already recorded.
+		for range 2 {
+			ids := []uuid.UUID{newPost("https://example.com/a"), newPost("https://example.com/b")}
+			purged, err := db.PurgePosts(ctx, database.PurgePostsParams{Ids: ids, PurgedAt: time.Now().UTC()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if purged != 2 {
+				t.Errorf("PurgePosts() = %d, want 2", purged)
+			}
+		}
+
+		pruned, err := db.PrunePurgedPosts(ctx, database.PrunePurgedPostsParams{FeedID: feed.ID, Urls: []string{"https://example.com/a", "https://example.com/new"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls, err := db.GetPurgedPostURLs(ctx, feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pruned != 1 || !slices.Equal(urls, []string{"https://example.com/a"}) {
+			t.Errorf("pruned %d and kept %v, want 1 pruned and only a kept", pruned, urls)
+		}
+	})
+}
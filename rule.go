@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/filter"
+	"github.com/google/uuid"
+)
+
+var ruleUsage = fmt.Sprintf("usage: rule add <%s> <%s> <pattern or expression> [--regex] | list | rm <id>",
+	strings.Join(filter.Actions, "|"), strings.Join(filter.Fields, "|"))
+
+type ruleRecord struct {
+	ID      string `json:"id"`
+	Action  string `json:"action"`
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+	Regex   bool   `json:"regex"`
+}
+
+// handlerRule manages the current user's filter rules. Star and read rules
+// are applied when the aggregator stores a new post; mute and highlight
+// rules are applied whenever posts are browsed.
+func handlerRule(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 1 {
+		return invalidArgs("%v", ruleUsage)
+	}
+	subcommand, args := cmd.arguments[0], cmd.arguments[1:]
+	switch {
+	case subcommand == "add" && (len(args) == 3 || len(args) == 4):
+		isRegex := false
+		if len(args) == 4 {
+			if args[3] != "--regex" {
+				return invalidArgs("%v", ruleUsage)
+			}
+			isRegex = true
+		}
+		return ruleAdd(s, user, args[0], args[1], args[2], isRegex)
+	case subcommand == "list" && len(args) == 0:
+		return ruleList(s, user)
+	case subcommand == "rm" && len(args) == 1:
+		return ruleRemove(s, user, args[0])
+	}
+	return invalidArgs("%v", ruleUsage)
+}
+
+func ruleAdd(s *state, user database.User, action, field, pattern string, isRegex bool) error {
+	if _, err := filter.Compile(field, pattern, isRegex, action); err != nil {
+		return err
+	}
+	params := database.CreateRuleParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: user.ID, Field: field, Pattern: pattern, IsRegex: isRegex, Action: action}
+	rule, err := s.db.CreateRule(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rule created: %v\n", rule.ID)
+	return nil
+}
+
+func ruleList(s *state, user database.User) error {
+	rules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if s.output != outputText {
+		records := []ruleRecord{}
+		for _, rule := range rules {
+			records = append(records, ruleRecord{ID: rule.ID.String(), Action: rule.Action, Field: rule.Field, Pattern: rule.Pattern, Regex: rule.IsRegex})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	for _, rule := range rules {
+		if rule.Field == "expr" {
+			fmt.Printf("%v  %v when %v\n", rule.ID, rule.Action, rule.Pattern)
+			continue
+		}
+		kind := "contains"
+		if rule.IsRegex {
+			kind = "matches"
+		}
+		fmt.Printf("%v  %v when %v %v %q\n", rule.ID, rule.Action, rule.Field, kind, rule.Pattern)
+	}
+	return nil
+}
+
+func ruleRemove(s *state, user database.User, id string) error {
+	ruleID, err := uuid.Parse(id)
+	if err != nil {
+		return invalidArgs("invalid rule id %q", id)
+	}
+	removed, err := s.db.DeleteRule(context.Background(), database.DeleteRuleParams{ID: ruleID, UserID: user.ID})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return notFound("No rule with id %v.", id)
+	}
+	fmt.Printf("Rule removed: %v\n", id)
+	return nil
+}
+
+// feedRule is a follower's rule, compiled once for each fetch of a feed,
+// with the category the follower keeps the feed in.
+type feedRule struct {
+	database.GetRulesForFeedFollowersRow
+	compiled filter.Rule
+}
+
+// compileFeedRules compiles the rules of a feed's followers. Rules that no
+// longer compile are logged and skipped.
+func compileFeedRules(rows []database.GetRulesForFeedFollowersRow) []feedRule {
+	rules := make([]feedRule, 0, len(rows))
+	for _, row := range rows {
+		compiled, err := filter.Compile(row.Field, row.Pattern, row.IsRegex, row.Action)
+		if err != nil {
+			log.Printf("skipping rule %v: %v", row.ID, err)
+			continue
+		}
+		rules = append(rules, feedRule{GetRulesForFeedFollowersRow: row, compiled: compiled})
+	}
+	return rules
+}
+
+// applyRules runs each follower's rules against a newly stored post. Muted
+// posts are marked read so they don't count as unread.
+func applyRules(s *state, rules []feedRule, feed database.GetNextFeedToFetchRow, post database.Post) {
+	target := filter.Post{
+		Title:       post.Title,
+		Description: post.Description,
+		Content:     post.Content,
+		Author:      post.Author,
+		Categories:  post.Categories,
+		URL:         post.Url,
+		PublishedAt: post.PublishedAt.Time,
+		FeedName:    feed.Name,
+		FeedURL:     feed.Url,
+	}
+	for _, rule := range rules {
+		target.Category = rule.CategoryName.String
+		if !rule.compiled.Matches(target) {
+			continue
+		}
+		var err error
+		switch rule.Action {
+		case filter.ActionStar:
+			err = s.db.StarPost(context.Background(), database.StarPostParams{UserID: rule.UserID, PostID: post.ID})
+		case filter.ActionRead, filter.ActionMute:
+			err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{UserID: rule.UserID, PostID: post.ID})
+		}
+		if err != nil {
+			log.Printf("failed to apply rule %v: %v", rule.ID, err)
+		}
+	}
+}
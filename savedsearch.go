@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/expr"
+	"github.com/curtisbraxdale/blog-gator/internal/filter"
+	"github.com/google/uuid"
+)
+
+const savedSearchUsage = "usage: saved-search add <name> <expression> | rm <name> | list"
+
+type savedSearchRecord struct {
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	Unread int64  `json:"unread"`
+}
+
+// handlerSavedSearch manages the current user's saved searches. A saved
+// search is a filter expression that browse --saved and the TUI treat like
+// a feed of its own.
+func handlerSavedSearch(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 1 {
+		return invalidArgs(savedSearchUsage)
+	}
+	subcommand, args := cmd.arguments[0], cmd.arguments[1:]
+	switch {
+	case subcommand == "add" && len(args) == 2:
+		return savedSearchAdd(s, user, args[0], args[1])
+	case subcommand == "rm" && len(args) == 1:
+		return savedSearchRemove(s, user, args[0])
+	case subcommand == "list" && len(args) == 0:
+		return savedSearchList(s, user)
+	}
+	return invalidArgs(savedSearchUsage)
+}
+
+func savedSearchAdd(s *state, user database.User, name, query string) error {
+	if _, err := expr.Compile(query); err != nil {
+		return invalidArgs("invalid expression: %w", err)
+	}
+	params := database.CreateSavedSearchParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, Query: query, UserID: user.ID}
+	search, err := s.db.CreateSavedSearch(context.Background(), params)
+	if isUniqueViolation(err) {
+		return alreadyExists("You already have a saved search named %v.", name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved search created: %v\n", search.Name)
+	return nil
+}
+
+func savedSearchRemove(s *state, user database.User, name string) error {
+	removed, err := s.db.DeleteSavedSearch(context.Background(), database.DeleteSavedSearchParams{UserID: user.ID, Name: name})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return notFound("No saved search named %v.", name)
+	}
+	fmt.Printf("Saved search removed: %v\n", name)
+	return nil
+}
+
+func savedSearchList(s *state, user database.User) error {
+	searches, err := s.db.GetSavedSearchesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	programs := make([]*expr.Program, len(searches))
+	for i, search := range searches {
+		programs[i], err = expr.Compile(search.Query)
+		if err != nil {
+			return fmt.Errorf("saved search %v: %w", search.Name, err)
+		}
+	}
+	unread, err := filter.UnreadCounts(context.Background(), s.db, user.ID, programs)
+	if err != nil {
+		return err
+	}
+	if s.output != outputText {
+		records := []savedSearchRecord{}
+		for i, search := range searches {
+			records = append(records, savedSearchRecord{Name: search.Name, Query: search.Query, Unread: unread[i]})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	for i, search := range searches {
+		fmt.Printf("* %v (%d unread): %v\n", search.Name, unread[i], search.Query)
+	}
+	return nil
+}
+
+func compileSavedSearch(s *state, user database.User, name string) (*expr.Program, error) {
+	search, err := s.db.GetSavedSearch(context.Background(), database.GetSavedSearchParams{UserID: user.ID, Name: name})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("No saved search named %v.", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	program, err := expr.Compile(search.Query)
+	if err != nil {
+		return nil, fmt.Errorf("saved search %v: %w", name, err)
+	}
+	return program, nil
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	apiPrefix       = "/api/v1"
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxRequestBody  = 1 << 20
+)
+
+// handlerServe runs the JSON API until the process is stopped.
+func handlerServe(s *state, cmd command) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	if err := flags.Parse(cmd.arguments); err != nil {
+		return invalidArgs("%v\nusage: serve [--addr <host:port>]", err)
+	}
+	if flags.NArg() > 0 {
+		return invalidArgs("unexpected argument %q\nusage: serve [--addr <host:port>]", flags.Arg(0))
+	}
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           newAPIServer(s, log.New(os.Stderr, "", log.LstdFlags)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving on http://%v/ (API at %v/)\n", *addr, apiPrefix)
+	return server.ListenAndServe()
+}
+
+type apiServer struct {
+	state  *state
+	logger *log.Logger
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiUser struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Admin     bool       `json:"admin"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type apiFeed struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	User          string     `json:"user"`
+	CreatedAt     *time.Time `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+type apiFollow struct {
+	FeedID   string `json:"feed_id"`
+	Feed     string `json:"feed"`
+	URL      string `json:"url"`
+	Category string `json:"category"`
+}
+
+// apiPage is the body of every list response. NextOffset or NextCursor is
+// set when there are more items to fetch.
+type apiPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextOffset int    `json:"next_offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// newAPIServer returns the handler for the versioned JSON API. Requests are
+// made on behalf of the user who owns the request's API token.
+func newAPIServer(s *state, logger *log.Logger) http.Handler {
+	api := &apiServer{state: s, logger: logger}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPrefix+"/users", api.authenticated(api.handleListUsers))
+	mux.HandleFunc("POST "+apiPrefix+"/users", api.handleCreateUser)
+	mux.HandleFunc("GET "+apiPrefix+"/me", api.authenticated(api.handleMe))
+	mux.HandleFunc("GET "+apiPrefix+"/feeds", api.authenticated(api.handleListFeeds))
+	mux.HandleFunc("POST "+apiPrefix+"/feeds", api.authenticated(api.handleCreateFeed))
+	mux.HandleFunc("GET "+apiPrefix+"/feeds/{feedID}/icon", api.authenticated(api.handleFeedIcon))
+	mux.HandleFunc("GET "+apiPrefix+"/follows", api.authenticated(api.handleListFollows))
+	mux.HandleFunc("POST "+apiPrefix+"/follows", api.authenticated(api.handleCreateFollow))
+	mux.HandleFunc("DELETE "+apiPrefix+"/follows/{feedID}", api.authenticated(api.handleDeleteFollow))
+	mux.HandleFunc("GET "+apiPrefix+"/posts", api.authenticated(api.handleListPosts))
+	mux.HandleFunc("PUT "+apiPrefix+"/posts/{postID}/read", api.authenticated(api.handlePostState(markRead)))
+	mux.HandleFunc("DELETE "+apiPrefix+"/posts/{postID}/read", api.authenticated(api.handlePostState(markUnread)))
+	mux.HandleFunc("PUT "+apiPrefix+"/posts/{postID}/star", api.authenticated(api.handlePostState(star)))
+	mux.HandleFunc("DELETE "+apiPrefix+"/posts/{postID}/star", api.authenticated(api.handlePostState(unstar)))
+	mux.HandleFunc("GET "+apiPrefix+"/timeline", api.authenticated(api.handleTimeline))
+	api.registerGReader(mux)
+	api.registerFever(mux)
+	api.registerWeb(mux)
+	return api.logRequests(mux)
+}
+
+// statusRecorder remembers the status code written so it can be logged.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (api *apiServer) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		api.logger.Printf("%v %v %v %v", r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+// authenticated is the API's version of middlewareLoggedIn. Requests carry
+// an API token in the "Authorization: Bearer <token>" header. Feed readers
+// can't set headers, so GET /timeline also takes a read token as the token
+// query parameter; URLs end up in logs and browser history, so nothing else
+// does. Read tokens may only make GET requests.
+func (api *apiServer) authenticated(handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		inQuery := false
+		if !ok && r.Method == http.MethodGet && r.URL.Path == apiPrefix+"/timeline" {
+			token, inQuery = r.URL.Query().Get("token"), true
+		}
+		if token == "" {
+			writeAPIError(w, http.StatusUnauthorized, "missing API token")
+			return
+		}
+		user, scope, err := userByAPIToken(r.Context(), api.state.db, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeAPIError(w, http.StatusUnauthorized, "invalid, expired or revoked API token")
+			return
+		}
+		if err != nil {
+			api.internalError(w, err)
+			return
+		}
+		if inQuery && scope != tokenScopeRead {
+			writeAPIError(w, http.StatusForbidden, "only read tokens can be passed in the URL")
+			return
+		}
+		if scope != tokenScopeWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeAPIError(w, http.StatusForbidden, "read-only API token")
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+func (api *apiServer) handleListUsers(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	users, err := api.state.db.ListUsers(r.Context(), database.ListUsersParams{Limit: int32(limit + 1), Offset: int32(offset)})
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	page := apiPage[apiUser]{Items: []apiUser{}}
+	for i, u := range users {
+		if i == limit {
+			page.NextOffset = offset + limit
+			break
+		}
+		page.Items = append(page.Items, newAPIUser(u))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (api *apiServer) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if err := validatePassword(body.Password); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hash, err := hashPassword(body.Password)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	userParams := database.CreateUserParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: body.Name, PasswordHash: hash}
+	newUser, err := api.state.db.CreateUser(r.Context(), userParams)
+	if err != nil {
+		api.databaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newAPIUser(newUser))
+}
+
+func (api *apiServer) handleMe(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, newAPIUser(user))
+}
+
+func (api *apiServer) handleListFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	feeds, err := api.state.db.ListFeeds(r.Context(), database.ListFeedsParams{Limit: int32(limit + 1), Offset: int32(offset)})
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	page := apiPage[apiFeed]{Items: []apiFeed{}}
+	for i, feed := range feeds {
+		if i == limit {
+			page.NextOffset = offset + limit
+			break
+		}
+		page.Items = append(page.Items, apiFeed{
+			ID:            feed.ID.String(),
+			Name:          feed.Name,
+			URL:           feed.Url,
+			User:          feed.UserName,
+			CreatedAt:     nullTime(feed.CreatedAt.Valid, feed.CreatedAt.Time),
+			LastFetchedAt: nullTime(feed.LastFetchedAt.Valid, feed.LastFetchedAt.Time),
+		})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleCreateFeed adds a feed and follows it, like addfeed.
+func (api *apiServer) handleCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" || body.URL == "" {
+		writeAPIError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	feed_params := database.CreateFeedParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: body.Name, Url: body.URL, UserID: user.ID}
+	new_feed, err := api.state.db.CreateFeed(r.Context(), feed_params)
+	if err != nil {
+		api.databaseError(w, err)
+		return
+	}
+	feedFollowParams := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: user.ID, FeedID: new_feed.ID}
+	if _, err := api.state.db.CreateFeedFollow(r.Context(), feedFollowParams); err != nil {
+		api.databaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFeed{
+		ID:        new_feed.ID.String(),
+		Name:      new_feed.Name,
+		URL:       new_feed.Url,
+		User:      user.Name,
+		CreatedAt: nullTime(new_feed.CreatedAt.Valid, new_feed.CreatedAt.Time),
+	})
+}
+
+func (api *apiServer) handleListFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	following, err := api.state.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	// A user follows few enough feeds to page through them in memory.
+	page := apiPage[apiFollow]{Items: []apiFollow{}}
+	for i := offset; i < len(following); i++ {
+		if i == offset+limit {
+			page.NextOffset = i
+			break
+		}
+		followRow := following[i]
+		page.Items = append(page.Items, apiFollow{FeedID: followRow.FeedID.String(), Feed: followRow.FeedName, URL: followRow.FeedUrl, Category: followRow.CategoryName.String})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (api *apiServer) handleCreateFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		URL string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.URL == "" {
+		writeAPIError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	feed_id, err := api.state.db.GetFeedID(r.Context(), body.URL)
+	if err != nil {
+		api.databaseError(w, err)
+		return
+	}
+	feedFollowParams := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: user.ID, FeedID: feed_id}
+	follow, err := api.state.db.CreateFeedFollow(r.Context(), feedFollowParams)
+	if err != nil {
+		api.databaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFollow{FeedID: feed_id.String(), Feed: follow.FeedName, URL: body.URL})
+}
+
+func (api *apiServer) handleDeleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid feed id")
+		return
+	}
+	deleteParams := database.DeleteFeedFollowParams{UserID: user.ID, FeedID: feedID}
+	if err := api.state.db.DeleteFeedFollow(r.Context(), deleteParams); err != nil {
+		api.internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListPosts takes the same options as browse, as query parameters:
+// limit, offset, feed, category, since, until, unread, starred, where,
+// saved, show-muted, cursor and sort.
+func (api *apiServer) handleListPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	args := append([]string{"--limit=" + strconv.Itoa(defaultPageSize)}, queryFlags(r.URL.Query(), "token")...)
+	opts, err := parseBrowseOptions(args)
+	if err == nil && opts.limit > maxPageSize {
+		err = fmt.Errorf("limit can't be more than %d", maxPageSize)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params, err := opts.params(user.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	wheres, err := opts.wheres(api.state, user)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	posts, more, err := loadBrowsePosts(api.state, user, params, wheres, opts.showMuted)
+	if err != nil {
+		api.internalError(w, err)
+		return
+	}
+	page := apiPage[postRecord]{Items: []postRecord{}}
+	for _, post := range posts {
+		page.Items = append(page.Items, newPostRecord(post))
+	}
+	if more {
+		page.NextCursor = page.Items[len(page.Items)-1].Cursor
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// postStateChange changes a post's read or starred state for a user.
+type postStateChange func(ctx context.Context, db database.Querier, userID, postID uuid.UUID) error
+
+func markRead(ctx context.Context, db database.Querier, userID, postID uuid.UUID) error {
+	return db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: userID, PostID: postID})
+}
+
+func markUnread(ctx context.Context, db database.Querier, userID, postID uuid.UUID) error {
+	return db.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: userID, PostID: postID})
+}
+
+func star(ctx context.Context, db database.Querier, userID, postID uuid.UUID) error {
+	return db.StarPost(ctx, database.StarPostParams{UserID: userID, PostID: postID})
+}
+
+func unstar(ctx context.Context, db database.Querier, userID, postID uuid.UUID) error {
+	return db.UnstarPost(ctx, database.UnstarPostParams{UserID: userID, PostID: postID})
+}
+
+func (api *apiServer) handlePostState(change postStateChange) func(w http.ResponseWriter, r *http.Request, user database.User) {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		postID, err := uuid.Parse(r.PathValue("postID"))
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid post id")
+			return
+		}
+		if err := change(r.Context(), api.state.db, user.ID, postID); err != nil {
+			api.databaseError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleTimeline serves the same feed as export. It takes the export flags
+// as query parameters: format, category, saved, starred, limit, title and
+// link. The link defaults to the server itself, where the web UI lists the
+// same posts.
+func (api *apiServer) handleTimeline(w http.ResponseWriter, r *http.Request, user database.User) {
+	opts, err := parseExportOptions(queryFlags(r.URL.Query(), "token"))
+	if err == nil && opts.limit > maxPageSize {
+		err = fmt.Errorf("limit can't be more than %d", maxPageSize)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.saved != "" {
+		if _, err := compileSavedSearch(api.state, user, opts.saved); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if opts.link == "" {
+		opts.link = serverURL(r)
+	}
+	contentType := "application/rss+xml; charset=utf-8"
+	if opts.format == "atom" {
+		contentType = "application/atom+xml; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if err := writeTimeline(w, api.state, user, opts); err != nil {
+		api.logger.Printf("writing timeline: %v", err)
+	}
+}
+
+// serverURL is the address the request reached the server at.
+func serverURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/"
+}
+
+func newAPIUser(user database.User) apiUser {
+	return apiUser{ID: user.ID.String(), Name: user.Name, Admin: user.IsAdmin, CreatedAt: nullTime(user.CreatedAt.Valid, user.CreatedAt.Time)}
+}
+
+// parsePage reads the limit and offset query parameters.
+func parsePage(query url.Values) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be a number from 1 to %d", maxPageSize)
+		}
+		limit = n
+	}
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a number of at least 0")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// queryFlags turns query parameters into command line flags, so endpoints
+// can share option parsing with the matching command.
+func queryFlags(query url.Values, skip ...string) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	args := []string{}
+	for _, key := range keys {
+		if slices.Contains(skip, key) {
+			continue
+		}
+		for _, value := range query[key] {
+			args = append(args, "--"+key+"="+value)
+		}
+	}
+	return args
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, body any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(body)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Error: message})
+}
+
+// databaseError maps the errors callers can cause, like naming a feed that
+// doesn't exist or adding one twice, to client errors.
+func (api *apiServer) databaseError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch {
+	case isUniqueViolation(err):
+		writeAPIError(w, http.StatusConflict, "already exists")
+		return
+	case isForeignKeyViolation(err):
+		writeAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+	api.internalError(w, err)
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23503"
+	}
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+func (api *apiServer) internalError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	api.logger.Printf("internal error: %v", err)
+	writeAPIError(w, http.StatusInternalServerError, "internal error")
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+// testAPI serves the API for a test. db may be nil for tests that never reach
+// the database.
+func testAPI(t *testing.T, db database.Querier) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(newAPIServer(&state{db: db}, log.New(io.Discard, "", 0)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// eachSQLBackend runs test once for every database driver, each time with a
+// freshly migrated database: a new SQLite file, and the Postgres database
+// in GATOR_TEST_DB_URL. Without GATOR_TEST_DB_URL the Postgres run is
+// skipped.
+func eachSQLBackend(t *testing.T, test func(t *testing.T, db *sql.DB, driver string)) {
+	for _, backend := range []string{driverSQLite, driverPostgres} {
+		t.Run(backend, func(t *testing.T) {
+			db, driver := testSQLDB(t, backend)
+			test(t, db, driver)
+		})
+	}
+}
+
+// eachBackend is eachSQLBackend for tests that only need the queries.
+func eachBackend(t *testing.T, test func(t *testing.T, db database.Querier)) {
+	eachSQLBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		test(t, newQueries(db, driver))
+	})
+}
+
+// testSQLDB opens a test database for driver and recreates the schema with
+// the embedded migrations. The tables in GATOR_TEST_DB_URL are dropped.
+func testSQLDB(t *testing.T, driver string) (*sql.DB, string) {
+	t.Helper()
+	dbURL := "sqlite:" + filepath.Join(t.TempDir(), "gator.db")
+	if driver == driverPostgres {
+		dbURL = os.Getenv("GATOR_TEST_DB_URL")
+		if dbURL == "" {
+			t.Skip("set GATOR_TEST_DB_URL to an empty Postgres database to run this against Postgres")
+		}
+	}
+	db, opened, err := openDatabase(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened != driver {
+		t.Fatalf("GATOR_TEST_DB_URL should be a Postgres URL, got a %v one", opened)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		// The tables may not exist yet.
+		db.Exec(migrations[i].down)
+	}
+	db.Exec("DROP TABLE IF EXISTS goose_db_version")
+	if _, err := migrateUp(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	return db, driver
+}
+
+// testToken creates an API token for the named user.
+func testToken(t *testing.T, db database.Querier, name, scope string) string {
+	t.Helper()
+	user, err := db.GetUser(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := createAPIToken(context.Background(), db, user, scope, scope, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+type apiResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// call makes a request to the test server. token is sent as a bearer token
+// unless it's empty.
+func call(t *testing.T, server *httptest.Server, method, path, token string, body any) apiResponse {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return apiResponse{status: res.StatusCode, header: res.Header, body: data}
+}
+
+func (r apiResponse) decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.body, v); err != nil {
+		t.Fatalf("decoding %s: %v", r.body, err)
+	}
+}
+
+func expectStatus(t *testing.T, r apiResponse, status int) {
+	t.Helper()
+	if r.status != status {
+		t.Fatalf("got status %v, want %v: %s", r.status, status, r.body)
+	}
+}
+
+func TestAPIRequiresUser(t *testing.T) {
+	server := testAPI(t, nil)
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/users"},
+		{"GET", "/api/v1/me"},
+		{"GET", "/api/v1/feeds"},
+		{"POST", "/api/v1/feeds"},
+		{"GET", "/api/v1/feeds/" + uuid.NewString() + "/icon"},
+		{"GET", "/api/v1/follows"},
+		{"DELETE", "/api/v1/follows/" + uuid.NewString()},
+		{"GET", "/api/v1/posts"},
+		{"PUT", "/api/v1/posts/" + uuid.NewString() + "/read"},
+		{"DELETE", "/api/v1/posts/" + uuid.NewString() + "/star"},
+		{"GET", "/api/v1/timeline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			res := call(t, server, tt.method, tt.path, "", nil)
+			expectStatus(t, res, http.StatusUnauthorized)
+			var body apiError
+			res.decode(t, &body)
+			if body.Error == "" {
+				t.Errorf("expected an error message")
+			}
+		})
+	}
+}
+
+func TestAPIRouting(t *testing.T) {
+	server := testAPI(t, nil)
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/api/v1/nope", http.StatusNotFound},
+		{"GET", "/api/v2/users", http.StatusNotFound},
+		{"DELETE", "/api/v1/users", http.StatusMethodNotAllowed},
+		{"POST", "/api/v1/posts", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			res := call(t, server, tt.method, tt.path, "", nil)
+			expectStatus(t, res, tt.status)
+		})
+	}
+}
+
+func TestAPICreateUserValidation(t *testing.T) {
+	server := testAPI(t, nil)
+	tests := []struct {
+		name string
+		body any
+	}{
+		{"missing name", map[string]string{}},
+		{"empty name", map[string]string{"name": ""}},
+		{"unknown field", map[string]string{"name": "a", "password": "correct horse", "admin": "yes"}},
+		{"missing password", map[string]string{"name": "a"}},
+		{"short password", map[string]string{"name": "a", "password": "short"}},
+		{"not an object", []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := call(t, server, "POST", "/api/v1/users", "", tt.body)
+			expectStatus(t, res, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query  string
+		limit  int
+		offset int
+		err    bool
+	}{
+		{"", defaultPageSize, 0, false},
+		{"limit=10", 10, 0, false},
+		{"limit=10&offset=20", 10, 20, false},
+		{"limit=0", 0, 0, true},
+		{"limit=501", 0, 0, true},
+		{"limit=ten", 0, 0, true},
+		{"offset=-1", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/?"+tt.query, nil)
+			limit, offset, err := parsePage(req.URL.Query())
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error: %v", err, tt.err)
+			}
+			if !tt.err && (limit != tt.limit || offset != tt.offset) {
+				t.Errorf("got %v, %v, want %v, %v", limit, offset, tt.limit, tt.offset)
+			}
+		})
+	}
+}
+
+func TestQueryFlags(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?unread=true&token=secret&limit=5&feed=a&feed=b", nil)
+	got := queryFlags(req.URL.Query(), "token")
+	want := []string{"--feed=a", "--feed=b", "--limit=5", "--unread=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAPIUsersFeedsAndFollows(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		server := testAPI(t, db)
+
+		for _, name := range []string{"david", "larry", "susan"} {
+			res := call(t, server, "POST", "/api/v1/users", "", map[string]string{"name": name, "password": "correct horse"})
+			expectStatus(t, res, http.StatusCreated)
+		}
+		expectStatus(t, call(t, server, "POST", "/api/v1/users", "", map[string]string{"name": "david", "password": "correct horse"}), http.StatusConflict)
+		david, larry := testToken(t, db, "david", tokenScopeWrite), testToken(t, db, "larry", tokenScopeWrite)
+		expectStatus(t, call(t, server, "GET", "/api/v1/me", "gator_nonsense", nil), http.StatusUnauthorized)
+
+		var me apiUser
+		res := call(t, server, "GET", "/api/v1/me", david, nil)
+		expectStatus(t, res, http.StatusOK)
+		res.decode(t, &me)
+		if me.Name != "david" || me.Admin {
+			t.Errorf("got user %+v, want david without admin even as the first user", me)
+		}
+		me = apiUser{}
+		call(t, server, "GET", "/api/v1/me", larry, nil).decode(t, &me)
+		if me.Name != "larry" || me.Admin {
+			t.Errorf("got user %+v, want larry without admin", me)
+		}
+
+		var users apiPage[apiUser]
+		res = call(t, server, "GET", "/api/v1/users?limit=2", david, nil)
+		expectStatus(t, res, http.StatusOK)
+		res.decode(t, &users)
+		if len(users.Items) != 2 || users.Items[0].Name != "david" || users.NextOffset != 2 {
+			t.Fatalf("unexpected first page: %+v", users)
+		}
+		res = call(t, server, "GET", "/api/v1/users?limit=2&offset=2", david, nil)
+		users = apiPage[apiUser]{}
+		res.decode(t, &users)
+		if len(users.Items) != 1 || users.Items[0].Name != "susan" || users.NextOffset != 0 {
+			t.Fatalf("unexpected last page: %+v", users)
+		}
+
+		var feed apiFeed
+		res = call(t, server, "POST", "/api/v1/feeds", david, map[string]string{"name": "Blog", "url": "https://example.com/feed.xml"})
+		expectStatus(t, res, http.StatusCreated)
+		res.decode(t, &feed)
+		expectStatus(t, call(t, server, "POST", "/api/v1/feeds", larry, map[string]string{"name": "Again", "url": "https://example.com/feed.xml"}), http.StatusConflict)
+		expectStatus(t, call(t, server, "POST", "/api/v1/feeds", larry, map[string]string{"name": "No URL"}), http.StatusBadRequest)
+
+		var feeds apiPage[apiFeed]
+		res = call(t, server, "GET", "/api/v1/feeds", larry, nil)
+		expectStatus(t, res, http.StatusOK)
+		res.decode(t, &feeds)
+		if len(feeds.Items) != 1 || feeds.Items[0].User != "david" || feeds.Items[0].ID != feed.ID {
+			t.Fatalf("unexpected feeds: %+v", feeds)
+		}
+
+		expectStatus(t, call(t, server, "POST", "/api/v1/follows", larry, map[string]string{"url": "https://example.com/missing.xml"}), http.StatusNotFound)
+		expectStatus(t, call(t, server, "POST", "/api/v1/follows", larry, map[string]string{"url": feed.URL}), http.StatusCreated)
+		expectStatus(t, call(t, server, "POST", "/api/v1/follows", larry, map[string]string{"url": feed.URL}), http.StatusConflict)
+
+		var follows apiPage[apiFollow]
+		res = call(t, server, "GET", "/api/v1/follows", larry, nil)
+		res.decode(t, &follows)
+		if len(follows.Items) != 1 || follows.Items[0].FeedID != feed.ID {
+			t.Fatalf("unexpected follows: %+v", follows)
+		}
+		expectStatus(t, call(t, server, "DELETE", "/api/v1/follows/"+feed.ID, larry, nil), http.StatusNoContent)
+		expectStatus(t, call(t, server, "DELETE", "/api/v1/follows/not-a-uuid", larry, nil), http.StatusBadRequest)
+		follows = apiPage[apiFollow]{}
+		call(t, server, "GET", "/api/v1/follows", larry, nil).decode(t, &follows)
+		if len(follows.Items) != 0 {
+			t.Fatalf("expected no follows after unfollowing, got %+v", follows)
+		}
+	})
+}
+
+func TestAPIPosts(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		server := testAPI(t, db)
+
+		expectStatus(t, call(t, server, "POST", "/api/v1/users", "", map[string]string{"name": "david", "password": "correct horse"}), http.StatusCreated)
+		david, reader := testToken(t, db, "david", tokenScopeWrite), testToken(t, db, "david", tokenScopeRead)
+		var feed apiFeed
+		res := call(t, server, "POST", "/api/v1/feeds", david, map[string]string{"name": "Blog", "url": "https://example.com/feed.xml"})
+		expectStatus(t, res, http.StatusCreated)
+		res.decode(t, &feed)
+		feedID := uuid.MustParse(feed.ID)
+
+		published := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i := 0; i < 5; i++ {
+			params := database.CreatePostParams{
+				ID:          uuid.New(),
+				CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+				UpdatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+				Title:       fmt.Sprintf("Post %d", i),
+				Url:         fmt.Sprintf("https://example.com/%d", i),
+				Description: "<p>Hello</p>",
+				PublishedAt: sql.NullTime{Time: published.Add(time.Duration(i) * time.Hour), Valid: true},
+				FeedID:      feedID,
+			}
+			if _, err := db.CreatePost(context.Background(), params); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		titles := func(page apiPage[postRecord]) []string {
+			names := []string{}
+			for _, post := range page.Items {
+				names = append(names, post.Title)
+			}
+			return names
+		}
+
+		var page apiPage[postRecord]
+		res = call(t, server, "GET", "/api/v1/posts?limit=2", david, nil)
+		expectStatus(t, res, http.StatusOK)
+		res.decode(t, &page)
+		if got := titles(page); !reflect.DeepEqual(got, []string{"Post 4", "Post 3"}) || page.NextCursor == "" {
+			t.Fatalf("unexpected first page %v (cursor %q)", got, page.NextCursor)
+		}
+		cursor := page.NextCursor
+		page = apiPage[postRecord]{}
+		call(t, server, "GET", "/api/v1/posts?limit=2&cursor="+cursor, david, nil).decode(t, &page)
+		if got := titles(page); !reflect.DeepEqual(got, []string{"Post 2", "Post 1"}) {
+			t.Fatalf("unexpected second page %v", got)
+		}
+
+		first := page.Items[0].ID
+		expectStatus(t, call(t, server, "PUT", "/api/v1/posts/"+first+"/read", david, nil), http.StatusNoContent)
+		expectStatus(t, call(t, server, "PUT", "/api/v1/posts/"+first+"/star", david, nil), http.StatusNoContent)
+		expectStatus(t, call(t, server, "PUT", "/api/v1/posts/"+uuid.NewString()+"/read", david, nil), http.StatusNotFound)
+
+		page = apiPage[postRecord]{}
+		call(t, server, "GET", "/api/v1/posts?unread=true", david, nil).decode(t, &page)
+		if got := titles(page); !reflect.DeepEqual(got, []string{"Post 4", "Post 3", "Post 1", "Post 0"}) {
+			t.Fatalf("unexpected unread posts %v", got)
+		}
+		page = apiPage[postRecord]{}
+		call(t, server, "GET", "/api/v1/posts?starred=true", david, nil).decode(t, &page)
+		if len(page.Items) != 1 || page.Items[0].ID != first || !page.Items[0].Read || !page.Items[0].Starred {
+			t.Fatalf("unexpected starred posts %+v", page.Items)
+		}
+
+		expectStatus(t, call(t, server, "DELETE", "/api/v1/posts/"+first+"/read", reader, nil), http.StatusForbidden)
+		expectStatus(t, call(t, server, "DELETE", "/api/v1/posts/"+first+"/read", david, nil), http.StatusNoContent)
+		page = apiPage[postRecord]{}
+		call(t, server, "GET", "/api/v1/posts?unread=true&limit=10", reader, nil).decode(t, &page)
+		if len(page.Items) != 5 {
+			t.Fatalf("expected every post to be unread again, got %v", titles(page))
+		}
+
+		page = apiPage[postRecord]{}
+		call(t, server, "GET", "/api/v1/posts?where="+url.QueryEscape(`post.title == "Post 3"`), david, nil).decode(t, &page)
+		if got := titles(page); !reflect.DeepEqual(got, []string{"Post 3"}) {
+			t.Fatalf("unexpected where results %v", got)
+		}
+		expectStatus(t, call(t, server, "GET", "/api/v1/posts?where="+url.QueryEscape("post.nope"), david, nil), http.StatusBadRequest)
+		expectStatus(t, call(t, server, "GET", "/api/v1/posts?bogus=1", david, nil), http.StatusBadRequest)
+
+		// Only the timeline takes a token in the URL, and only a read token.
+		expectStatus(t, call(t, server, "GET", "/api/v1/posts?token="+url.QueryEscape(reader), "", nil), http.StatusUnauthorized)
+		expectStatus(t, call(t, server, "PUT", "/api/v1/posts/"+first+"/read?token="+url.QueryEscape(david), "", nil), http.StatusUnauthorized)
+		expectStatus(t, call(t, server, "GET", "/api/v1/timeline?token="+url.QueryEscape(david), "", nil), http.StatusForbidden)
+		res = call(t, server, "GET", "/api/v1/timeline?format=atom&token="+url.QueryEscape(reader), "", nil)
+		expectStatus(t, res, http.StatusOK)
+		if contentType := res.header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/atom+xml") {
+			t.Errorf("got content type %q", contentType)
+		}
+		var atom struct {
+			Entries []struct {
+				Title string `xml:"title"`
+			} `xml:"entry"`
+		}
+		if err := xml.Unmarshal(res.body, &atom); err != nil {
+			t.Fatal(err)
+		}
+		if len(atom.Entries) != 5 || atom.Entries[0].Title != "Post 4" {
+			t.Fatalf("unexpected timeline entries %+v", atom.Entries)
+		}
+
+		var timeline rss.RSSFeed
+		res = call(t, server, "GET", "/api/v1/timeline", reader, nil)
+		if err := xml.Unmarshal(res.body, &timeline); err != nil {
+			t.Fatal(err)
+		}
+		if timeline.Channel.Link != server.URL+"/" {
+			t.Errorf("the timeline should link to the server, got %q", timeline.Channel.Link)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+-- name: CreateAPIToken :exec
+INSERT INTO api_tokens (id, created_at, name, token_hash, scope, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens WHERE user_id = $1 AND name = $2;
+
+-- name: GetUserByAPIToken :one
+SELECT sqlc.embed(users), api_tokens.id AS token_id, api_tokens.scope
+FROM api_tokens
+INNER JOIN users
+ON users.id = api_tokens.user_id
+WHERE api_tokens.token_hash = $1
+AND (api_tokens.expires_at IS NULL OR api_tokens.expires_at > CURRENT_TIMESTAMP);
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens SET last_used_at = $2 WHERE id = $1;
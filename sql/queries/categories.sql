@@ -0,0 +1,35 @@
+-- name: CreateCategory :one
+INSERT INTO categories (id, created_at, updated_at, name, user_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetCategoriesForUser :many
+SELECT * FROM categories WHERE user_id = $1 ORDER BY name;
+
+-- name: RenameCategory :execrows
+UPDATE categories
+SET name = sqlc.arg('new_name'), updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg('user_id') AND name = sqlc.arg('name');
+
+-- name: DeleteCategory :execrows
+DELETE FROM categories WHERE user_id = $1 AND name = $2;
+
+-- name: SetFeedFollowCategory :execrows
+UPDATE feed_follows
+SET category_id = categories.id, updated_at = CURRENT_TIMESTAMP
+FROM categories
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND feed_follows.feed_id = sqlc.arg('feed_id')
+AND categories.user_id = feed_follows.user_id
+AND categories.name = sqlc.arg('category');
+
+-- name: ClearFeedFollowCategory :execrows
+UPDATE feed_follows
+SET category_id = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2;
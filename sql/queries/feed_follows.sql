@@ -30,11 +30,17 @@ DELETE FROM feed_follows WHERE user_id = $1 AND feed_id = $2;
 -- name: GetFeedFollowsForUser :many
 SELECT
     feed_follows.id,
+    feed_follows.feed_id,
     feeds.name AS feed_name,
-    users.name AS user_name
+    feeds.url AS feed_url,
+    users.name AS user_name,
+    categories.name AS category_name
 FROM feed_follows
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
 INNER JOIN users
 ON feed_follows.user_id = users.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN categories
+ON feed_follows.category_id = categories.id
+WHERE feed_follows.user_id = $1
+ORDER BY categories.name NULLS LAST, feeds.name;
@@ -0,0 +1,31 @@
+-- name: SetFeedIcon :exec
+INSERT INTO feed_icons (feed_id, fetched_at, source_url, content_type, data, hash)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (feed_id)
+DO UPDATE SET
+    fetched_at = EXCLUDED.fetched_at,
+    source_url = EXCLUDED.source_url,
+    content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data,
+    hash = EXCLUDED.hash;
+
+-- name: GetFeedIcon :one
+SELECT * FROM feed_icons WHERE feed_id = $1 AND hash <> '';
+
+-- name: GetFeedIconFetchedAt :one
+SELECT fetched_at FROM feed_icons WHERE feed_id = $1;
+
+-- name: GetFeedIconsForUser :many
+SELECT feed_icons.* FROM feed_icons
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feed_icons.feed_id
+WHERE feed_follows.user_id = $1
+AND feed_icons.hash <> ''
+ORDER BY feed_icons.feed_id;
+
+-- name: GetFeedIconHashesForUser :many
+SELECT feed_icons.feed_id, feed_icons.hash FROM feed_icons
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feed_icons.feed_id
+WHERE feed_follows.user_id = $1
+AND feed_icons.hash <> '';
@@ -14,7 +14,7 @@ RETURNING *;
 DELETE FROM feeds;
 
 -- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds;
+SELECT name, url, user_id, title, site_url, description FROM feeds;
 
 -- name: GetFeedID :one
 SELECT id FROM feeds WHERE url = $1;
@@ -28,3 +28,50 @@ WHERE id = $1;
 SELECT id, name, url FROM feeds
 ORDER BY last_fetched_at NULLS FIRST
 LIMIT 1;
+
+-- name: ListFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.name, feeds.url, feeds.last_fetched_at, users.name AS user_name
+FROM feeds
+INNER JOIN users ON users.id = feeds.user_id
+ORDER BY feeds.created_at, feeds.id
+LIMIT $1 OFFSET $2;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = sqlc.arg('to_user_id'), updated_at = sqlc.arg('updated_at')
+WHERE user_id = sqlc.arg('from_user_id');
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds WHERE url = $1;
+
+-- name: SetFeedLastError :exec
+UPDATE feeds
+SET last_error = $2
+WHERE id = $1;
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: GetFeedFollowerNames :many
+SELECT users.name FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY users.name;
+
+-- name: GetFeedPostStats :one
+SELECT
+    COUNT(*) AS posts,
+    COALESCE(MIN(published_at), '1970-01-01')::timestamp AS oldest_published_at,
+    COALESCE(MAX(published_at), '1970-01-01')::timestamp AS newest_published_at
+FROM posts
+WHERE feed_id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, site_url = $3, description = $4, language = $5, image_url = $6, generator = $7
+WHERE id = $1;
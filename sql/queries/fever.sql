@@ -0,0 +1,71 @@
+-- name: SetFeverAPIKey :exec
+INSERT INTO fever_api_keys (user_id, created_at, api_key)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id)
+DO UPDATE SET created_at = EXCLUDED.created_at, api_key = EXCLUDED.api_key;
+
+-- name: DeleteFeverAPIKey :execrows
+DELETE FROM fever_api_keys WHERE user_id = $1;
+
+-- name: GetUserByFeverAPIKey :one
+SELECT users.* FROM users
+INNER JOIN fever_api_keys
+ON fever_api_keys.user_id = users.id
+WHERE fever_api_keys.api_key = $1;
+
+-- name: GetFeverItems :many
+SELECT
+    posts.seq,
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.content,
+    posts.author,
+    posts.published_at,
+    posts.feed_id,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND posts.seq > sqlc.arg('since_id')
+AND (sqlc.arg('max_id')::bigint = 0 OR posts.seq < sqlc.arg('max_id'))
+AND (sqlc.narg('with_ids')::bigint[] IS NULL OR posts.seq = ANY(sqlc.narg('with_ids')::bigint[]))
+ORDER BY
+    CASE WHEN sqlc.arg('max_id')::bigint > 0 THEN posts.seq END DESC,
+    posts.seq ASC
+LIMIT sqlc.arg('item_limit');
+
+-- name: GetFeverItemIDs :many
+SELECT posts.seq
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND (NOT sqlc.arg('unread_only')::bool OR post_states.read_at IS NULL)
+AND (NOT sqlc.arg('starred_only')::bool OR post_states.starred_at IS NOT NULL)
+ORDER BY posts.seq;
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: MarkFeedsReadBefore :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, CURRENT_TIMESTAMP
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND posts.feed_id = ANY(sqlc.arg('feed_ids')::uuid[])
+AND posts.published_at < sqlc.arg('before')
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET read_at = COALESCE(post_states.read_at, CURRENT_TIMESTAMP);
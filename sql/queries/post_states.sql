@@ -0,0 +1,29 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET read_at = COALESCE(post_states.read_at, CURRENT_TIMESTAMP);
+
+-- name: MarkPostUnread :exec
+UPDATE post_states SET read_at = NULL
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: StarPost :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET starred_at = COALESCE(post_states.starred_at, CURRENT_TIMESTAMP);
+
+-- name: UnstarPost :exec
+UPDATE post_states SET starred_at = NULL
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, COUNT(*) AS unread, COALESCE(MAX(posts.published_at), '1970-01-01')::timestamp AS newest_published_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.read_at IS NULL
+GROUP BY posts.feed_id;
@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories)
 VALUES (
     $1,
     $2,
@@ -8,7 +8,10 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
 RETURNING *;
 
@@ -19,3 +22,49 @@ ON feed_follows.feed_id = posts.feed_id
 WHERE feed_follows.user_id = $1
 ORDER BY published_at DESC
 LIMIT $2;
+
+-- name: BrowsePostsForUser :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    posts.content,
+    posts.author,
+    posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    categories.name AS category_name,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN categories
+ON categories.id = feed_follows.category_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND (sqlc.narg('feed')::text IS NULL OR feeds.url = sqlc.narg('feed') OR feeds.name = sqlc.narg('feed'))
+AND (sqlc.narg('category')::text IS NULL OR categories.name = sqlc.narg('category'))
+AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until'))
+AND (NOT sqlc.arg('unread_only')::bool OR post_states.read_at IS NULL)
+AND (NOT sqlc.arg('starred_only')::bool OR post_states.starred_at IS NOT NULL)
+AND (
+    sqlc.narg('cursor_published_at')::timestamp IS NULL
+    OR (sqlc.arg('oldest_first')::bool AND (posts.published_at, posts.id) > (sqlc.narg('cursor_published_at'), sqlc.narg('cursor_id')::uuid))
+    OR (NOT sqlc.arg('oldest_first')::bool AND (posts.published_at, posts.id) < (sqlc.narg('cursor_published_at'), sqlc.narg('cursor_id')::uuid))
+)
+AND (sqlc.narg('item_ids')::text[] IS NULL OR substr(replace(posts.id::text, '-', ''), 1, 16) = ANY(sqlc.narg('item_ids')::text[]))
+ORDER BY
+    CASE WHEN sqlc.arg('oldest_first')::bool THEN posts.published_at END ASC,
+    CASE WHEN sqlc.arg('oldest_first')::bool THEN posts.id END ASC,
+    CASE WHEN NOT sqlc.arg('oldest_first')::bool THEN posts.published_at END DESC,
+    CASE WHEN NOT sqlc.arg('oldest_first')::bool THEN posts.id END DESC
+LIMIT sqlc.arg('post_limit')
+OFFSET sqlc.arg('post_offset');
@@ -0,0 +1,74 @@
+-- name: GetRetentionSettings :one
+SELECT * FROM retention_settings WHERE id = 1;
+
+-- name: SetRetentionSettings :exec
+UPDATE retention_settings
+SET updated_at = $1, max_age_days = $2, max_posts = $3, keep_latest = $4
+WHERE id = 1;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_days = $2, retention_max_posts = $3, updated_at = $4
+WHERE id = $1;
+
+-- name: GetFeedRetentions :many
+SELECT name, url, retention_max_age_days, retention_max_posts FROM feeds
+WHERE retention_max_age_days IS NOT NULL OR retention_max_posts IS NOT NULL
+ORDER BY name;
+
+-- name: GetPurgeablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.feed_id,
+        posts.published_at,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC NULLS LAST, posts.id) AS position
+    FROM posts
+)
+SELECT ranked.id, feeds.name AS feed_name
+FROM ranked
+INNER JOIN feeds
+ON feeds.id = ranked.feed_id
+WHERE ranked.position > sqlc.arg('keep_latest')::int
+AND (
+    (
+        COALESCE(feeds.retention_max_age_days, sqlc.arg('max_age_days')::int) > 0
+        AND ranked.published_at < CURRENT_TIMESTAMP - make_interval(days => COALESCE(feeds.retention_max_age_days, sqlc.arg('max_age_days')::int))
+    )
+    OR (
+        COALESCE(feeds.retention_max_posts, sqlc.arg('max_posts')::int) > 0
+        AND ranked.position > COALESCE(feeds.retention_max_posts, sqlc.arg('max_posts')::int)
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = ranked.id AND post_states.starred_at IS NOT NULL
+)
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    LEFT JOIN post_states
+    ON post_states.post_id = ranked.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.feed_id = ranked.feed_id AND post_states.read_at IS NULL
+)
+ORDER BY feeds.name, ranked.published_at;
+
+-- name: PurgePosts :one
+WITH purged AS (
+    DELETE FROM posts
+    WHERE posts.id = ANY(sqlc.arg('ids')::uuid[])
+    RETURNING posts.url, posts.feed_id
+), recorded AS (
+    INSERT INTO purged_posts (url, feed_id, purged_at)
+    SELECT purged.url, purged.feed_id, sqlc.arg('purged_at')
+    FROM purged
+    ON CONFLICT (url) DO NOTHING
+)
+SELECT COUNT(*) FROM purged;
+
+-- name: GetPurgedPostURLs :many
+SELECT url FROM purged_posts WHERE feed_id = $1;
+
+-- name: PrunePurgedPosts :execrows
+DELETE FROM purged_posts
+WHERE feed_id = sqlc.arg('feed_id')
+AND NOT (url = ANY(sqlc.arg('urls')::text[]));
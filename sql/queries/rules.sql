@@ -0,0 +1,28 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, field, pattern, is_regex, action)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT * FROM rules WHERE user_id = $1 ORDER BY created_at;
+
+-- name: GetRulesForFeedFollowers :many
+SELECT rules.*, categories.name AS category_name FROM rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = rules.user_id
+LEFT JOIN categories
+ON feed_follows.category_id = categories.id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.created_at;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules WHERE id = $1 AND user_id = $2;
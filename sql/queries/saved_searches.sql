@@ -0,0 +1,20 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, created_at, updated_at, name, query, user_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetSavedSearchesForUser :many
+SELECT * FROM saved_searches WHERE user_id = $1 ORDER BY name;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches WHERE user_id = $1 AND name = $2;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches WHERE user_id = $1 AND name = $2;
@@ -0,0 +1,16 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (id, created_at, expires_at, token_hash, user_id)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetUserBySession :one
+SELECT users.* FROM users
+INNER JOIN sessions
+ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1
+AND sessions.expires_at > CURRENT_TIMESTAMP;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions WHERE token_hash = $1;
+
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions WHERE user_id = $1;
@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    sqlc.arg(can_be_admin) AND NOT EXISTS (SELECT 1 FROM users WHERE is_admin)
 )
 RETURNING *;
 
@@ -22,3 +24,37 @@ DELETE FROM users;
 
 -- name: GetUsers :many
 SELECT name FROM users;
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY name
+LIMIT $1 OFFSET $2;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $2, updated_at = $3
+WHERE name = $1;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE is_admin;
+
+-- name: RenameUser :execrows
+UPDATE users
+SET name = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follows,
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feeds_added,
+    (SELECT COUNT(*) FROM post_states WHERE post_states.user_id = $1 AND post_states.read_at IS NOT NULL) AS read_posts,
+    (SELECT COUNT(*) FROM post_states WHERE post_states.user_id = $1 AND post_states.starred_at IS NOT NULL) AS starred_posts,
+    (SELECT COUNT(*) FROM api_tokens WHERE api_tokens.user_id = $1) AS api_tokens;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN seq BIGSERIAL NOT NULL UNIQUE;
+
+CREATE TABLE fever_api_keys (
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP,
+    api_key TEXT NOT NULL UNIQUE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE fever_api_keys;
+
+ALTER TABLE posts
+DROP COLUMN seq;
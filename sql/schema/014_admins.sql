@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Nobody is made an admin here: users from before passwords existed have
+-- none yet, so promoting one would hand admin rights to whoever logs in as
+-- them first. The next user registered with the CLI becomes the admin
+-- instead; users created through the API never do.
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN is_admin;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE retention_settings (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    updated_at TIMESTAMP,
+    max_age_days INTEGER NOT NULL DEFAULT 0,
+    max_posts INTEGER NOT NULL DEFAULT 0,
+    keep_latest INTEGER NOT NULL DEFAULT 20
+);
+
+INSERT INTO retention_settings (id) VALUES (1);
+
+ALTER TABLE feeds
+ADD COLUMN retention_max_age_days INTEGER,
+ADD COLUMN retention_max_posts INTEGER;
+
+CREATE TABLE purged_posts (
+    url TEXT PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    purged_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE purged_posts;
+
+ALTER TABLE feeds
+DROP COLUMN retention_max_age_days,
+DROP COLUMN retention_max_posts;
+
+DROP TABLE retention_settings;
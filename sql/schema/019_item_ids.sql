@@ -0,0 +1,7 @@
+-- +goose Up
+-- Google Reader item ids are the first 64 bits of a post's UUID in hex.
+-- BrowsePostsForUser looks posts up by them with this same expression.
+CREATE INDEX posts_item_id_idx ON posts ((substr(replace(id::text, '-', ''), 1, 16)));
+
+-- +goose Down
+DROP INDEX posts_item_id_idx;
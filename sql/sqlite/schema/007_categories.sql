@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE categories (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+ALTER TABLE feed_follows
+ADD COLUMN category_id TEXT REFERENCES categories (id) ON DELETE SET NULL;
+
+-- +goose Down
+-- SQLite can't drop a column with a foreign key, so the table is rebuilt.
+CREATE TABLE feed_follows_old (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    user_id TEXT NOT NULL,
+    feed_id TEXT NOT NULL,
+    UNIQUE (user_id, feed_id),
+    FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+INSERT INTO feed_follows_old (id, created_at, updated_at, user_id, feed_id)
+SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows;
+
+DROP TABLE feed_follows;
+
+ALTER TABLE feed_follows_old RENAME TO feed_follows;
+
+DROP TABLE categories;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN author TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN categories TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN content;
+ALTER TABLE posts DROP COLUMN author;
+ALTER TABLE posts DROP COLUMN categories;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE saved_searches (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE saved_searches;
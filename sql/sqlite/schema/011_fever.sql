@@ -0,0 +1,23 @@
+-- +goose Up
+-- SQLite has no sequences. CreatePost numbers new posts itself.
+ALTER TABLE posts
+ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts SET seq = rowid;
+
+CREATE UNIQUE INDEX posts_seq_key ON posts (seq);
+
+CREATE TABLE fever_api_keys (
+    user_id TEXT PRIMARY KEY,
+    created_at TIMESTAMP,
+    api_key TEXT NOT NULL UNIQUE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE fever_api_keys;
+
+DROP INDEX posts_seq_key;
+
+ALTER TABLE posts
+DROP COLUMN seq;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE feed_icons (
+    feed_id TEXT PRIMARY KEY,
+    fetched_at TIMESTAMP NOT NULL,
+    source_url TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT '',
+    data BLOB NOT NULL DEFAULT x'',
+    hash TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_icons;
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX posts_item_id_idx ON posts (substr(replace(id, '-', ''), 1, 16));
+
+-- +goose Down
+DROP INDEX posts_item_id_idx;
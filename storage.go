@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// The database drivers gator can use, picked by the scheme of db_url.
+const (
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite"
+)
+
+// sqliteOptions turns on foreign keys, which SQLite leaves off by default,
+// and stores times in UTC in the same text format as CURRENT_TIMESTAMP so
+// they compare correctly.
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_timezone=UTC&_time_format=sqlite&_txlock=immediate"
+
+// databaseDriver works out which driver a db_url is for and the data source
+// name to give it. sqlite: URLs name a database file, which is created if it
+// doesn't exist: sqlite:gator.db or sqlite:///home/me/gator.db. Anything else
+// is passed to Postgres as it is.
+func databaseDriver(dbURL string) (string, string, error) {
+	scheme, rest, _ := strings.Cut(dbURL, ":")
+	if scheme != "sqlite" && scheme != "sqlite3" {
+		return driverPostgres, dbURL, nil
+	}
+	path := strings.TrimPrefix(rest, "//")
+	if path == "" || strings.ContainsAny(path, "?#") {
+		return "", "", fmt.Errorf("db_url %q needs a file path after sqlite:.", dbURL)
+	}
+	return driverSQLite, "file:" + path + "?" + sqliteOptions, nil
+}
+
+// openDatabase opens the database in db_url and returns it with the name of
+// its driver.
+func openDatabase(dbURL string) (*sql.DB, string, error) {
+	driver, dataSource, err := databaseDriver(dbURL)
+	if err != nil {
+		return nil, "", err
+	}
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, driver, nil
+}
+
+// newQueries returns the queries written for the database's driver.
+func newQueries(db *sql.DB, driver string) database.Querier {
+	if driver == driverSQLite {
+		return database.NewSQLite(db)
+	}
+	return database.New(db)
+}
+
+// inQueryTransaction runs f with queries that share one transaction, so
+// either all of its changes are saved or none are.
+func inQueryTransaction(s *state, f func(db database.Querier) error) error {
+	db, ok := s.db.(database.Transactor)
+	if !ok {
+		return errors.New("The database can't run transactions.")
+	}
+	return db.InTx(context.Background(), f)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDatabaseDriver(t *testing.T) {
+	tests := []struct {
+		url        string
+		driver     string
+		dataSource string
+	}{
+		{"postgres://david:@localhost:5432/gator", driverPostgres, "postgres://david:@localhost:5432/gator"},
+		{"host=localhost dbname=gator", driverPostgres, "host=localhost dbname=gator"},
+		{"sqlite:gator.db", driverSQLite, "file:gator.db?"},
+		{"sqlite:///home/david/gator.db", driverSQLite, "file:/home/david/gator.db?"},
+		{"sqlite:", "", ""},
+		{"sqlite:gator.db?mode=ro", "", ""},
+	}
+	for _, tt := range tests {
+		driver, dataSource, err := databaseDriver(tt.url)
+		if tt.driver == "" {
+			if err == nil {
+				t.Errorf("databaseDriver(%q) should fail", tt.url)
+			}
+			continue
+		}
+		if err != nil || driver != tt.driver || !strings.HasPrefix(dataSource, tt.dataSource) {
+			t.Errorf("databaseDriver(%q) = %q, %q, %v, want %q, %q...", tt.url, driver, dataSource, err, tt.driver, tt.dataSource)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const tokenUsage = "usage: token create <name> [--scope read|write] [--expires 90d] | list | revoke <name>"
+
+// API tokens are for scripts and the API server. A read token can only look
+// at data; a write token can do anything the user can. The CLI uses the
+// token in GATOR_TOKEN instead of the login session when it's set.
+const (
+	tokenEnv        = "GATOR_TOKEN"
+	tokenPrefix     = "gator_"
+	tokenScopeRead  = "read"
+	tokenScopeWrite = "write"
+)
+
+type tokenRecord struct {
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  *time.Time `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// handlerToken manages the current user's API tokens. The token itself is
+// only printed when it's created; gator keeps just its hash.
+func handlerToken(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 1 {
+		return invalidArgs(tokenUsage)
+	}
+	subcommand, args := cmd.arguments[0], cmd.arguments[1:]
+	switch {
+	case subcommand == "create" && len(args) >= 1:
+		return tokenCreate(s, user, args[0], args[1:])
+	case subcommand == "list" && len(args) == 0:
+		return tokenList(s, user)
+	case subcommand == "revoke" && len(args) == 1:
+		return tokenRevoke(s, user, args[0])
+	}
+	return invalidArgs(tokenUsage)
+}
+
+func tokenCreate(s *state, user database.User, name string, args []string) error {
+	if strings.HasPrefix(name, "-") {
+		return invalidArgs(tokenUsage)
+	}
+	flags := flag.NewFlagSet("token create", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	scope := flags.String("scope", tokenScopeWrite, "read or write")
+	expires := flags.String("expires", "", "how long the token lasts, like 90d or 12h (default: forever)")
+	if err := flags.Parse(args); err != nil {
+		return invalidArgs("%v\n%v", err, tokenUsage)
+	}
+	if flags.NArg() > 0 {
+		return invalidArgs("unexpected argument %q\n%v", flags.Arg(0), tokenUsage)
+	}
+	if *scope != tokenScopeRead && *scope != tokenScopeWrite {
+		return invalidArgs("invalid scope %q (use read or write)", *scope)
+	}
+	var lifetime time.Duration
+	if *expires != "" {
+		var err error
+		lifetime, err = parseLifetime(*expires)
+		if err != nil {
+			return err
+		}
+	}
+	token, err := createAPIToken(context.Background(), s.db, user, name, *scope, lifetime)
+	if isUniqueViolation(err) {
+		return alreadyExists("%v already has a token named %v.", user.Name, name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Token created: %v (%v)\n", name, *scope)
+	fmt.Println(token)
+	fmt.Println("Copy it now, it won't be shown again.")
+	return nil
+}
+
+// createAPIToken stores a new token for user and returns it. A zero lifetime
+// never expires.
+func createAPIToken(ctx context.Context, db database.Querier, user database.User, name, scope string, lifetime time.Duration) (string, error) {
+	token := tokenPrefix + randomToken()
+	expiresAt := sql.NullTime{}
+	if lifetime > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().Add(lifetime).UTC(), Valid: true}
+	}
+	params := database.CreateAPITokenParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, TokenHash: hashToken(token), Scope: scope, ExpiresAt: expiresAt, UserID: user.ID}
+	if err := db.CreateAPIToken(ctx, params); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func tokenList(s *state, user database.User) error {
+	tokens, err := s.db.GetAPITokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if s.output != outputText {
+		records := []tokenRecord{}
+		for _, token := range tokens {
+			records = append(records, tokenRecord{
+				Name:       token.Name,
+				Scope:      token.Scope,
+				CreatedAt:  nullTime(token.CreatedAt.Valid, token.CreatedAt.Time),
+				ExpiresAt:  nullTime(token.ExpiresAt.Valid, token.ExpiresAt.Time),
+				LastUsedAt: nullTime(token.LastUsedAt.Valid, token.LastUsedAt.Time),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+	for _, token := range tokens {
+		expires, used := "never expires", "never used"
+		if token.ExpiresAt.Valid {
+			expires = "expires " + token.ExpiresAt.Time.Local().Format("2006-01-02")
+			if token.ExpiresAt.Time.Before(time.Now()) {
+				expires = "expired " + token.ExpiresAt.Time.Local().Format("2006-01-02")
+			}
+		}
+		if token.LastUsedAt.Valid {
+			used = "last used " + token.LastUsedAt.Time.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("* %v (%v, %v, %v)\n", token.Name, token.Scope, expires, used)
+	}
+	return nil
+}
+
+func tokenRevoke(s *state, user database.User, name string) error {
+	removed, err := s.db.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{UserID: user.ID, Name: name})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return notFound("No token named %v.", name)
+	}
+	fmt.Printf("Token revoked: %v\n", name)
+	return nil
+}
+
+// userByAPIToken looks up the user a token belongs to and notes that the
+// token was used.
+func userByAPIToken(ctx context.Context, db database.Querier, token string) (database.User, string, error) {
+	row, err := db.GetUserByAPIToken(ctx, hashToken(token))
+	if err != nil {
+		return database.User{}, "", err
+	}
+	params := database.TouchAPITokenParams{ID: row.TokenID, LastUsedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	if err := db.TouchAPIToken(ctx, params); err != nil {
+		return database.User{}, "", err
+	}
+	return row.User, row.Scope, nil
+}
+
+// parseLifetime is time.ParseDuration plus a "d" suffix for days.
+func parseLifetime(value string) (time.Duration, error) {
+	invalid := invalidArgs("invalid expiry %q (use a duration like 90d or 12h)", value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, invalid
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, invalid
+	}
+	return d, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+)
+
+const userUsage = "usage: user info [name] | rename <name> <new name> | passwd <name> | delete <name> [--transfer-to <name>] [--yes]"
+
+type userInfoRecord struct {
+	Name         string     `json:"name"`
+	ID           string     `json:"id"`
+	Admin        bool       `json:"admin"`
+	CreatedAt    *time.Time `json:"created_at"`
+	Follows      int64      `json:"follows"`
+	FeedsAdded   int64      `json:"feeds_added"`
+	ReadPosts    int64      `json:"read_posts"`
+	StarredPosts int64      `json:"starred_posts"`
+	APITokens    int64      `json:"api_tokens"`
+}
+
+// handlerUser manages user accounts. Anyone can look at or rename their own
+// account; admins can do it for any user, and only admins can set another
+// user's password or delete one.
+func handlerUser(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 1 {
+		return invalidArgs(userUsage)
+	}
+	subcommand, args := cmd.arguments[0], cmd.arguments[1:]
+	switch {
+	case subcommand == "info" && len(args) == 0:
+		return userInfo(s, user, user.Name)
+	case subcommand == "info" && len(args) == 1:
+		return userInfo(s, user, args[0])
+	case subcommand == "rename" && len(args) == 2:
+		return userRename(s, user, args[0], args[1])
+	case subcommand == "passwd" && len(args) == 1:
+		return userPasswd(s, user, args[0])
+	case subcommand == "delete" && len(args) >= 1:
+		return userDelete(s, user, args[0], args[1:])
+	}
+	return invalidArgs(userUsage)
+}
+
+// lookupUser finds the named user, as long as user may manage them.
+func lookupUser(s *state, user database.User, name string) (database.User, error) {
+	if name != user.Name && !user.IsAdmin {
+		return database.User{}, unauthorized("Only admins can manage other users.")
+	}
+	target, err := s.db.GetUser(context.Background(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, notFound("No user named %v.", name)
+	}
+	return target, err
+}
+
+func userInfo(s *state, user database.User, name string) error {
+	target, err := lookupUser(s, user, name)
+	if err != nil {
+		return err
+	}
+	stats, err := s.db.GetUserStats(context.Background(), target.ID)
+	if err != nil {
+		return err
+	}
+	record := userInfoRecord{
+		Name:         target.Name,
+		ID:           target.ID.String(),
+		Admin:        target.IsAdmin,
+		CreatedAt:    nullTime(target.CreatedAt.Valid, target.CreatedAt.Time),
+		Follows:      stats.Follows,
+		FeedsAdded:   stats.FeedsAdded,
+		ReadPosts:    stats.ReadPosts,
+		StarredPosts: stats.StarredPosts,
+		APITokens:    stats.ApiTokens,
+	}
+	if s.output != outputText {
+		return writeRecords(os.Stdout, s.output, []userInfoRecord{record})
+	}
+	fmt.Printf("Name:          %v\n", record.Name)
+	fmt.Printf("ID:            %v\n", record.ID)
+	fmt.Printf("Admin:         %v\n", record.Admin)
+	if record.CreatedAt != nil {
+		fmt.Printf("Created:       %v\n", record.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("Following:     %d feeds\n", record.Follows)
+	fmt.Printf("Feeds added:   %d\n", record.FeedsAdded)
+	fmt.Printf("Read posts:    %d\n", record.ReadPosts)
+	fmt.Printf("Starred posts: %d\n", record.StarredPosts)
+	fmt.Printf("API tokens:    %d\n", record.APITokens)
+	return nil
+}
+
+func userRename(s *state, user database.User, name, newName string) error {
+	target, err := lookupUser(s, user, name)
+	if err != nil {
+		return err
+	}
+	if newName == "" {
+		return invalidArgs("The new name can't be empty.")
+	}
+	// The Fever key is derived from the user name, so it can't survive the
+	// rename.
+	var feverKeys int64
+	err = inQueryTransaction(s, func(db database.Querier) error {
+		params := database.RenameUserParams{ID: target.ID, Name: newName, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+		if _, err := db.RenameUser(context.Background(), params); err != nil {
+			return err
+		}
+		feverKeys, err = db.DeleteFeverAPIKey(context.Background(), target.ID)
+		return err
+	})
+	if isUniqueViolation(err) {
+		return alreadyExists("There's already a user named %v.", newName)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User renamed: %v -> %v\n", name, newName)
+	if feverKeys > 0 {
+		fmt.Printf("The Fever password for %v stopped working. Set a new one with: gator fever-key\n", newName)
+	}
+	return nil
+}
+
+// userPasswd lets an admin set someone else's password, which is the only way
+// users from before passwords existed can get one. Their sessions are logged
+// out.
+func userPasswd(s *state, user database.User, name string) error {
+	if !user.IsAdmin {
+		return unauthorized("Only admins can set other users' passwords.")
+	}
+	if name == user.Name {
+		return invalidArgs("Change your own password with: gator passwd")
+	}
+	target, err := lookupUser(s, user, name)
+	if err != nil {
+		return err
+	}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := storePassword(context.Background(), s.db, target, password); err != nil {
+		return err
+	}
+	fmt.Printf("Password set for %v. Their sessions have been logged out.\n", target.Name)
+	return nil
+}
+
+// userDelete removes a user and everything that is only theirs: follows,
+// read and starred state, categories, rules, saved searches and tokens.
+// Feeds they added may be followed by others, so those go to another user
+// instead, the admin running the command unless --transfer-to says who.
+func userDelete(s *state, user database.User, name string, args []string) error {
+	if !user.IsAdmin {
+		return unauthorized("Only admins can delete users.")
+	}
+	args, yes := takeYes(args)
+	flags := flag.NewFlagSet("user delete", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	transferTo := flags.String("transfer-to", "", "user who takes over the deleted user's feeds")
+	if err := flags.Parse(args); err != nil {
+		return invalidArgs("%v\n%v", err, userUsage)
+	}
+	if flags.NArg() > 0 {
+		return invalidArgs("unexpected argument %q\n%v", flags.Arg(0), userUsage)
+	}
+
+	target, err := lookupUser(s, user, name)
+	if err != nil {
+		return err
+	}
+	if target.IsAdmin {
+		admins, err := s.db.CountAdmins(context.Background())
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return unauthorized("Can't delete the last admin.")
+		}
+	}
+	recipient := user
+	if *transferTo != "" {
+		recipient, err = s.db.GetUser(context.Background(), *transferTo)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFound("No user named %v.", *transferTo)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if recipient.ID == target.ID {
+		return invalidArgs("Use --transfer-to to choose who takes over %v's feeds.", target.Name)
+	}
+
+	stats, err := s.db.GetUserStats(context.Background(), target.ID)
+	if err != nil {
+		return err
+	}
+	warning := fmt.Sprintf("This deletes %v with their follows, read and starred posts, categories, rules, saved searches and tokens. The %d feeds they added go to %v.", target.Name, stats.FeedsAdded, recipient.Name)
+	if err := confirmAction(yes, warning); err != nil {
+		return err
+	}
+
+	// The feeds only move if the user is really deleted.
+	var moved int64
+	err = inQueryTransaction(s, func(db database.Querier) error {
+		transferParams := database.TransferFeedsParams{ToUserID: recipient.ID, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, FromUserID: target.ID}
+		moved, err = db.TransferFeeds(context.Background(), transferParams)
+		if err != nil {
+			return err
+		}
+		_, err = db.DeleteUser(context.Background(), target.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if target.ID == user.ID {
+		if err := s.config.SetSession(""); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("User deleted: %v (%d feeds transferred to %v)\n", target.Name, moved, recipient.Name)
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// testUser creates a user directly in the database, the way register does.
+func testUser(t *testing.T, db database.Querier, name string) database.User {
+	t.Helper()
+	params := database.CreateUserParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, CanBeAdmin: true}
+	user, err := db.CreateUser(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestUserDeleteTransfersFeeds(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		s := &state{db: db, output: outputText}
+		david, larry, susan := testUser(t, db, "david"), testUser(t, db, "larry"), testUser(t, db, "susan")
+		if !david.IsAdmin || larry.IsAdmin {
+			t.Fatalf("only the first user should be an admin")
+		}
+
+		if err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"Blog", "https://example.com/feed.xml"}}, larry); err != nil {
+			t.Fatal(err)
+		}
+		if err := handlerFollow(s, command{name: "follow", arguments: []string{"https://example.com/feed.xml"}}, susan); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := handlerUser(s, command{name: "user", arguments: []string{"delete", "larry", "--yes"}}, susan); err == nil {
+			t.Fatal("only admins can delete users")
+		}
+		if err := handlerUser(s, command{name: "user", arguments: []string{"delete", "david", "--yes"}}, david); err == nil {
+			t.Fatal("the last admin can't be deleted")
+		}
+		if err := handlerUser(s, command{name: "user", arguments: []string{"delete", "larry", "--transfer-to", "susan", "--yes"}}, david); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.GetUser(context.Background(), "larry"); err != sql.ErrNoRows {
+			t.Fatalf("larry should be gone, got %v", err)
+		}
+		stats, err := db.GetUserStats(context.Background(), susan.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.FeedsAdded != 1 || stats.Follows != 1 {
+			t.Fatalf("susan should own and still follow the feed, got %+v", stats)
+		}
+	})
+}
+
+// failingDelete is a database where deleting a user fails, inside
+// transactions too.
+type failingDelete struct {
+	database.Querier
+}
+
+func (f failingDelete) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	return 0, errors.New("disk full")
+}
+
+func (f failingDelete) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	return f.Querier.(database.Transactor).InTx(ctx, func(db database.Querier) error {
+		return fn(failingDelete{db})
+	})
+}
+
+func TestUserDeleteRollsBack(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		david, larry := testUser(t, db, "david"), testUser(t, db, "larry")
+		s := &state{db: db, output: outputText}
+		if err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"Blog", "https://example.com/feed.xml"}}, larry); err != nil {
+			t.Fatal(err)
+		}
+
+		s.db = failingDelete{db}
+		if err := handlerUser(s, command{name: "user", arguments: []string{"delete", "larry", "--yes"}}, david); err == nil {
+			t.Fatal("the delete should fail")
+		}
+		stats, err := db.GetUserStats(context.Background(), larry.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.FeedsAdded != 1 {
+			t.Errorf("larry should still own the feed after a failed delete, got %+v", stats)
+		}
+	})
+}
+
+func TestUserPermissions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		s := &state{db: db, output: outputText}
+		testUser(t, db, "david")
+		larry := testUser(t, db, "larry")
+		testUser(t, db, "susan")
+
+		if err := handlerUser(s, command{name: "user", arguments: []string{"info", "susan"}}, larry); err == nil {
+			t.Error("users can't look at other accounts")
+		}
+		if err := handlerUser(s, command{name: "user", arguments: []string{"rename", "larry", "susan"}}, larry); err == nil {
+			t.Error("renaming onto an existing name should fail")
+		}
+		if err := handlerUser(s, command{name: "user", arguments: []string{"rename", "larry", "lawrence"}}, larry); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.GetUser(context.Background(), "lawrence"); err != nil {
+			t.Fatalf("rename didn't stick: %v", err)
+		}
+	})
+}
+
+func TestRegisterWithoutAdmins(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		// An install upgraded from before admins existed has users but no admin.
+		testUser(t, db, "david")
+		params := database.SetUserAdminParams{Name: "david", IsAdmin: false, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+		if _, err := db.SetUserAdmin(context.Background(), params); err != nil {
+			t.Fatal(err)
+		}
+		if larry := testUser(t, db, "larry"); !larry.IsAdmin {
+			t.Error("the next user registered should become an admin")
+		}
+		if susan := testUser(t, db, "susan"); susan.IsAdmin {
+			t.Error("only one user should become an admin")
+		}
+	})
+}
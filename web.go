@@ -0,0 +1,431 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/curtisbraxdale/blog-gator/internal/sanitize"
+	"github.com/google/uuid"
+)
+
+// The web UI: plain server-rendered pages for reading posts and managing
+// follows. Logging in starts the same kind of session as gator login, so
+// logout and passwd end it too.
+
+//go:embed web/templates/*.html
+var webTemplateFiles embed.FS
+
+var webTemplates = func() map[string]*template.Template {
+	pages := map[string]*template.Template{}
+	for _, page := range []string{"login", "posts", "post", "feeds"} {
+		pages[page] = template.Must(template.ParseFS(webTemplateFiles, "web/templates/layout.html", "web/templates/"+page+".html"))
+	}
+	return pages
+}()
+
+const (
+	webSessionCookie = "gator_session"
+	webSessionLength = 30 * 24 * time.Hour
+	webPageSize      = 20
+)
+
+// webCSRFToken is the CSRF token for the forms of the session with token.
+// It's derived from the token, so it needs no state of its own, and a
+// different prefix keeps it from being the hash stored in the database.
+func webCSRFToken(token string) string {
+	return hashToken("csrf " + token)
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type webPost struct {
+	ID          string
+	Title       string
+	URL         string
+	FeedID      string
+	Feed        string
+	Author      string
+	PublishedAt *time.Time
+	Read        bool
+	Starred     bool
+	Highlighted bool
+	Body        template.HTML
+}
+
+type webFollow struct {
+	ID       string
+	Name     string
+	URL      string
+	Category string
+}
+
+type webButtons struct {
+	Post   webPost
+	CSRF   string
+	Return string
+}
+
+type webPage struct {
+	Title     string
+	User      *database.User
+	CSRF      string
+	Error     string
+	Return    string
+	Posts     []webPost
+	Post      webPost
+	Next      string
+	Following []webFollow
+	Others    []apiFeed
+	Icons     map[string]string
+}
+
+// Buttons is the data for the read and star buttons of one post.
+func (p webPage) Buttons(post webPost) webButtons {
+	return webButtons{Post: post, CSRF: p.CSRF, Return: p.Return}
+}
+
+func (api *apiServer) registerWeb(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", api.handleWebLoginPage)
+	mux.HandleFunc("POST /login", api.handleWebLogin)
+	mux.HandleFunc("POST /logout", api.webAuthenticated(api.handleWebLogout))
+	mux.HandleFunc("GET /{$}", api.webAuthenticated(api.handleWebPosts))
+	mux.HandleFunc("GET /posts/{postID}", api.webAuthenticated(api.handleWebPost))
+	mux.HandleFunc("POST /posts/{postID}/read", api.webAuthenticated(api.handleWebPostState(markRead)))
+	mux.HandleFunc("POST /posts/{postID}/unread", api.webAuthenticated(api.handleWebPostState(markUnread)))
+	mux.HandleFunc("POST /posts/{postID}/star", api.webAuthenticated(api.handleWebPostState(star)))
+	mux.HandleFunc("POST /posts/{postID}/unstar", api.webAuthenticated(api.handleWebPostState(unstar)))
+	mux.HandleFunc("GET /feeds", api.webAuthenticated(api.handleWebFeeds))
+	mux.HandleFunc("POST /feeds", api.webAuthenticated(api.handleWebAddFeed))
+	mux.HandleFunc("GET /feeds/{feedID}/icon", api.webAuthenticated(api.handleWebFeedIcon))
+	mux.HandleFunc("POST /follow", api.webAuthenticated(api.handleWebFollow))
+	mux.HandleFunc("POST /unfollow", api.webAuthenticated(api.handleWebUnfollow))
+}
+
+// webAuthenticated sends visitors without a session to the login page and
+// checks the CSRF token on every form post.
+func (api *apiServer) webAuthenticated(handler func(w http.ResponseWriter, r *http.Request, page webPage)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(webSessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := api.state.db.GetUserBySession(r.Context(), hashToken(cookie.Value))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			api.webError(w, err)
+			return
+		}
+		csrf := webCSRFToken(cookie.Value)
+		if r.Method == http.MethodPost {
+			if subtle.ConstantTimeCompare([]byte(r.PostFormValue("csrf")), []byte(csrf)) != 1 {
+				http.Error(w, "invalid form token, reload the page and try again", http.StatusForbidden)
+				return
+			}
+		}
+		handler(w, r, webPage{User: &user, CSRF: csrf, Return: r.URL.RequestURI()})
+	}
+}
+
+func (api *apiServer) renderWeb(w http.ResponseWriter, status int, name string, page webPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := webTemplates[name].ExecuteTemplate(w, "layout", page); err != nil {
+		api.logger.Printf("rendering %v: %v", name, err)
+	}
+}
+
+func (api *apiServer) webError(w http.ResponseWriter, err error) {
+	api.logger.Printf("internal error: %v", err)
+	http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+}
+
+func (api *apiServer) handleWebLoginPage(w http.ResponseWriter, r *http.Request) {
+	api.renderWeb(w, http.StatusOK, "login", webPage{Title: "Log in"})
+}
+
+func (api *apiServer) handleWebLogin(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	user, err := api.state.db.GetUser(r.Context(), name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		api.webError(w, err)
+		return
+	}
+	if !checkPassword(user, r.PostFormValue("password")) {
+		api.renderWeb(w, http.StatusUnauthorized, "login", webPage{Title: "Log in", Error: errBadLogin.Error()})
+		return
+	}
+	token, err := createSession(r.Context(), api.state.db, user, webSessionLength)
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webSessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(webSessionLength),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (api *apiServer) handleWebLogout(w http.ResponseWriter, r *http.Request, page webPage) {
+	if cookie, err := r.Cookie(webSessionCookie); err == nil {
+		if err := api.state.db.DeleteSession(r.Context(), hashToken(cookie.Value)); err != nil {
+			api.webError(w, err)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: webSessionCookie, Value: "", Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// handleWebPosts lists posts. It takes the browse flags as query
+// parameters, like the JSON API.
+func (api *apiServer) handleWebPosts(w http.ResponseWriter, r *http.Request, page webPage) {
+	query := r.URL.Query()
+	opts, err := parseBrowseOptions(append([]string{"--limit=" + strconv.Itoa(webPageSize)}, queryFlags(query)...))
+	var params database.BrowsePostsForUserParams
+	if err == nil {
+		params, err = opts.params(page.User.ID)
+	}
+	if err != nil {
+		page.Title = "Posts"
+		page.Error = err.Error()
+		api.renderWeb(w, http.StatusBadRequest, "posts", page)
+		return
+	}
+	wheres, err := opts.wheres(api.state, *page.User)
+	if err != nil {
+		page.Title = "Posts"
+		page.Error = err.Error()
+		api.renderWeb(w, http.StatusBadRequest, "posts", page)
+		return
+	}
+	posts, more, err := loadBrowsePosts(api.state, *page.User, params, wheres, opts.showMuted)
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	switch {
+	case opts.unread:
+		page.Title = "Unread"
+	case opts.starred:
+		page.Title = "Starred"
+	case opts.feed != "" && len(posts) > 0:
+		page.Title = posts[0].FeedName
+	case opts.category != "":
+		page.Title = opts.category
+	default:
+		page.Title = "All posts"
+	}
+	page.Icons, err = iconPaths(r.Context(), api.state.db, page.User.ID)
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	for _, post := range posts {
+		page.Posts = append(page.Posts, newWebPost(post, false))
+	}
+	if more {
+		last := posts[len(posts)-1]
+		next := url.Values{}
+		for key, values := range query {
+			next[key] = values
+		}
+		next.Set("cursor", encodeCursor(last.PublishedAt.Time, last.ID))
+		page.Next = "/?" + next.Encode()
+	}
+	api.renderWeb(w, http.StatusOK, "posts", page)
+}
+
+// handleWebPost shows one post and marks it read.
+func (api *apiServer) handleWebPost(w http.ResponseWriter, r *http.Request, page webPage) {
+	postID, err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	itemID := hex.EncodeToString(postID[:8])
+	params := database.BrowsePostsForUserParams{UserID: page.User.ID, ItemIds: []string{itemID}, PostLimit: 1}
+	posts, _, err := loadBrowsePosts(api.state, *page.User, params, nil, true)
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	if len(posts) == 0 || posts[0].ID != postID {
+		http.NotFound(w, r)
+		return
+	}
+	if err := markRead(r.Context(), api.state.db, page.User.ID, postID); err != nil {
+		api.webError(w, err)
+		return
+	}
+	page.Post = newWebPost(posts[0], true)
+	page.Post.Read = true
+	page.Title = page.Post.Title
+	api.renderWeb(w, http.StatusOK, "post", page)
+}
+
+func (api *apiServer) handleWebPostState(change postStateChange) func(w http.ResponseWriter, r *http.Request, page webPage) {
+	return func(w http.ResponseWriter, r *http.Request, page webPage) {
+		postID, err := uuid.Parse(r.PathValue("postID"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := change(r.Context(), api.state.db, page.User.ID, postID); err != nil {
+			api.webError(w, err)
+			return
+		}
+		http.Redirect(w, r, safeReturn(r.PostFormValue("return")), http.StatusSeeOther)
+	}
+}
+
+func (api *apiServer) handleWebFeeds(w http.ResponseWriter, r *http.Request, page webPage) {
+	api.renderFeeds(w, r, page, http.StatusOK)
+}
+
+func (api *apiServer) renderFeeds(w http.ResponseWriter, r *http.Request, page webPage, status int) {
+	following, err := api.state.db.GetFeedFollowsForUser(r.Context(), page.User.ID)
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	feeds, err := api.state.db.ListFeeds(r.Context(), database.ListFeedsParams{Limit: maxPageSize, Offset: 0})
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	page.Icons, err = iconPaths(r.Context(), api.state.db, page.User.ID)
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	followed := map[uuid.UUID]bool{}
+	for _, followRow := range following {
+		followed[followRow.FeedID] = true
+		page.Following = append(page.Following, webFollow{ID: followRow.FeedID.String(), Name: followRow.FeedName, URL: followRow.FeedUrl, Category: followRow.CategoryName.String})
+	}
+	for _, feed := range feeds {
+		if !followed[feed.ID] {
+			page.Others = append(page.Others, apiFeed{ID: feed.ID.String(), Name: feed.Name, URL: feed.Url, User: feed.UserName})
+		}
+	}
+	page.Title = "Feeds"
+	api.renderWeb(w, status, "feeds", page)
+}
+
+func (api *apiServer) handleWebAddFeed(w http.ResponseWriter, r *http.Request, page webPage) {
+	name, feedURL := strings.TrimSpace(r.PostFormValue("name")), strings.TrimSpace(r.PostFormValue("url"))
+	if name == "" || feedURL == "" {
+		page.Error = "A feed needs a name and a URL."
+		api.renderFeeds(w, r, page, http.StatusBadRequest)
+		return
+	}
+	feed_params := database.CreateFeedParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, Name: name, Url: feedURL, UserID: page.User.ID}
+	new_feed, err := api.state.db.CreateFeed(r.Context(), feed_params)
+	if isUniqueViolation(err) {
+		page.Error = "That feed has already been added."
+		api.renderFeeds(w, r, page, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	feedFollowParams := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: page.User.ID, FeedID: new_feed.ID}
+	if _, err := api.state.db.CreateFeedFollow(r.Context(), feedFollowParams); err != nil {
+		api.webError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
+
+func (api *apiServer) handleWebFollow(w http.ResponseWriter, r *http.Request, page webPage) {
+	feed_id, err := api.state.db.GetFeedID(r.Context(), r.PostFormValue("url"))
+	if errors.Is(err, sql.ErrNoRows) {
+		page.Error = "No feed with that URL."
+		api.renderFeeds(w, r, page, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.webError(w, err)
+		return
+	}
+	feedFollowParams := database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true}, UserID: page.User.ID, FeedID: feed_id}
+	if _, err := api.state.db.CreateFeedFollow(r.Context(), feedFollowParams); err != nil && !isUniqueViolation(err) {
+		api.webError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
+
+func (api *apiServer) handleWebUnfollow(w http.ResponseWriter, r *http.Request, page webPage) {
+	feedID, err := uuid.Parse(r.PostFormValue("feed_id"))
+	if err != nil {
+		http.Error(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	deleteParams := database.DeleteFeedFollowParams{UserID: page.User.ID, FeedID: feedID}
+	if err := api.state.db.DeleteFeedFollow(r.Context(), deleteParams); err != nil {
+		api.webError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
+
+// newWebPost prepares a post for the templates. The body is only sanitized
+// when it's going to be shown.
+func newWebPost(post browsedPost, withBody bool) webPost {
+	p := webPost{
+		ID:          post.ID.String(),
+		Title:       post.Title,
+		URL:         post.Url,
+		FeedID:      post.FeedID.String(),
+		Feed:        post.FeedName,
+		Author:      post.Author,
+		PublishedAt: nullTime(post.PublishedAt.Valid, post.PublishedAt.Time),
+		Read:        post.ReadAt.Valid,
+		Starred:     post.StarredAt.Valid,
+		Highlighted: post.highlighted,
+	}
+	if withBody {
+		body := post.Content
+		if body == "" {
+			body = post.Description
+		}
+		base, _ := url.Parse(post.Url)
+		p.Body = template.HTML(sanitize.HTML(body, base))
+	}
+	return p
+}
+
+// safeReturn only allows redirects back to a page on this site.
+func safeReturn(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
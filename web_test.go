@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curtisbraxdale/blog-gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestWebRequiresLogin(t *testing.T) {
+	server := testAPI(t, nil)
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/"},
+		{"GET", "/?unread=true"},
+		{"GET", "/feeds"},
+		{"GET", "/posts/" + uuid.NewString()},
+		{"POST", "/posts/" + uuid.NewString() + "/star"},
+		{"POST", "/follow"},
+		{"POST", "/logout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/login" {
+				t.Errorf("got %v to %q, want a redirect to /login", res.StatusCode, res.Header.Get("Location"))
+			}
+		})
+	}
+
+	res := call(t, server, "GET", "/login", "", nil)
+	expectStatus(t, res, http.StatusOK)
+	if !strings.Contains(string(res.body), `action="/login"`) {
+		t.Errorf("login page has no login form: %s", res.body)
+	}
+}
+
+func TestWebTemplates(t *testing.T) {
+	api := &apiServer{logger: log.New(io.Discard, "", 0)}
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	feedID := uuid.New()
+	post := newWebPost(browsedPost{
+		BrowsePostsForUserRow: database.BrowsePostsForUserRow{
+			ID:          uuid.New(),
+			FeedID:      feedID,
+			Title:       "<b>Hello</b>",
+			Url:         "https://example.com/posts/hello",
+			Content:     `<p onclick="x()">Hi <a href="/about">there</a><script>alert(1)</script></p>`,
+			PublishedAt: sql.NullTime{Time: published, Valid: true},
+			FeedName:    "Example",
+		},
+	}, true)
+	page := webPage{User: &database.User{Name: "kahya"}, CSRF: "token", Return: "/", Post: post, Posts: []webPost{post}, Title: post.Title}
+	page.Icons = map[string]string{feedID.String(): "/feeds/" + feedID.String() + "/icon?v=abc"}
+
+	for _, name := range []string{"posts", "post", "feeds", "login"} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.renderWeb(w, http.StatusOK, name, page)
+			body := w.Body.String()
+			if w.Code != http.StatusOK || !strings.Contains(body, "</html>") {
+				t.Fatalf("incomplete page: %s", body)
+			}
+			if strings.Contains(body, "<b>Hello</b>") || strings.Contains(body, "<script>") {
+				t.Errorf("unescaped output: %s", body)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	api.renderWeb(w, http.StatusOK, "post", page)
+	want := `<p>Hi <a href="https://example.com/about" rel="nofollow noopener noreferrer" target="_blank">there</a></p>`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("post body not sanitized as expected: %s", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `name="csrf" value="token"`) {
+		t.Errorf("forms are missing the csrf token: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	api.renderWeb(w, http.StatusOK, "posts", page)
+	if !strings.Contains(w.Body.String(), `<img class="icon" src="/feeds/`+feedID.String()+`/icon?v=abc"`) {
+		t.Errorf("feed icon missing: %s", w.Body.String())
+	}
+}
+
+func TestSafeReturn(t *testing.T) {
+	tests := map[string]string{
+		"/?unread=true":        "/?unread=true",
+		"/posts/abc":           "/posts/abc",
+		"":                     "/",
+		"https://evil.example": "/",
+		"//evil.example":       "/",
+		"/\\evil.example":      "/",
+	}
+	for path, want := range tests {
+		if got := safeReturn(path); got != want {
+			t.Errorf("safeReturn(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWebSessions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db database.Querier) {
+		david := testUser(t, db, "david")
+		if err := storePassword(context.Background(), db, david, fixturePassword); err != nil {
+			t.Fatal(err)
+		}
+		server := testAPI(t, db)
+		client := server.Client()
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		request := func(method, path string, cookie *http.Cookie, form url.Values) *http.Response {
+			t.Helper()
+			req, err := http.NewRequest(method, server.URL+path, strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			return res
+		}
+		login := func() *http.Cookie {
+			t.Helper()
+			res := request("POST", "/login", nil, url.Values{"name": {"david"}, "password": {fixturePassword}})
+			for _, cookie := range res.Cookies() {
+				if cookie.Name == webSessionCookie {
+					return cookie
+				}
+			}
+			t.Fatalf("login gave no session cookie, status %v", res.StatusCode)
+			return nil
+		}
+		loggedIn := func(cookie *http.Cookie) bool {
+			t.Helper()
+			return request("GET", "/", cookie, nil).StatusCode == http.StatusOK
+		}
+
+		if res := request("POST", "/login", nil, url.Values{"name": {"david"}, "password": {"wrong password"}}); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("wrong password: got %v, want %v", res.StatusCode, http.StatusUnauthorized)
+		}
+		cookie := login()
+		if !loggedIn(cookie) {
+			t.Fatal("the session cookie should log in")
+		}
+		if res := request("POST", "/follow", cookie, url.Values{"url": {"https://example.com/feed.xml"}}); res.StatusCode != http.StatusForbidden {
+			t.Errorf("a form without the csrf token: got %v, want %v", res.StatusCode, http.StatusForbidden)
+		}
+
+		// Changing the password ends browser sessions like any other.
+		if err := storePassword(context.Background(), db, david, "battery staple"); err != nil {
+			t.Fatal(err)
+		}
+		if loggedIn(cookie) {
+			t.Error("the web session should end when the password changes")
+		}
+
+		if err := storePassword(context.Background(), db, david, fixturePassword); err != nil {
+			t.Fatal(err)
+		}
+		cookie = login()
+		request("POST", "/logout", cookie, url.Values{"csrf": {webCSRFToken(cookie.Value)}})
+		if loggedIn(cookie) {
+			t.Error("the web session should end on logout")
+		}
+	})
+}